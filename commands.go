@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/malytinKonstantin/go-fiber/internal/app"
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/seed"
+	"github.com/malytinKonstantin/go-fiber/internal/user"
+	"github.com/spf13/viper"
+)
+
+// runCommand executes a command-line subcommand instead of starting the server
+func runCommand(application *app.App, args []string) error {
+	switch args[0] {
+	case "import-users":
+		return runImportUsers(application, args[1:])
+	case "seed":
+		return runSeed(application, args[1:])
+	case "outbox":
+		return runOutbox(application, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q, available commands: import-users, seed, outbox, migrate", args[0])
+	}
+}
+
+// runOutbox lists the events the outbox relay gave up on and queues them for
+// publishing again
+func runOutbox(application *app.App, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: outbox dead|requeue")
+	}
+	ctx := db.WithAllTenants(context.Background())
+	events := application.OutboxModule.Outbox
+
+	switch args[0] {
+	case "dead":
+		flags := flag.NewFlagSet("outbox dead", flag.ContinueOnError)
+		limit := flags.Int("limit", 100, "maximum number of events to list")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		dead, err := events.ListDeadEvents(ctx, int32(*limit))
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, event := range dead {
+			fmt.Fprintf(w, "%d\t%s\t%s:%s\t%d\t%s\n", event.ID, event.Type, event.AggregateType, event.AggregateID, event.Attempts,
+				strings.ReplaceAll(event.LastError, "\n", "; "))
+		}
+		return w.Flush()
+	case "requeue":
+		flags := flag.NewFlagSet("outbox requeue", flag.ContinueOnError)
+		id := flags.Int64("id", 0, "ID of the dead event to publish again")
+		all := flags.Bool("all", false, "publish all dead events again")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *all == (*id != 0) {
+			return errors.New("one of -id or -all is required")
+		}
+		if *all {
+			requeued, err := events.RequeueAll(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Requeued %d events\n", requeued)
+			return nil
+		}
+		return events.Requeue(ctx, *id)
+	default:
+		return fmt.Errorf("unknown outbox command %q, available commands: dead, requeue", args[0])
+	}
+}
+
+// runSeed seeds a bundled profile, fixture files and fake users, in that
+// order, and prints the report. Seeding again updates the same rows.
+func runSeed(application *app.App, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	profile := flags.String("profile", "", "bundled profile: dev, demo or load-test")
+	var files []string
+	flags.Func("file", "YAML or JSON fixture file; may be repeated", func(path string) error {
+		files = append(files, path)
+		return nil
+	})
+	fake := flags.Int("fake", 0, "number of fake users to generate")
+	fakeSeed := flags.Uint64("fake-seed", 0, "seed of the fake users; a different seed generates different users")
+	tenantSlug := flags.String("tenant", "", "tenant of the fake users; TENANT_DEFAULT by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *profile == "" && len(files) == 0 && *fake == 0 {
+		return errors.New("one of -profile, -file or -fake is required")
+	}
+
+	var fixtures seed.Fixtures
+	if *profile != "" {
+		profileFixtures, err := seed.Profile(*profile)
+		if err != nil {
+			return err
+		}
+		fixtures = fixtures.Merge(profileFixtures)
+	}
+	for _, file := range files {
+		fileFixtures, err := seed.LoadFixtures(file)
+		if err != nil {
+			return err
+		}
+		fixtures = fixtures.Merge(fileFixtures)
+	}
+	if *fake > 0 {
+		fixtures.FakeUsers = append(fixtures.FakeUsers, seed.FakeUsersFixture{Tenant: *tenantSlug, Count: *fake, Seed: *fakeSeed})
+	}
+
+	report, err := application.Seeder.Seed(context.Background(), fixtures)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// runImportUsers imports users from a CSV or NDJSON file and prints the report
+func runImportUsers(application *app.App, args []string) error {
+	flags := flag.NewFlagSet("import-users", flag.ContinueOnError)
+	file := flags.String("file", "", "path to a CSV or NDJSON file, - for stdin")
+	format := flags.String("format", "", "csv or ndjson; derived from the file extension by default")
+	mode := flags.String("mode", user.ImportAllOrNothing, "dry_run, all_or_nothing or best_effort")
+	tenantSlug := flags.String("tenant", viper.GetString("TENANT_DEFAULT"), "slug of the tenant the users are imported into")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+	if *tenantSlug == "" {
+		return errors.New("-tenant is required")
+	}
+	tenantID, err := application.Tenants.ResolveTenant(context.Background(), *tenantSlug)
+	if err != nil {
+		return fmt.Errorf("tenant %q: %w", *tenantSlug, err)
+	}
+
+	var input io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+	if *format == "" {
+		switch strings.ToLower(filepath.Ext(*file)) {
+		case ".csv":
+			*format = user.ImportFormatCSV
+		case ".ndjson", ".jsonl":
+			*format = user.ImportFormatNDJSON
+		}
+	}
+
+	ctx := db.WithTenant(context.Background(), tenantID)
+	report, err := application.UserModule.Importer.Import(ctx, input, *format, *mode)
+	if err != nil && !errors.Is(err, user.ErrImportRejected) {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(report); encodeErr != nil {
+		return encodeErr
+	}
+	return err
+}
@@ -0,0 +1,243 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// catalogQueries describe the parts of the public schema that sqlc and the
+// application depend on, one line per object. Column order is left out, as
+// columns added by migrations come last while the schema lists them where
+// they belong.
+var catalogQueries = []string{
+	`SELECT 'extension ' || extname FROM pg_extension WHERE extname <> 'plpgsql'`,
+	`SELECT format('enum %s (%s)', t.typname, string_agg(e.enumlabel, ', ' ORDER BY e.enumsortorder))
+	FROM pg_type t
+	JOIN pg_enum e ON e.enumtypid = t.oid
+	JOIN pg_namespace n ON n.oid = t.typnamespace
+	WHERE n.nspname = 'public'
+	GROUP BY t.typname`,
+	`SELECT format('table %s%s%s', c.relname,
+		CASE WHEN c.relrowsecurity THEN ' ROW LEVEL SECURITY' ELSE '' END,
+		CASE WHEN c.relforcerowsecurity THEN ' FORCE' ELSE '' END)
+	FROM pg_class c
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE n.nspname = 'public' AND c.relkind IN ('r', 'p')`,
+	`SELECT format('column %s.%s %s%s%s', c.relname, a.attname, format_type(a.atttypid, a.atttypmod),
+		CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END,
+		COALESCE(CASE WHEN a.attgenerated = 's' THEN ' GENERATED ' ELSE ' DEFAULT ' END || pg_get_expr(d.adbin, d.adrelid), ''))
+	FROM pg_attribute a
+	JOIN pg_class c ON c.oid = a.attrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+	WHERE n.nspname = 'public' AND c.relkind IN ('r', 'p', 'v', 'm') AND a.attnum > 0 AND NOT a.attisdropped`,
+	`SELECT format('constraint %s.%s %s', c.relname, co.conname, pg_get_constraintdef(co.oid))
+	FROM pg_constraint co
+	JOIN pg_class c ON c.oid = co.conrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE n.nspname = 'public'`,
+	`SELECT 'index ' || indexdef FROM pg_indexes WHERE schemaname = 'public'`,
+	`SELECT format('view %s %s', viewname, definition) FROM pg_views WHERE schemaname = 'public'`,
+	`SELECT format('policy %s.%s %s %s USING %s WITH CHECK %s', tablename, policyname, permissive, cmd, qual, with_check)
+	FROM pg_policies
+	WHERE schemaname = 'public'`,
+	`SELECT 'function ' || pg_get_functiondef(p.oid)
+	FROM pg_proc p
+	JOIN pg_namespace n ON n.oid = p.pronamespace
+	WHERE n.nspname = 'public' AND p.prokind IN ('f', 'p')
+		AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = p.oid AND d.deptype = 'e')`,
+	`SELECT 'trigger ' || pg_get_triggerdef(t.oid)
+	FROM pg_trigger t
+	JOIN pg_class c ON c.oid = t.tgrelid
+	JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE n.nspname = 'public' AND NOT t.tgisinternal`,
+}
+
+// SchemaDrift lists the catalog objects that differ between a database built
+// by the migrations and one built from the sqlc schema
+type SchemaDrift struct {
+	OnlyInMigrations []string
+	OnlyInSchema     []string
+}
+
+func (d SchemaDrift) Empty() bool {
+	return len(d.OnlyInMigrations) == 0 && len(d.OnlyInSchema) == 0
+}
+
+// String renders the drift as a diff from the migrations to the schema
+func (d SchemaDrift) String() string {
+	var b strings.Builder
+	b.WriteString("--- migrations (db/migration)\n+++ schema (db/schema)\n")
+	for _, line := range d.OnlyInMigrations {
+		fmt.Fprintf(&b, "- %s\n", line)
+	}
+	for _, line := range d.OnlyInSchema {
+		fmt.Fprintf(&b, "+ %s\n", line)
+	}
+	return b.String()
+}
+
+// CheckSchemaDrift applies the embedded migrations to one scratch database
+// and the schema files matching schemaGlob to another, and compares their
+// catalogs. The scratch databases are created on the server of serverURL,
+// whose role needs the CREATEDB privilege, and dropped afterwards.
+func CheckSchemaDrift(ctx context.Context, serverURL, schemaGlob string) (SchemaDrift, error) {
+	files, err := filepath.Glob(schemaGlob)
+	if err != nil {
+		return SchemaDrift{}, err
+	}
+	if len(files) == 0 {
+		return SchemaDrift{}, fmt.Errorf("no schema files match %s", schemaGlob)
+	}
+	server, err := pgx.ParseConfig(serverURL)
+	if err != nil {
+		return SchemaDrift{}, err
+	}
+	// migrations and schema scripts see the rows of all tenants
+	server.RuntimeParams["app.tenant_id"] = "*"
+
+	migrated, err := withScratchDatabase(ctx, server, func(config *pgx.ConnConfig) ([]string, error) {
+		sqlDB := stdlib.OpenDB(*config)
+		defer sqlDB.Close()
+		migrator, err := NewMigrator(ctx, sqlDB)
+		if err != nil {
+			return nil, err
+		}
+		defer migrator.Close()
+		if err := migrator.Up(); err != nil {
+			return nil, fmt.Errorf("apply migrations: %w", err)
+		}
+		return dumpCatalog(ctx, config, "DROP TABLE schema_migrations")
+	})
+	if err != nil {
+		return SchemaDrift{}, err
+	}
+
+	declared, err := withScratchDatabase(ctx, server, func(config *pgx.ConnConfig) ([]string, error) {
+		if err := applySchemaFiles(ctx, config, files); err != nil {
+			return nil, err
+		}
+		return dumpCatalog(ctx, config)
+	})
+	if err != nil {
+		return SchemaDrift{}, err
+	}
+
+	return SchemaDrift{
+		OnlyInMigrations: difference(migrated, declared),
+		OnlyInSchema:     difference(declared, migrated),
+	}, nil
+}
+
+// withScratchDatabase creates an empty database, runs fn with its connection
+// config and drops it again
+func withScratchDatabase(ctx context.Context, server *pgx.ConnConfig, fn func(config *pgx.ConnConfig) ([]string, error)) ([]string, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	name := "schema_drift_" + hex.EncodeToString(suffix)
+
+	admin, err := pgx.ConnectConfig(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close(context.Background())
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{name}.Sanitize()+" TEMPLATE template0"); err != nil {
+		return nil, fmt.Errorf("create scratch database: %w", err)
+	}
+	defer admin.Exec(context.Background(), "DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()+" WITH (FORCE)")
+
+	config := server.Copy()
+	config.Database = name
+	return fn(config)
+}
+
+// applySchemaFiles runs each file in a transaction. The files reference each
+// other, so a file that fails is retried after the others until a round
+// makes no progress.
+func applySchemaFiles(ctx context.Context, config *pgx.ConnConfig, files []string) error {
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	pending := files
+	for len(pending) > 0 {
+		var failed []string
+		var errs []error
+		for _, file := range pending {
+			script, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, string(script))
+				return err
+			})
+			if err != nil {
+				failed = append(failed, file)
+				errs = append(errs, fmt.Errorf("%s: %w", file, err))
+			}
+		}
+		if len(failed) == len(pending) {
+			return fmt.Errorf("apply schema: %w", errors.Join(errs...))
+		}
+		pending = failed
+	}
+	return nil
+}
+
+// dumpCatalog runs the setup statements, then returns the sorted catalog
+// lines with whitespace collapsed
+func dumpCatalog(ctx context.Context, config *pgx.ConnConfig, setup ...string) ([]string, error) {
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(context.Background())
+
+	for _, statement := range setup {
+		if _, err := conn.Exec(ctx, statement); err != nil {
+			return nil, err
+		}
+	}
+	var lines []string
+	for _, query := range catalogQueries {
+		rows, err := conn.Query(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		objects, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			lines = append(lines, strings.Join(strings.Fields(object), " "))
+		}
+	}
+	slices.Sort(lines)
+	return lines, nil
+}
+
+// difference returns the lines of a missing from b; both are sorted
+func difference(a, b []string) []string {
+	var missing []string
+	for _, line := range a {
+		if _, found := slices.BinarySearch(b, line); !found {
+			missing = append(missing, line)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,3 @@
+-- Stale claims are detected by the claim time, not by the age of the export
+ALTER TABLE data_exports ADD COLUMN claimed_at TIMESTAMP WITH TIME ZONE;
+UPDATE data_exports SET claimed_at = CURRENT_TIMESTAMP WHERE status = 'processing';
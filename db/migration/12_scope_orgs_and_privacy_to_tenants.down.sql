@@ -0,0 +1,25 @@
+DROP POLICY erasure_requests_tenant_isolation ON erasure_requests;
+ALTER TABLE erasure_requests NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE erasure_requests DISABLE ROW LEVEL SECURITY;
+DROP POLICY data_exports_tenant_isolation ON data_exports;
+ALTER TABLE data_exports NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE data_exports DISABLE ROW LEVEL SECURITY;
+DROP POLICY org_invitations_tenant_isolation ON org_invitations;
+ALTER TABLE org_invitations NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE org_invitations DISABLE ROW LEVEL SECURITY;
+DROP POLICY org_memberships_tenant_isolation ON org_memberships;
+ALTER TABLE org_memberships NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE org_memberships DISABLE ROW LEVEL SECURITY;
+DROP POLICY organizations_tenant_isolation ON organizations;
+ALTER TABLE organizations NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE organizations DISABLE ROW LEVEL SECURITY;
+
+-- Restoring global uniqueness fails if tenants share organization slugs
+ALTER TABLE organizations DROP CONSTRAINT organizations_tenant_id_slug_key;
+ALTER TABLE organizations ADD CONSTRAINT organizations_slug_key UNIQUE (slug);
+
+ALTER TABLE erasure_requests DROP COLUMN tenant_id;
+ALTER TABLE data_exports DROP COLUMN tenant_id;
+ALTER TABLE org_invitations DROP COLUMN tenant_id;
+ALTER TABLE org_memberships DROP COLUMN tenant_id;
+ALTER TABLE organizations DROP COLUMN tenant_id;
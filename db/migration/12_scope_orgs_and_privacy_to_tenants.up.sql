@@ -0,0 +1,64 @@
+-- Organizations belong to the tenant of their owner
+ALTER TABLE organizations ADD COLUMN tenant_id INTEGER REFERENCES tenants(id);
+UPDATE organizations o SET tenant_id = u.tenant_id
+FROM org_memberships m
+JOIN users u ON u.id = m.user_id
+WHERE m.org_id = o.id AND m.role = 'owner';
+UPDATE organizations SET tenant_id = (SELECT id FROM tenants WHERE slug = 'default') WHERE tenant_id IS NULL;
+
+-- Memberships and invitations belong to the tenant of their organization
+ALTER TABLE org_memberships ADD COLUMN tenant_id INTEGER REFERENCES tenants(id);
+UPDATE org_memberships m SET tenant_id = o.tenant_id FROM organizations o WHERE o.id = m.org_id;
+ALTER TABLE org_invitations ADD COLUMN tenant_id INTEGER REFERENCES tenants(id);
+UPDATE org_invitations i SET tenant_id = o.tenant_id FROM organizations o WHERE o.id = i.org_id;
+
+-- Privacy requests belong to the tenant of their user
+ALTER TABLE data_exports ADD COLUMN tenant_id INTEGER REFERENCES tenants(id);
+UPDATE data_exports e SET tenant_id = u.tenant_id FROM users u WHERE u.id = e.user_id;
+ALTER TABLE erasure_requests ADD COLUMN tenant_id INTEGER REFERENCES tenants(id);
+UPDATE erasure_requests r SET tenant_id = u.tenant_id FROM users u WHERE u.id = r.user_id;
+
+ALTER TABLE organizations ALTER COLUMN tenant_id SET NOT NULL;
+ALTER TABLE organizations ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE org_memberships ALTER COLUMN tenant_id SET NOT NULL;
+ALTER TABLE org_memberships ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE org_invitations ALTER COLUMN tenant_id SET NOT NULL;
+ALTER TABLE org_invitations ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE data_exports ALTER COLUMN tenant_id SET NOT NULL;
+ALTER TABLE data_exports ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE erasure_requests ALTER COLUMN tenant_id SET NOT NULL;
+ALTER TABLE erasure_requests ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+
+-- Slugs are unique within a tenant
+ALTER TABLE organizations DROP CONSTRAINT organizations_slug_key;
+ALTER TABLE organizations ADD CONSTRAINT organizations_tenant_id_slug_key UNIQUE (tenant_id, slug);
+
+ALTER TABLE organizations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE organizations FORCE ROW LEVEL SECURITY;
+CREATE POLICY organizations_tenant_isolation ON organizations
+    USING (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id())
+    WITH CHECK (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id());
+
+ALTER TABLE org_memberships ENABLE ROW LEVEL SECURITY;
+ALTER TABLE org_memberships FORCE ROW LEVEL SECURITY;
+CREATE POLICY org_memberships_tenant_isolation ON org_memberships
+    USING (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id())
+    WITH CHECK (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id());
+
+ALTER TABLE org_invitations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE org_invitations FORCE ROW LEVEL SECURITY;
+CREATE POLICY org_invitations_tenant_isolation ON org_invitations
+    USING (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id())
+    WITH CHECK (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id());
+
+ALTER TABLE data_exports ENABLE ROW LEVEL SECURITY;
+ALTER TABLE data_exports FORCE ROW LEVEL SECURITY;
+CREATE POLICY data_exports_tenant_isolation ON data_exports
+    USING (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id())
+    WITH CHECK (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id());
+
+ALTER TABLE erasure_requests ENABLE ROW LEVEL SECURITY;
+ALTER TABLE erasure_requests FORCE ROW LEVEL SECURITY;
+CREATE POLICY erasure_requests_tenant_isolation ON erasure_requests
+    USING (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id())
+    WITH CHECK (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id());
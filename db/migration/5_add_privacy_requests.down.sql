@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS erasure_requests;
+DROP TABLE IF EXISTS data_exports;
@@ -0,0 +1,14 @@
+DROP POLICY users_tenant_isolation ON users;
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE users DISABLE ROW LEVEL SECURITY;
+
+-- Restoring global uniqueness fails if tenants share usernames or emails
+DROP INDEX users_username_active_key;
+DROP INDEX users_email_active_key;
+CREATE UNIQUE INDEX users_username_active_key ON users(username) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX users_email_active_key ON users(email) WHERE deleted_at IS NULL;
+
+DROP INDEX idx_users_tenant_id;
+ALTER TABLE users DROP COLUMN tenant_id;
+DROP FUNCTION current_tenant_id();
+DROP TABLE tenants;
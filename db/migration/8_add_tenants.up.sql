@@ -0,0 +1,35 @@
+CREATE TABLE tenants (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Tenant of the current connection, taken from the app.tenant_id setting.
+-- NULL when no tenant is set or the connection works across tenants ('*').
+CREATE FUNCTION current_tenant_id() RETURNS INTEGER
+LANGUAGE sql STABLE
+AS $$
+    SELECT NULLIF(NULLIF(current_setting('app.tenant_id', true), ''), '*')::INTEGER
+$$;
+
+-- Existing users become the users of the default tenant
+INSERT INTO tenants (slug, name) VALUES ('default', 'Default');
+
+ALTER TABLE users ADD COLUMN tenant_id INTEGER REFERENCES tenants(id);
+UPDATE users SET tenant_id = (SELECT id FROM tenants WHERE slug = 'default');
+ALTER TABLE users ALTER COLUMN tenant_id SET NOT NULL;
+ALTER TABLE users ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+CREATE INDEX idx_users_tenant_id ON users(tenant_id);
+
+-- Usernames and emails are unique within a tenant
+DROP INDEX users_username_active_key;
+DROP INDEX users_email_active_key;
+CREATE UNIQUE INDEX users_username_active_key ON users(tenant_id, username) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX users_email_active_key ON users(tenant_id, email) WHERE deleted_at IS NULL;
+
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+CREATE POLICY users_tenant_isolation ON users
+    USING (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id())
+    WITH CHECK (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id());
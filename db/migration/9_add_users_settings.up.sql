@@ -0,0 +1,3 @@
+-- Only settings changed from the defaults are stored; defaults live in code
+ALTER TABLE users ADD COLUMN settings JSONB NOT NULL DEFAULT '{}'::jsonb
+    CHECK (jsonb_typeof(settings) = 'object');
@@ -1,32 +1,237 @@
 package db
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-func RunMigrations(db *sql.DB, migrationPath string) error {
-	driver, err := postgres.WithInstance(db, &postgres.Config{})
+// migrationLockKey is the advisory lock that serializes the auto-migrations
+// of instances starting at the same time
+const migrationLockKey int64 = 7_351_023_819
+
+//go:embed migration/*.sql
+var migrationFiles embed.FS
+
+var migrationNameSanitizer = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Migration is a migration known to the binary
+type Migration struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
+
+// MigrationStatus describes the schema version of the database
+type MigrationStatus struct {
+	// Version is the last applied migration, 0 for an empty database
+	Version uint `json:"version"`
+	// Dirty is set when a migration failed halfway; the schema has to be
+	// fixed by hand and the version set with Force
+	Dirty      bool        `json:"dirty"`
+	Migrations []Migration `json:"migrations"`
+}
+
+// Migrator applies the migrations embedded in the binary. It works on a
+// single connection, so a setting made when the connection is acquired, such
+// as the tenant scope, holds for every migration: migrations that touch rows
+// of tables with row-level security need a connection that sees all tenants.
+type Migrator struct {
+	conn    *sql.Conn
+	migrate *migrate.Migrate
+}
+
+func NewMigrator(ctx context.Context, sqlDB *sql.DB) (*Migrator, error) {
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// migrations such as index builds may run longer than requests may
+	if _, err := conn.ExecContext(ctx, "SET statement_timeout = 0"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not create the postgres driver: %w", err)
+	}
+	files, err := iofs.New(migrationFiles, "migration")
+	if err != nil {
+		driver.Close()
+		return nil, err
+	}
+	m, err := migrate.NewWithInstance("iofs", files, "postgres", driver)
+	if err != nil {
+		driver.Close()
+		return nil, fmt.Errorf("could not create the migrate instance: %w", err)
+	}
+	m.Log = migrationLogger{}
+	return &Migrator{conn: conn, migrate: m}, nil
+}
+
+// Close releases the connection
+func (m *Migrator) Close() error {
+	sourceErr, databaseErr := m.migrate.Close()
+	return errors.Join(sourceErr, databaseErr)
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up() error {
+	return m.result(m.migrate.Up())
+}
+
+// UpLocked applies the pending migrations while holding an advisory lock,
+// so that of several instances starting together one migrates and the
+// others wait for it and then find nothing left to do. ctx bounds the wait.
+func (m *Migrator) UpLocked(ctx context.Context) error {
+	if _, err := m.conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("could not acquire the migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := m.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			log.Printf("Failed to release the migration lock: %v", err)
+		}
+	}()
+	return m.Up()
+}
+
+// Down rolls back the last steps migrations
+func (m *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("invalid number of steps %d", steps)
+	}
+	return m.result(m.migrate.Steps(-steps))
+}
+
+// Force sets the schema version without running migrations and clears the
+// dirty flag. -1 marks an empty database.
+func (m *Migrator) Force(version int) error {
+	return m.migrate.Force(version)
+}
+
+// Status returns the schema version and the migrations known to the binary
+func (m *Migrator) Status() (MigrationStatus, error) {
+	version, dirty, err := m.migrate.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return MigrationStatus{}, err
+	}
+	migrations, err := embeddedMigrations()
 	if err != nil {
-		return fmt.Errorf("could not create the postgres driver: %v", err)
+		return MigrationStatus{}, err
+	}
+	for i := range migrations {
+		migrations[i].Applied = migrations[i].Version < version || migrations[i].Version == version && !dirty
 	}
+	return MigrationStatus{Version: version, Dirty: dirty, Migrations: migrations}, nil
+}
+
+func (m *Migrator) result(err error) error {
+	var dirty migrate.ErrDirty
+	switch {
+	case err == nil, errors.Is(err, migrate.ErrNoChange):
+		return nil
+	case errors.As(err, &dirty):
+		return fmt.Errorf("migration %d failed halfway: fix the schema by hand, then run migrate force", dirty.Version)
+	default:
+		return err
+	}
+}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://"+migrationPath,
-		"postgres", driver)
+func embeddedMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migration")
 	if err != nil {
-		return fmt.Errorf("could not create the migrate instance: %v", err)
+		return nil, err
 	}
+	var migrations []Migration
+	for _, entry := range entries {
+		parsed, err := source.DefaultParse(entry.Name())
+		if err != nil || parsed.Direction != source.Up {
+			continue
+		}
+		migrations = append(migrations, Migration{Version: parsed.Version, Name: parsed.Identifier})
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return int(a.Version) - int(b.Version)
+	})
+	return migrations, nil
+}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("an error occurred while syncing the database: %v", err)
+// MigrationsChecksum identifies the embedded migrations: it changes whenever
+// a migration is added, removed or edited
+func MigrationsChecksum() (string, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migration")
+	if err != nil {
+		return "", err
 	}
+	hash := sha256.New()
+	for _, entry := range entries {
+		content, err := migrationFiles.ReadFile("migration/" + entry.Name())
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s %d\n", entry.Name(), len(content))
+		hash.Write(content)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CreateMigration writes empty up and down files for the next version to
+// dir, the migration directory of the source tree, and returns their paths.
+// The binary has to be rebuilt to embed them.
+func CreateMigration(dir, name string) ([]string, error) {
+	name = strings.Trim(migrationNameSanitizer.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name is required")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var next uint = 1
+	for _, entry := range entries {
+		if parsed, err := source.DefaultParse(entry.Name()); err == nil && parsed.Version >= next {
+			next = parsed.Version + 1
+		}
+	}
+
+	var paths []string
+	for _, direction := range []source.Direction{source.Up, source.Down} {
+		path := filepath.Join(dir, fmt.Sprintf("%d_%s.%s.sql", next, name, direction))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return paths, err
+		}
+		if err := f.Close(); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+type migrationLogger struct{}
+
+func (migrationLogger) Printf(format string, v ...interface{}) {
+	log.Printf(strings.TrimSuffix(format, "\n"), v...)
+}
 
-	log.Println("Migrations completed successfully")
-	return nil
+func (migrationLogger) Verbose() bool {
+	return false
 }
@@ -0,0 +1,135 @@
+-- name: CreateOrganization :one
+-- Creates a new organization; the creator is added as owner separately
+INSERT INTO organizations (name, slug)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetOrganization :one
+-- Retrieves an organization by its ID
+SELECT * FROM organizations
+WHERE id = $1;
+
+-- name: UpdateOrganization :one
+-- Renames an organization
+UPDATE organizations
+SET
+    name = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteOrganization :exec
+-- Deletes an organization together with its memberships and invitations
+DELETE FROM organizations
+WHERE id = $1;
+
+-- name: ListUserOrganizations :many
+-- Lists the organizations the user is a member of, with the user's role
+SELECT o.id, o.name, o.slug, o.created_at, o.updated_at, m.role
+FROM organizations o
+JOIN org_memberships m ON m.org_id = o.id
+WHERE m.user_id = $1
+ORDER BY o.name, o.id;
+
+-- name: ListOrganizationsByUserIDs :many
+-- Lists the organizations of several users at once
+-- Used to embed organizations into user listings
+SELECT m.user_id, o.id, o.name, o.slug, m.role
+FROM org_memberships m
+JOIN organizations o ON o.id = m.org_id
+WHERE m.user_id = ANY(@user_ids::int[])
+ORDER BY m.user_id, o.name, o.id;
+
+-- name: AddMembership :one
+-- Adds a user to an organization with the given role
+INSERT INTO org_memberships (org_id, user_id, role)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetMembership :one
+-- Retrieves the membership of a user in an organization
+SELECT * FROM org_memberships
+WHERE org_id = $1 AND user_id = $2;
+
+-- name: ListMembers :many
+-- Lists the members of an organization with their public profile data
+-- Soft-deleted users are not listed
+SELECT m.user_id, m.role, m.created_at, u.username, u.full_name
+FROM org_memberships m
+JOIN users u ON u.id = m.user_id
+WHERE m.org_id = $1 AND u.deleted_at IS NULL
+ORDER BY m.created_at, m.user_id;
+
+-- name: UpdateMembershipRole :one
+-- Changes the role of a member
+UPDATE org_memberships
+SET role = $3
+WHERE org_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteMembership :execrows
+-- Removes a user from an organization
+DELETE FROM org_memberships
+WHERE org_id = $1 AND user_id = $2;
+
+-- name: ExpireInvitationsForEmail :exec
+-- Marks expired pending invitations of an email as expired
+-- so that the email can be invited again
+UPDATE org_invitations
+SET status = 'expired'
+WHERE org_id = $1
+    AND lower(email) = lower(@email)
+    AND status = 'pending'
+    AND expires_at <= CURRENT_TIMESTAMP;
+
+-- name: CreateInvitation :one
+-- Creates a pending invitation into an organization
+INSERT INTO org_invitations (org_id, email, role, token_hash, invited_by, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetInvitationByTokenHash :one
+-- Retrieves an invitation by the hash of the token sent by email
+SELECT * FROM org_invitations
+WHERE token_hash = $1;
+
+-- name: ListPendingInvitations :many
+-- Lists the invitations of an organization awaiting a response
+SELECT * FROM org_invitations
+WHERE org_id = $1 AND status = 'pending' AND expires_at > CURRENT_TIMESTAMP
+ORDER BY created_at DESC, id DESC;
+
+-- name: RespondToInvitation :execrows
+-- Moves a pending invitation to its final status: accepted, declined or revoked
+UPDATE org_invitations
+SET
+    status = @status,
+    responded_at = CURRENT_TIMESTAMP
+WHERE id = @id AND org_id = @org_id AND status = 'pending';
+
+-- name: ListInvitationsForUser :many
+-- Lists the invitations sent to the email of the user in the user's tenant,
+-- including deleted users
+SELECT i.* FROM org_invitations i
+JOIN users u ON u.tenant_id = i.tenant_id AND lower(u.email) = lower(i.email)
+WHERE u.id = $1
+ORDER BY i.id;
+
+-- name: ListInvitationsSentBy :many
+-- Lists the invitations the user has sent
+SELECT * FROM org_invitations
+WHERE invited_by = $1
+ORDER BY id;
+
+-- name: DeleteInvitationsForUser :exec
+-- Deletes the invitations sent to the email of the user in the user's tenant
+-- Must run before the user's email is anonymized
+DELETE FROM org_invitations i
+USING users u
+WHERE u.id = $1 AND u.tenant_id = i.tenant_id AND lower(u.email) = lower(i.email);
+
+-- name: ClearInvitationsSentBy :exec
+-- Forgets who sent the invitations of the user
+UPDATE org_invitations
+SET invited_by = NULL
+WHERE invited_by = $1;
@@ -0,0 +1,90 @@
+-- name: CreateOutboxEvent :exec
+-- Writes an event to the outbox; the tenant defaults to the tenant of the connection
+INSERT INTO outbox (tenant_id, aggregate_type, aggregate_id, event_type, payload)
+VALUES (COALESCE(sqlc.narg(tenant_id)::INTEGER, current_tenant_id()), @aggregate_type, @aggregate_id, @event_type, @payload);
+
+-- name: ClaimOutboxEvents :many
+-- Leases the events that are due for publishing, taking only the oldest
+-- pending event of each aggregate, so that the events of an aggregate are
+-- published one after another in order. An event whose lease expired, because
+-- its relay stopped, is claimed again.
+-- SKIP LOCKED lets several relays run concurrently
+UPDATE outbox
+SET
+    locked_until = CURRENT_TIMESTAMP + make_interval(secs => @lease_seconds::DOUBLE PRECISION),
+    attempts = attempts + 1
+WHERE id IN (
+    SELECT o.id FROM outbox o
+    WHERE o.status = 'pending'
+        AND o.available_at <= CURRENT_TIMESTAMP
+        AND (o.locked_until IS NULL OR o.locked_until < CURRENT_TIMESTAMP)
+        AND NOT EXISTS (
+            SELECT 1 FROM outbox earlier
+            WHERE earlier.aggregate_type = o.aggregate_type
+                AND earlier.aggregate_id = o.aggregate_id
+                AND earlier.status = 'pending'
+                AND earlier.id < o.id
+        )
+    ORDER BY o.id
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxEventPublished :exec
+-- Marks the event as published by every sink
+UPDATE outbox
+SET
+    status = 'published',
+    published_at = CURRENT_TIMESTAMP,
+    locked_until = NULL,
+    last_error = NULL
+WHERE id = $1;
+
+-- name: RetryOutboxEvent :exec
+-- Releases the event after a failed attempt and postpones the next one
+UPDATE outbox
+SET
+    available_at = CURRENT_TIMESTAMP + make_interval(secs => @delay_seconds::DOUBLE PRECISION),
+    locked_until = NULL,
+    last_error = @last_error
+WHERE id = @id;
+
+-- name: DeadLetterOutboxEvent :exec
+-- Gives up on the event; the next event of the aggregate is published next
+UPDATE outbox
+SET
+    status = 'dead',
+    locked_until = NULL,
+    last_error = @last_error
+WHERE id = @id;
+
+-- name: ListDeadOutboxEvents :many
+-- Lists the events the relay gave up on, oldest first
+SELECT * FROM outbox
+WHERE status = 'dead'
+ORDER BY id
+LIMIT $1;
+
+-- name: RequeueDeadOutboxEvent :execrows
+-- Queues a dead event for publishing again with a fresh number of attempts
+UPDATE outbox
+SET
+    status = 'pending',
+    attempts = 0,
+    available_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'dead';
+
+-- name: RequeueDeadOutboxEvents :execrows
+-- Queues all dead events for publishing again
+UPDATE outbox
+SET
+    status = 'pending',
+    attempts = 0,
+    available_at = CURRENT_TIMESTAMP
+WHERE status = 'dead';
+
+-- name: DeletePublishedOutboxEvents :execrows
+-- Removes events published before the cutoff
+DELETE FROM outbox
+WHERE status = 'published' AND published_at < @published_before;
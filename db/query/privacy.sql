@@ -0,0 +1,99 @@
+-- name: CreateDataExport :one
+-- Creates a pending personal data export for the user
+INSERT INTO data_exports (user_id)
+VALUES ($1)
+RETURNING *;
+
+-- name: GetLatestDataExport :one
+-- Retrieves the most recent data export of the user
+SELECT * FROM data_exports
+WHERE user_id = $1
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: ClaimDataExport :one
+-- Marks the oldest pending export as processing and returns it
+-- Exports claimed over an hour ago and still processing are picked up again
+-- SKIP LOCKED lets several workers run concurrently
+UPDATE data_exports
+SET
+    status = 'processing',
+    claimed_at = CURRENT_TIMESTAMP
+WHERE id = (
+    SELECT id FROM data_exports
+    WHERE status = 'pending'
+        OR (status = 'processing' AND claimed_at < CURRENT_TIMESTAMP - INTERVAL '1 hour')
+    ORDER BY id
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteDataExport :exec
+-- Marks the export as ready for download until it expires
+UPDATE data_exports
+SET
+    status = 'ready',
+    file_path = @file_path,
+    completed_at = CURRENT_TIMESTAMP,
+    expires_at = @expires_at
+WHERE id = @id;
+
+-- name: FailDataExport :exec
+-- Marks the export as failed with the given error
+UPDATE data_exports
+SET
+    status = 'failed',
+    error = @error,
+    completed_at = CURRENT_TIMESTAMP
+WHERE id = @id;
+
+-- name: ListExpiredDataExports :many
+-- Lists ready exports whose download window has passed
+SELECT * FROM data_exports
+WHERE status = 'ready' AND expires_at < CURRENT_TIMESTAMP;
+
+-- name: ExpireDataExport :exec
+-- Marks the export as expired once its archive has been removed
+UPDATE data_exports
+SET
+    status = 'expired',
+    file_path = NULL
+WHERE id = $1;
+
+-- name: CreateErasureRequest :one
+-- Schedules erasure of the user's personal data
+-- Fails with a unique violation if a pending request already exists
+INSERT INTO erasure_requests (user_id, scheduled_for)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetPendingErasureRequest :one
+-- Retrieves the pending erasure request of the user
+SELECT * FROM erasure_requests
+WHERE user_id = $1 AND status = 'pending'
+LIMIT 1;
+
+-- name: CancelErasureRequest :one
+-- Cancels the pending erasure request of the user during the cooling-off period
+UPDATE erasure_requests
+SET
+    status = 'cancelled',
+    cancelled_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: ListDueErasureRequests :many
+-- Lists pending erasure requests whose cooling-off period has ended
+SELECT * FROM erasure_requests
+WHERE status = 'pending' AND scheduled_for <= CURRENT_TIMESTAMP
+ORDER BY scheduled_for
+LIMIT $1;
+
+-- name: CompleteErasureRequest :exec
+-- Marks the erasure request as completed
+UPDATE erasure_requests
+SET
+    status = 'completed',
+    completed_at = CURRENT_TIMESTAMP
+WHERE id = $1;
@@ -0,0 +1,12 @@
+-- name: GetTenantBySlug :one
+-- Retrieves a tenant by the slug used as subdomain or X-Tenant header
+SELECT * FROM tenants
+WHERE slug = $1;
+
+-- name: UpsertTenant :one
+-- Creates a tenant or renames the existing tenant with the same slug
+-- Used by seeding, which must be repeatable
+INSERT INTO tenants (slug, name)
+VALUES ($1, $2)
+ON CONFLICT (slug) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
@@ -9,74 +9,146 @@ INSERT INTO users (
 RETURNING *;
 
 -- name: GetUser :one
--- Retrieves a user by their ID
--- Returns a single user or null if not found
+-- Retrieves an active user by their ID
+-- Returns a single user or null if not found or deleted
 SELECT * FROM users
-WHERE id = $1 LIMIT 1;
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetUsersByIDs :many
+-- Retrieves the active users with the given IDs in no particular order
+-- IDs of missing or deleted users are skipped
+SELECT * FROM users
+WHERE id = ANY(@ids::int[]) AND deleted_at IS NULL;
 
 -- name: GetUserByUsername :one
--- Retrieves a user by their username
--- Returns a single user or null if not found
+-- Retrieves an active user by their username
+-- Returns a single user or null if not found or deleted
 SELECT * FROM users
-WHERE username = $1 LIMIT 1;
-
--- name: SearchUsers :many
--- Searches for users based on various criteria
--- Supports partial matching and date range for created_at
--- Allows sorting by different fields in ascending or descending order
--- Returns a paginated list of users
-SELECT *
-FROM users
-WHERE 
-    (@username::text IS NULL OR username ILIKE '%' || @username::text || '%')
-    AND (@email::text IS NULL OR email ILIKE '%' || @email::text || '%')
-    AND (@full_name::text IS NULL OR full_name ILIKE '%' || @full_name::text || '%')
-    AND (@bio::text IS NULL OR bio ILIKE '%' || @bio::text || '%')
-    AND (@created_from::timestamptz IS NULL OR DATE(created_at) >= @created_from::date)
-    AND (@created_to::timestamptz IS NULL OR DATE(created_at) <= @created_to::date)
-    AND (@search::text IS NULL OR 
-         LOWER(email) LIKE '%' || LOWER(@search::text) || '%' OR
-         LOWER(username) LIKE '%' || LOWER(@search::text) || '%' OR
-         LOWER(full_name) LIKE '%' || LOWER(@search::text) || '%' OR
-         LOWER(bio) LIKE '%' || LOWER(@search::text) || '%')
-ORDER BY
-    CASE 
-        WHEN @sort_by::text = 'username_asc' THEN username
-        WHEN @sort_by::text = 'email_asc' THEN email
-        WHEN @sort_by::text = 'created_at_asc' THEN NULL
-        WHEN @sort_by::text = 'id_asc' THEN NULL
-    END ASC,
-    CASE 
-        WHEN @sort_by::text = 'username_desc' THEN username
-        WHEN @sort_by::text = 'email_desc' THEN email
-        WHEN @sort_by::text = 'created_at_desc' THEN NULL
-        WHEN @sort_by::text = 'id_desc' THEN NULL
-    END DESC,
-    CASE WHEN @sort_by::text = 'created_at_asc' THEN created_at END ASC,
-    CASE WHEN @sort_by::text = 'id_asc' THEN id END ASC,
-    CASE WHEN @sort_by::text = 'created_at_desc' THEN created_at END DESC,
-    CASE WHEN @sort_by::text = 'id_desc' THEN id END DESC,
-    id ASC -- Always fallback sort by id
-LIMIT sqlc.narg('limit_param')::int
-OFFSET sqlc.narg('offset_param')::int;
+WHERE username = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: ListTakenUsernamesAndEmails :many
+-- Returns active users holding any of the given usernames or emails
+-- Used to report conflicts of bulk imports before writing
+SELECT username, email FROM users
+WHERE deleted_at IS NULL
+  AND (username = ANY(@usernames::text[]) OR email = ANY(@emails::text[]));
+
+-- User listings (search, count) are built dynamically in
+-- internal/user/search_query.go to support field selection
+
+-- name: EstimateUsersCount :one
+-- Returns the planner's row estimate for the users table from pg_class
+-- Much cheaper than COUNT(*) on large tables, but approximate and unfiltered
+-- Returns -1 if the table has never been analyzed
+SELECT reltuples::bigint AS estimate
+FROM pg_catalog.pg_class
+WHERE oid = 'users'::regclass;
 
 -- name: UpdateUser :one
 -- Updates user information for the specified user ID
--- Only updates non-null fields, leaving others unchanged
+-- Only updates non-null fields and non-empty username, email and password hash,
+-- leaving others unchanged
 -- Returns the updated user information
 UPDATE users
 SET
-    username = COALESCE(@username, username),
-    email = COALESCE(@email, email),
-    password_hash = COALESCE(@password_hash, password_hash),
+    username = COALESCE(NULLIF(@username::text, ''), username),
+    email = COALESCE(NULLIF(@email::text, ''), email),
+    password_hash = COALESCE(NULLIF(@password_hash::text, ''), password_hash),
     full_name = COALESCE(@full_name, full_name),
     bio = COALESCE(@bio, bio),
     updated_at = CURRENT_TIMESTAMP
-WHERE id = @id
+WHERE id = @id AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SetUserAdmin :one
+-- Grants or revokes the administrator role of an active user
+-- Returns the updated user
+UPDATE users
+SET is_admin = @is_admin, updated_at = CURRENT_TIMESTAMP
+WHERE id = @id AND deleted_at IS NULL
 RETURNING *;
 
 -- name: DeleteUser :exec
--- Deletes a user with the specified ID
+-- Soft-deletes a user with the specified ID
+-- The user can be restored until it is purged
+UPDATE users
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestoreUser :one
+-- Restores a soft-deleted user
+-- Fails with a unique violation if the username or email was taken meanwhile
+UPDATE users
+SET
+    deleted_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
+
+-- name: SetUserAvatar :one
+-- Sets or clears (NULL) the avatar of an active user
+-- Returns the updated user information
+UPDATE users
+SET
+    avatar_key = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: GetUserAvatarKey :one
+-- Returns the avatar key of a user, including deleted ones
+SELECT avatar_key FROM users
+WHERE id = $1;
+
+-- name: GetUserTenant :one
+-- Returns the tenant of a user, including deleted ones
+SELECT tenant_id FROM users
+WHERE id = $1;
+
+-- name: AnonymizeUser :exec
+-- Replaces the user's personal data with placeholders and marks the user deleted
+-- The empty password hash never matches, so the account cannot sign in again
+UPDATE users
+SET
+    username = 'erased_' || id,
+    email = 'erased_' || id || '@invalid',
+    password_hash = '',
+    full_name = NULL,
+    bio = NULL,
+    avatar_key = NULL,
+    settings = '{}'::jsonb,
+    is_admin = FALSE,
+    deleted_at = COALESCE(deleted_at, CURRENT_TIMESTAMP),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: PurgeDeletedUsers :many
+-- Permanently deletes users soft-deleted before the given time
+-- Owners of organizations are kept until the ownership is transferred, as
+-- deleting them would leave their organizations without an owner
+-- Returns the deleted users with their tenants, to record an event about
+-- each of them, and their avatar keys so their blobs can be removed
 -- This operation is irreversible
 DELETE FROM users
-WHERE id = $1;
\ No newline at end of file
+WHERE deleted_at IS NOT NULL AND deleted_at < @deleted_before
+    AND NOT EXISTS (
+        SELECT 1 FROM org_memberships m
+        WHERE m.user_id = users.id AND m.role = 'owner'
+    )
+RETURNING id, tenant_id, avatar_key;
+-- name: GetUserSettings :one
+-- Retrieves the settings the user has changed from the defaults
+SELECT settings FROM users
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: GetUserSettingsForUpdate :one
+-- Retrieves the user's settings and locks the row until the transaction ends
+SELECT settings FROM users
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: SetUserSettings :exec
+-- Replaces the settings the user has changed from the defaults
+UPDATE users
+SET settings = $2
+WHERE id = $1 AND deleted_at IS NULL;
@@ -0,0 +1,22 @@
+-- Application role of DATABASE_URL. Row-level security does not apply to
+-- superusers, roles with BYPASSRLS or the owners of the tables, so the
+-- application connects as a plain role that only reads and writes rows, while
+-- the owner of MIGRATE_DATABASE_URL changes the schema.
+--
+-- Run once as the owner, which must be allowed to create roles:
+--   psql "$MIGRATE_DATABASE_URL" -v app_password=... -f db/roles.sql
+-- The script is idempotent and may be run again, e.g. to change the password.
+
+SELECT 'CREATE ROLE app'
+WHERE NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'app')
+\gexec
+
+ALTER ROLE app LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE NOBYPASSRLS PASSWORD :'app_password';
+
+GRANT USAGE ON SCHEMA public TO app;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO app;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO app;
+
+-- tables and sequences created by later migrations of the owner
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO app;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO app;
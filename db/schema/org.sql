@@ -0,0 +1,63 @@
+-- Организации (команды клиентов)
+CREATE TABLE organizations (
+    id SERIAL PRIMARY KEY,
+    -- Арендатор; по умолчанию берется из настройки соединения app.tenant_id
+    tenant_id INTEGER NOT NULL DEFAULT current_tenant_id() REFERENCES tenants(id),
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Slug уникален в пределах арендатора
+    UNIQUE (tenant_id, slug)
+);
+
+-- Участники организаций и их роли: owner, admin, member
+CREATE TABLE org_memberships (
+    org_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Арендатор организации
+    tenant_id INTEGER NOT NULL DEFAULT current_tenant_id() REFERENCES tenants(id),
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, user_id)
+);
+
+-- Ровно один владелец на организацию
+CREATE UNIQUE INDEX org_memberships_owner_key ON org_memberships(org_id) WHERE role = 'owner';
+CREATE INDEX idx_org_memberships_user_id ON org_memberships(user_id);
+
+-- Приглашения в организацию по email; хранится только хеш токена из письма
+CREATE TABLE org_invitations (
+    id SERIAL PRIMARY KEY,
+    org_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    -- Арендатор организации; приглашение можно принять только в нем
+    tenant_id INTEGER NOT NULL DEFAULT current_tenant_id() REFERENCES tenants(id),
+    email VARCHAR(100) NOT NULL,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('admin', 'member')),
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Не более одного активного приглашения на email в организации
+CREATE UNIQUE INDEX org_invitations_pending_key ON org_invitations(org_id, lower(email)) WHERE status = 'pending';
+
+-- Организации, участники и приглашения изолируются так же, как users
+ALTER TABLE organizations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE organizations FORCE ROW LEVEL SECURITY;
+CREATE POLICY organizations_tenant_isolation ON organizations
+    USING (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id())
+    WITH CHECK (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id());
+ALTER TABLE org_memberships ENABLE ROW LEVEL SECURITY;
+ALTER TABLE org_memberships FORCE ROW LEVEL SECURITY;
+CREATE POLICY org_memberships_tenant_isolation ON org_memberships
+    USING (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id())
+    WITH CHECK (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id());
+ALTER TABLE org_invitations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE org_invitations FORCE ROW LEVEL SECURITY;
+CREATE POLICY org_invitations_tenant_isolation ON org_invitations
+    USING (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id())
+    WITH CHECK (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id());
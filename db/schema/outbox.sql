@@ -0,0 +1,36 @@
+-- Доменные события, ожидающие публикации (transactional outbox).
+-- Событие записывается в той же транзакции, что и изменение, которое оно
+-- описывает, а релей публикует его после фиксации
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    -- Арендатор, к данным которого относится событие; NULL для общих событий
+    tenant_id INTEGER REFERENCES tenants(id),
+    -- Сущность, изменение которой описывает событие, например user и ее ID;
+    -- события одной сущности публикуются в порядке id
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id VARCHAR(100) NOT NULL,
+    -- Тип события, например user.created
+    event_type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    -- pending, published или dead — исчерпаны попытки публикации
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Момент следующей попытки публикации
+    available_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Событие захвачено релеем до этого момента
+    locked_until TIMESTAMP WITH TIME ZONE,
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_outbox_pending ON outbox(aggregate_type, aggregate_id, id) WHERE status = 'pending';
+CREATE INDEX idx_outbox_published_at ON outbox(published_at) WHERE status = 'published';
+CREATE INDEX idx_outbox_dead ON outbox(id) WHERE status = 'dead';
+
+-- События содержат данные арендатора и изолируются так же, как users
+ALTER TABLE outbox ENABLE ROW LEVEL SECURITY;
+ALTER TABLE outbox FORCE ROW LEVEL SECURITY;
+CREATE POLICY outbox_tenant_isolation ON outbox
+    USING (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id())
+    WITH CHECK (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id());
@@ -0,0 +1,16 @@
+-- Клиенты (арендаторы), данные которых хранятся в одной базе
+CREATE TABLE tenants (
+    id SERIAL PRIMARY KEY,
+    -- Поддомен и значение заголовка X-Tenant
+    slug VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Арендатор текущего соединения из настройки app.tenant_id;
+-- NULL, если арендатор не задан или соединение работает со всеми арендаторами ('*')
+CREATE FUNCTION current_tenant_id() RETURNS INTEGER
+LANGUAGE sql STABLE
+AS $$
+    SELECT NULLIF(NULLIF(current_setting('app.tenant_id', true), ''), '*')::INTEGER
+$$;
@@ -1,18 +1,58 @@
--- Удаление существующей таблицы, если она существует
-DROP TABLE IF EXISTS users;
+-- Расширение для нечеткого поиска по триграммам
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
 
 -- Создание таблицы users
 CREATE TABLE users (
     id SERIAL PRIMARY KEY,
-    username VARCHAR(50) UNIQUE NOT NULL,
-    email VARCHAR(100) UNIQUE NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    email VARCHAR(100) NOT NULL,
     password_hash VARCHAR(255) NOT NULL,
     full_name VARCHAR(100),
     bio TEXT,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    -- Лексемы всех поддерживаемых конфигураций, чтобы один GIN-индекс
+    -- обслуживал поиск в simple, english и russian
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(username, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(full_name, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(full_name, '')), 'A') ||
+        setweight(to_tsvector('russian', coalesce(full_name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(email, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(bio, '')), 'C') ||
+        setweight(to_tsvector('english', coalesce(bio, '')), 'C') ||
+        setweight(to_tsvector('russian', coalesce(bio, '')), 'C')
+    ) STORED,
+    -- Момент мягкого удаления; NULL для активных пользователей
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    is_admin BOOLEAN NOT NULL DEFAULT FALSE,
+    -- Префикс ключей миниатюр аватара в хранилище; NULL, если аватара нет
+    avatar_key TEXT,
+    -- Арендатор; по умолчанию берется из настройки соединения app.tenant_id
+    tenant_id INTEGER NOT NULL DEFAULT current_tenant_id() REFERENCES tenants(id),
+    -- Настройки, заданные пользователем; значения по умолчанию определены в коде
+    settings JSONB NOT NULL DEFAULT '{}'::jsonb CHECK (jsonb_typeof(settings) = 'object')
 );
 
 -- Создание индексов
+-- Уникальность только среди активных пользователей, чтобы имена и email
+-- удаленных пользователей можно было использовать повторно
+CREATE UNIQUE INDEX users_username_active_key ON users(tenant_id, username) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX users_email_active_key ON users(tenant_id, email) WHERE deleted_at IS NULL;
+CREATE INDEX idx_users_tenant_id ON users(tenant_id);
+CREATE INDEX idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
 CREATE INDEX idx_users_username ON users(username);
-CREATE INDEX idx_users_email ON users(email);
\ No newline at end of file
+CREATE INDEX idx_users_email ON users(email);
+CREATE INDEX idx_users_search_vector ON users USING GIN (search_vector);
+CREATE INDEX idx_users_username_trgm ON users USING GIN (username gin_trgm_ops);
+CREATE INDEX idx_users_email_trgm ON users USING GIN (email gin_trgm_ops);
+CREATE INDEX idx_users_full_name_trgm ON users USING GIN (full_name gin_trgm_ops);
+
+-- Изоляция арендаторов: соединение видит только строки своего арендатора,
+-- а без app.tenant_id не видит ничего. FORCE распространяет политику и на
+-- владельца таблицы; суперпользователи и роли с BYPASSRLS ее игнорируют.
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+CREATE POLICY users_tenant_isolation ON users
+    USING (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id())
+    WITH CHECK (current_setting('app.tenant_id', true) = '*' OR tenant_id = current_tenant_id());
@@ -1,24 +1,64 @@
 package app
 
 import (
-	"database/sql"
+	"context"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/org"
+	"github.com/malytinKonstantin/go-fiber/internal/outbox"
+	"github.com/malytinKonstantin/go-fiber/internal/privacy"
+	"github.com/malytinKonstantin/go-fiber/internal/seed"
+	"github.com/malytinKonstantin/go-fiber/internal/storage"
+	"github.com/malytinKonstantin/go-fiber/internal/tenant"
 	"github.com/malytinKonstantin/go-fiber/internal/user"
 )
 
 type App struct {
-	UserModule *user.Module
-	DB         *sql.DB
+	UserModule    *user.Module
+	OrgModule     *org.Module
+	PrivacyModule *privacy.Module
+	OutboxModule  *outbox.Module
+	Blobs         storage.BlobStore
+	Tenants       *tenant.TenantResolver
+	DB            *db.DB
+	Seeder        *seed.Seeder
 }
 
-func NewApp(userModule *user.Module, db *sql.DB) *App {
+func NewApp(userModule *user.Module, orgModule *org.Module, privacyModule *privacy.Module, outboxModule *outbox.Module, blobs storage.BlobStore, tenants *tenant.TenantResolver, database *db.DB, seeder *seed.Seeder) *App {
 	return &App{
-		UserModule: userModule,
-		DB:         db,
+		UserModule:    userModule,
+		OrgModule:     orgModule,
+		PrivacyModule: privacyModule,
+		OutboxModule:  outboxModule,
+		Blobs:         blobs,
+		Tenants:       tenants,
+		DB:            database,
+		Seeder:        seeder,
 	}
 }
 
 func (a *App) SetupRoutes(router fiber.Router) {
 	a.UserModule.SetupRoutes(router)
+	a.OrgModule.SetupRoutes(router)
+	a.PrivacyModule.SetupRoutes(router)
+}
+
+// SetupStatic serves uploaded files when they are kept on the local filesystem
+func (a *App) SetupStatic(router fiber.Router) {
+	if local, ok := a.Blobs.(*storage.LocalStore); ok && strings.HasPrefix(local.PublicURL(), "/") {
+		router.Static(local.PublicURL(), local.Root())
+	}
+}
+
+// StartJobs launches the background jobs of all modules, the outbox relay
+// and the replica health checks. Jobs work across tenants, so their queries are not scoped
+// to one.
+func (a *App) StartJobs(ctx context.Context) {
+	ctx = db.WithAllTenants(ctx)
+	a.DB.StartReplicaChecks(ctx)
+	a.UserModule.StartJobs(ctx)
+	a.PrivacyModule.StartJobs(ctx)
+	a.OutboxModule.StartJobs(ctx)
 }
@@ -0,0 +1,19 @@
+package app
+
+import (
+	"github.com/malytinKonstantin/go-fiber/internal/org"
+	"github.com/malytinKonstantin/go-fiber/internal/privacy"
+	"github.com/malytinKonstantin/go-fiber/internal/user"
+)
+
+// NewPrivacyRegistry registers every module that holds personal data. The
+// user module comes last, as the others find the user's data by the email
+// its eraser replaces.
+func NewPrivacyRegistry(orgs *org.PrivacyParticipant, users *user.PrivacyParticipant) *privacy.Registry {
+	registry := privacy.NewRegistry()
+	registry.RegisterExporter("org", orgs)
+	registry.RegisterEraser("org", orgs)
+	registry.RegisterExporter("user", users)
+	registry.RegisterEraser("user", users)
+	return registry
+}
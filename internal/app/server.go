@@ -0,0 +1,35 @@
+package app
+
+import (
+	"encoding/json"
+	"expvar"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/gofiber/swagger"
+	_ "github.com/malytinKonstantin/go-fiber/docs"
+	"github.com/malytinKonstantin/go-fiber/internal/middleware"
+)
+
+// NewFiberApp builds the HTTP server: the API routes under apiPrefix behind
+// the validation, auth, tenant and read-your-writes middleware, uploaded
+// files, the Swagger UI and, for admins only, the metrics under
+// apiPrefix/admin/debug/vars
+func (a *App) NewFiberApp(apiPrefix string) *fiber.App {
+	fiberApp := fiber.New(fiber.Config{
+		JSONDecoder: json.Unmarshal,
+	})
+	a.SetupStatic(fiberApp)
+	api := fiberApp.Group(apiPrefix)
+	api.Use(middleware.ValidateDTO())
+	api.Use(middleware.AuthMiddleware())
+	api.Use(middleware.TenantMiddleware(a.Tenants))
+	api.Use(middleware.ReadYourWritesMiddleware(a.DB))
+	a.SetupRoutes(api)
+	// runtime, connection pool, transaction retry and outbox metrics; they
+	// reveal the command line and the load of the service, so they are not public
+	api.Get("/admin/debug/vars", middleware.AdminOnly(adaptor.HTTPHandler(expvar.Handler())))
+
+	fiberApp.Get("/swagger/*", swagger.HandlerDefault)
+	return fiberApp
+}
@@ -0,0 +1,66 @@
+//go:build wireinject
+// +build wireinject
+
+package app
+
+import (
+	"github.com/google/wire"
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/mail"
+	"github.com/malytinKonstantin/go-fiber/internal/org"
+	"github.com/malytinKonstantin/go-fiber/internal/outbox"
+	"github.com/malytinKonstantin/go-fiber/internal/privacy"
+	"github.com/malytinKonstantin/go-fiber/internal/seed"
+	"github.com/malytinKonstantin/go-fiber/internal/storage"
+	"github.com/malytinKonstantin/go-fiber/internal/tenant"
+	"github.com/malytinKonstantin/go-fiber/internal/user"
+)
+
+var PostgresSet = wire.NewSet(
+	db.NewPostgresPool,
+	db.NewDB,
+	db.NewTxManager,
+	wire.Bind(new(db.Transactor), new(*db.TxManager)),
+)
+
+var AppSet = wire.NewSet(
+	PostgresSet,
+	storage.NewBlobStore,
+	mail.NewMailer,
+	tenant.NewTenantResolver,
+	tenant.NewTenantRepository,
+	NewApp,
+	outbox.NewModule,
+	outbox.NewOutbox,
+	outbox.NewOutboxRepository,
+	outbox.NewBus,
+	outbox.NewSinks,
+	outbox.NewRelay,
+	wire.Bind(new(outbox.Recorder), new(*outbox.Outbox)),
+	user.NewModule,
+	user.NewPurgeJob,
+	user.NewUserController,
+	user.NewUserService,
+	user.NewUserImporter,
+	user.NewUserRepository,
+	wire.Bind(new(user.Repository), new(*user.UserRepository)),
+	user.NewPrivacyParticipant,
+	org.NewModule,
+	org.NewOrgController,
+	org.NewOrgService,
+	org.NewOrgRepository,
+	org.NewPrivacyParticipant,
+	NewPrivacyRegistry,
+	privacy.NewModule,
+	privacy.NewPrivacyWorker,
+	privacy.NewPrivacyController,
+	privacy.NewPrivacyService,
+	privacy.NewPrivacyRepository,
+	seed.NewSeeder,
+)
+
+// InitializeApp builds the application on the database of databaseURL
+func InitializeApp(databaseURL string) (*App, error) {
+	wire.Build(AppSet)
+	return nil, nil
+}
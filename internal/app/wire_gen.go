@@ -0,0 +1,87 @@
+// Code generated by Wire. DO NOT EDIT.
+
+//go:generate go run -mod=mod github.com/google/wire/cmd/wire
+//go:build !wireinject
+// +build !wireinject
+
+package app
+
+import (
+	"github.com/google/wire"
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/mail"
+	"github.com/malytinKonstantin/go-fiber/internal/org"
+	"github.com/malytinKonstantin/go-fiber/internal/outbox"
+	"github.com/malytinKonstantin/go-fiber/internal/privacy"
+	"github.com/malytinKonstantin/go-fiber/internal/seed"
+	"github.com/malytinKonstantin/go-fiber/internal/storage"
+	"github.com/malytinKonstantin/go-fiber/internal/tenant"
+	"github.com/malytinKonstantin/go-fiber/internal/user"
+)
+
+import (
+	_ "github.com/malytinKonstantin/go-fiber/docs"
+)
+
+// Injectors from wire.go:
+
+// InitializeApp builds the application on the database of databaseURL
+func InitializeApp(databaseURL string) (*App, error) {
+	pool, err := db.NewPostgresPool(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	dbDB, err := db.NewDB(pool)
+	if err != nil {
+		return nil, err
+	}
+	txManager, err := db.NewTxManager(dbDB)
+	if err != nil {
+		return nil, err
+	}
+	userRepository := user.NewUserRepository(dbDB, txManager)
+	blobStore, err := storage.NewBlobStore()
+	if err != nil {
+		return nil, err
+	}
+	outboxRepository := outbox.NewOutboxRepository(dbDB)
+	outboxOutbox := outbox.NewOutbox(outboxRepository)
+	userService := user.NewUserService(userRepository, txManager, blobStore, outboxOutbox)
+	userImporter := user.NewUserImporter(userService)
+	userController := user.NewUserController(userService, userImporter)
+	purgeJob := user.NewPurgeJob(userService)
+	module := user.NewModule(userController, userImporter, purgeJob)
+	orgRepository := org.NewOrgRepository(dbDB)
+	mailer := mail.NewMailer()
+	orgService := org.NewOrgService(orgRepository, txManager, userService, mailer)
+	orgController := org.NewOrgController(orgService)
+	orgModule := org.NewModule(orgController)
+	privacyRepository := privacy.NewPrivacyRepository(dbDB)
+	privacyParticipant := org.NewPrivacyParticipant(orgRepository, txManager)
+	userPrivacyParticipant := user.NewPrivacyParticipant(userService)
+	registry := NewPrivacyRegistry(privacyParticipant, userPrivacyParticipant)
+	privacyService := privacy.NewPrivacyService(privacyRepository, registry)
+	privacyController := privacy.NewPrivacyController(privacyService)
+	privacyWorker := privacy.NewPrivacyWorker(privacyService)
+	privacyModule := privacy.NewModule(privacyController, privacyWorker)
+	bus := outbox.NewBus()
+	v, err := outbox.NewSinks(bus)
+	if err != nil {
+		return nil, err
+	}
+	relay := outbox.NewRelay(outboxOutbox, v)
+	outboxModule := outbox.NewModule(outboxOutbox, bus, relay)
+	tenantRepository := tenant.NewTenantRepository(dbDB)
+	tenantResolver := tenant.NewTenantResolver(tenantRepository)
+	seeder := seed.NewSeeder(userService, tenantRepository, txManager)
+	app := NewApp(module, orgModule, privacyModule, outboxModule, blobStore, tenantResolver, dbDB, seeder)
+	return app, nil
+}
+
+// wire.go:
+
+var PostgresSet = wire.NewSet(db.NewPostgresPool, db.NewDB, db.NewTxManager, wire.Bind(new(db.Transactor), new(*db.TxManager)))
+
+var AppSet = wire.NewSet(
+	PostgresSet, storage.NewBlobStore, mail.NewMailer, tenant.NewTenantResolver, tenant.NewTenantRepository, NewApp, outbox.NewModule, outbox.NewOutbox, outbox.NewOutboxRepository, outbox.NewBus, outbox.NewSinks, outbox.NewRelay, wire.Bind(new(outbox.Recorder), new(*outbox.Outbox)), user.NewModule, user.NewPurgeJob, user.NewUserController, user.NewUserService, user.NewUserImporter, user.NewUserRepository, wire.Bind(new(user.Repository), new(*user.UserRepository)), user.NewPrivacyParticipant, org.NewModule, org.NewOrgController, org.NewOrgService, org.NewOrgRepository, org.NewPrivacyParticipant, NewPrivacyRegistry, privacy.NewModule, privacy.NewPrivacyWorker, privacy.NewPrivacyController, privacy.NewPrivacyService, privacy.NewPrivacyRepository, seed.NewSeeder,
+)
@@ -11,18 +11,24 @@ import (
 var jwtKey = []byte(viper.GetString("JWT_SECRET"))
 
 type Claims struct {
-	UserID int32 `json:"user_id"`
+	UserID   int32 `json:"user_id"`
+	IsAdmin  bool  `json:"is_admin,omitempty"`
+	TenantID int32 `json:"tenant_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
 type User struct {
-	ID int32
+	ID       int32
+	IsAdmin  bool
+	TenantID int32
 }
 
 func GenerateToken(user User) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &Claims{
-		UserID: user.ID,
+		UserID:   user.ID,
+		IsAdmin:  user.IsAdmin,
+		TenantID: user.TenantID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/malytinKonstantin/go-fiber/internal/shared"
+	"github.com/spf13/viper"
+)
+
+// DB is the database handle shared by all repositories. Connections are
+// borrowed from the single pgx pool of the primary, and every statement runs
+// in the transaction carried by the context, if there is one (see TxManager),
+// so repositories take part in a transaction without being aware of it.
+// Reads that may be served by read replicas go through Reader.
+type DB struct {
+	pool  *pgxpool.Pool
+	sqlDB *sql.DB
+
+	replicas    []*replica
+	nextReplica atomic.Uint32
+	pinned      *shared.TTLCache[string, struct{}]
+}
+
+// NewDB wraps the primary pool and opens the replicas listed in
+// DATABASE_REPLICA_URLS. The pool stats are published with expvar.
+func NewDB(pool *pgxpool.Pool) (*DB, error) {
+	replicas, err := newReplicas()
+	if err != nil {
+		return nil, err
+	}
+	window := viper.GetDuration("DB_READ_YOUR_WRITES_WINDOW")
+	if window <= 0 {
+		window = defaultReadYourWritesWindow
+	}
+	d := &DB{
+		pool:     pool,
+		sqlDB:    stdlib.OpenDBFromPool(pool),
+		replicas: replicas,
+		pinned:   shared.NewTTLCache[string, struct{}](window, maxPinnedSessions),
+	}
+	monitoredDB.Store(d)
+	return d, nil
+}
+
+// Queries returns the sqlc queries bound to the handle
+func (d *DB) Queries() *Queries {
+	return New(d)
+}
+
+// SQL returns the database/sql handle for code that manages its own
+// connections, such as migrations; it ignores the ambient transaction
+func (d *DB) SQL() *sql.DB {
+	return d.sqlDB
+}
+
+func (d *DB) Pool() *pgxpool.Pool {
+	return d.pool
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if state := txFromContext(ctx); state != nil {
+		return state.tx.ExecContext(ctx, query, args...)
+	}
+	return d.sqlDB.ExecContext(ctx, query, args...)
+}
+
+func (d *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if state := txFromContext(ctx); state != nil {
+		return state.tx.PrepareContext(ctx, query)
+	}
+	return d.sqlDB.PrepareContext(ctx, query)
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if state := txFromContext(ctx); state != nil {
+		return state.tx.QueryContext(ctx, query, args...)
+	}
+	return d.sqlDB.QueryContext(ctx, query, args...)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if state := txFromContext(ctx); state != nil {
+		return state.tx.QueryRowContext(ctx, query, args...)
+	}
+	return d.sqlDB.QueryRowContext(ctx, query, args...)
+}
+
+// WithPgxConn runs fn on a pgx connection for features database/sql lacks,
+// such as COPY. Inside a transaction fn gets the transaction's connection,
+// so its statements are part of the transaction; otherwise a connection is
+// acquired from the pool for the duration of fn.
+func (d *DB) WithPgxConn(ctx context.Context, fn func(conn *pgx.Conn) error) error {
+	if state := txFromContext(ctx); state != nil {
+		return state.conn.Raw(func(driverConn any) error {
+			stdConn, ok := driverConn.(*stdlib.Conn)
+			if !ok {
+				return errors.New("db: transaction connection is not a pgx connection")
+			}
+			return fn(stdConn.Conn())
+		})
+	}
+
+	conn, err := d.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	return fn(conn.Conn())
+}
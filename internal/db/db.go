@@ -24,52 +24,487 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.addMembershipStmt, err = db.PrepareContext(ctx, AddMembership); err != nil {
+		return nil, fmt.Errorf("error preparing query AddMembership: %w", err)
+	}
+	if q.anonymizeUserStmt, err = db.PrepareContext(ctx, AnonymizeUser); err != nil {
+		return nil, fmt.Errorf("error preparing query AnonymizeUser: %w", err)
+	}
+	if q.cancelErasureRequestStmt, err = db.PrepareContext(ctx, CancelErasureRequest); err != nil {
+		return nil, fmt.Errorf("error preparing query CancelErasureRequest: %w", err)
+	}
+	if q.claimDataExportStmt, err = db.PrepareContext(ctx, ClaimDataExport); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimDataExport: %w", err)
+	}
+	if q.claimOutboxEventsStmt, err = db.PrepareContext(ctx, ClaimOutboxEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimOutboxEvents: %w", err)
+	}
+	if q.clearInvitationsSentByStmt, err = db.PrepareContext(ctx, ClearInvitationsSentBy); err != nil {
+		return nil, fmt.Errorf("error preparing query ClearInvitationsSentBy: %w", err)
+	}
+	if q.completeDataExportStmt, err = db.PrepareContext(ctx, CompleteDataExport); err != nil {
+		return nil, fmt.Errorf("error preparing query CompleteDataExport: %w", err)
+	}
+	if q.completeErasureRequestStmt, err = db.PrepareContext(ctx, CompleteErasureRequest); err != nil {
+		return nil, fmt.Errorf("error preparing query CompleteErasureRequest: %w", err)
+	}
+	if q.createDataExportStmt, err = db.PrepareContext(ctx, CreateDataExport); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateDataExport: %w", err)
+	}
+	if q.createErasureRequestStmt, err = db.PrepareContext(ctx, CreateErasureRequest); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateErasureRequest: %w", err)
+	}
+	if q.createInvitationStmt, err = db.PrepareContext(ctx, CreateInvitation); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateInvitation: %w", err)
+	}
+	if q.createOrganizationStmt, err = db.PrepareContext(ctx, CreateOrganization); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateOrganization: %w", err)
+	}
+	if q.createOutboxEventStmt, err = db.PrepareContext(ctx, CreateOutboxEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateOutboxEvent: %w", err)
+	}
 	if q.createUserStmt, err = db.PrepareContext(ctx, CreateUser); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateUser: %w", err)
 	}
+	if q.deadLetterOutboxEventStmt, err = db.PrepareContext(ctx, DeadLetterOutboxEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query DeadLetterOutboxEvent: %w", err)
+	}
+	if q.deleteInvitationsForUserStmt, err = db.PrepareContext(ctx, DeleteInvitationsForUser); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteInvitationsForUser: %w", err)
+	}
+	if q.deleteMembershipStmt, err = db.PrepareContext(ctx, DeleteMembership); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteMembership: %w", err)
+	}
+	if q.deleteOrganizationStmt, err = db.PrepareContext(ctx, DeleteOrganization); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrganization: %w", err)
+	}
+	if q.deletePublishedOutboxEventsStmt, err = db.PrepareContext(ctx, DeletePublishedOutboxEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query DeletePublishedOutboxEvents: %w", err)
+	}
 	if q.deleteUserStmt, err = db.PrepareContext(ctx, DeleteUser); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteUser: %w", err)
 	}
+	if q.estimateUsersCountStmt, err = db.PrepareContext(ctx, EstimateUsersCount); err != nil {
+		return nil, fmt.Errorf("error preparing query EstimateUsersCount: %w", err)
+	}
+	if q.expireDataExportStmt, err = db.PrepareContext(ctx, ExpireDataExport); err != nil {
+		return nil, fmt.Errorf("error preparing query ExpireDataExport: %w", err)
+	}
+	if q.expireInvitationsForEmailStmt, err = db.PrepareContext(ctx, ExpireInvitationsForEmail); err != nil {
+		return nil, fmt.Errorf("error preparing query ExpireInvitationsForEmail: %w", err)
+	}
+	if q.failDataExportStmt, err = db.PrepareContext(ctx, FailDataExport); err != nil {
+		return nil, fmt.Errorf("error preparing query FailDataExport: %w", err)
+	}
+	if q.getInvitationByTokenHashStmt, err = db.PrepareContext(ctx, GetInvitationByTokenHash); err != nil {
+		return nil, fmt.Errorf("error preparing query GetInvitationByTokenHash: %w", err)
+	}
+	if q.getLatestDataExportStmt, err = db.PrepareContext(ctx, GetLatestDataExport); err != nil {
+		return nil, fmt.Errorf("error preparing query GetLatestDataExport: %w", err)
+	}
+	if q.getMembershipStmt, err = db.PrepareContext(ctx, GetMembership); err != nil {
+		return nil, fmt.Errorf("error preparing query GetMembership: %w", err)
+	}
+	if q.getOrganizationStmt, err = db.PrepareContext(ctx, GetOrganization); err != nil {
+		return nil, fmt.Errorf("error preparing query GetOrganization: %w", err)
+	}
+	if q.getPendingErasureRequestStmt, err = db.PrepareContext(ctx, GetPendingErasureRequest); err != nil {
+		return nil, fmt.Errorf("error preparing query GetPendingErasureRequest: %w", err)
+	}
+	if q.getTenantBySlugStmt, err = db.PrepareContext(ctx, GetTenantBySlug); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTenantBySlug: %w", err)
+	}
 	if q.getUserStmt, err = db.PrepareContext(ctx, GetUser); err != nil {
 		return nil, fmt.Errorf("error preparing query GetUser: %w", err)
 	}
+	if q.getUserAvatarKeyStmt, err = db.PrepareContext(ctx, GetUserAvatarKey); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserAvatarKey: %w", err)
+	}
 	if q.getUserByUsernameStmt, err = db.PrepareContext(ctx, GetUserByUsername); err != nil {
 		return nil, fmt.Errorf("error preparing query GetUserByUsername: %w", err)
 	}
-	if q.searchUsersStmt, err = db.PrepareContext(ctx, SearchUsers); err != nil {
-		return nil, fmt.Errorf("error preparing query SearchUsers: %w", err)
+	if q.getUserSettingsStmt, err = db.PrepareContext(ctx, GetUserSettings); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserSettings: %w", err)
+	}
+	if q.getUserSettingsForUpdateStmt, err = db.PrepareContext(ctx, GetUserSettingsForUpdate); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserSettingsForUpdate: %w", err)
+	}
+	if q.getUserTenantStmt, err = db.PrepareContext(ctx, GetUserTenant); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserTenant: %w", err)
+	}
+	if q.getUsersByIDsStmt, err = db.PrepareContext(ctx, GetUsersByIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUsersByIDs: %w", err)
+	}
+	if q.listDeadOutboxEventsStmt, err = db.PrepareContext(ctx, ListDeadOutboxEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query ListDeadOutboxEvents: %w", err)
+	}
+	if q.listDueErasureRequestsStmt, err = db.PrepareContext(ctx, ListDueErasureRequests); err != nil {
+		return nil, fmt.Errorf("error preparing query ListDueErasureRequests: %w", err)
+	}
+	if q.listExpiredDataExportsStmt, err = db.PrepareContext(ctx, ListExpiredDataExports); err != nil {
+		return nil, fmt.Errorf("error preparing query ListExpiredDataExports: %w", err)
+	}
+	if q.listInvitationsForUserStmt, err = db.PrepareContext(ctx, ListInvitationsForUser); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInvitationsForUser: %w", err)
+	}
+	if q.listInvitationsSentByStmt, err = db.PrepareContext(ctx, ListInvitationsSentBy); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInvitationsSentBy: %w", err)
+	}
+	if q.listMembersStmt, err = db.PrepareContext(ctx, ListMembers); err != nil {
+		return nil, fmt.Errorf("error preparing query ListMembers: %w", err)
+	}
+	if q.listOrganizationsByUserIDsStmt, err = db.PrepareContext(ctx, ListOrganizationsByUserIDs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListOrganizationsByUserIDs: %w", err)
+	}
+	if q.listPendingInvitationsStmt, err = db.PrepareContext(ctx, ListPendingInvitations); err != nil {
+		return nil, fmt.Errorf("error preparing query ListPendingInvitations: %w", err)
+	}
+	if q.listTakenUsernamesAndEmailsStmt, err = db.PrepareContext(ctx, ListTakenUsernamesAndEmails); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTakenUsernamesAndEmails: %w", err)
+	}
+	if q.listUserOrganizationsStmt, err = db.PrepareContext(ctx, ListUserOrganizations); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUserOrganizations: %w", err)
+	}
+	if q.markOutboxEventPublishedStmt, err = db.PrepareContext(ctx, MarkOutboxEventPublished); err != nil {
+		return nil, fmt.Errorf("error preparing query MarkOutboxEventPublished: %w", err)
+	}
+	if q.purgeDeletedUsersStmt, err = db.PrepareContext(ctx, PurgeDeletedUsers); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeDeletedUsers: %w", err)
+	}
+	if q.requeueDeadOutboxEventStmt, err = db.PrepareContext(ctx, RequeueDeadOutboxEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query RequeueDeadOutboxEvent: %w", err)
+	}
+	if q.requeueDeadOutboxEventsStmt, err = db.PrepareContext(ctx, RequeueDeadOutboxEvents); err != nil {
+		return nil, fmt.Errorf("error preparing query RequeueDeadOutboxEvents: %w", err)
+	}
+	if q.respondToInvitationStmt, err = db.PrepareContext(ctx, RespondToInvitation); err != nil {
+		return nil, fmt.Errorf("error preparing query RespondToInvitation: %w", err)
+	}
+	if q.restoreUserStmt, err = db.PrepareContext(ctx, RestoreUser); err != nil {
+		return nil, fmt.Errorf("error preparing query RestoreUser: %w", err)
+	}
+	if q.retryOutboxEventStmt, err = db.PrepareContext(ctx, RetryOutboxEvent); err != nil {
+		return nil, fmt.Errorf("error preparing query RetryOutboxEvent: %w", err)
+	}
+	if q.setUserAdminStmt, err = db.PrepareContext(ctx, SetUserAdmin); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserAdmin: %w", err)
+	}
+	if q.setUserAvatarStmt, err = db.PrepareContext(ctx, SetUserAvatar); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserAvatar: %w", err)
+	}
+	if q.setUserSettingsStmt, err = db.PrepareContext(ctx, SetUserSettings); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserSettings: %w", err)
+	}
+	if q.updateMembershipRoleStmt, err = db.PrepareContext(ctx, UpdateMembershipRole); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateMembershipRole: %w", err)
+	}
+	if q.updateOrganizationStmt, err = db.PrepareContext(ctx, UpdateOrganization); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateOrganization: %w", err)
 	}
 	if q.updateUserStmt, err = db.PrepareContext(ctx, UpdateUser); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateUser: %w", err)
 	}
+	if q.upsertTenantStmt, err = db.PrepareContext(ctx, UpsertTenant); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertTenant: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.addMembershipStmt != nil {
+		if cerr := q.addMembershipStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addMembershipStmt: %w", cerr)
+		}
+	}
+	if q.anonymizeUserStmt != nil {
+		if cerr := q.anonymizeUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing anonymizeUserStmt: %w", cerr)
+		}
+	}
+	if q.cancelErasureRequestStmt != nil {
+		if cerr := q.cancelErasureRequestStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing cancelErasureRequestStmt: %w", cerr)
+		}
+	}
+	if q.claimDataExportStmt != nil {
+		if cerr := q.claimDataExportStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimDataExportStmt: %w", cerr)
+		}
+	}
+	if q.claimOutboxEventsStmt != nil {
+		if cerr := q.claimOutboxEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimOutboxEventsStmt: %w", cerr)
+		}
+	}
+	if q.clearInvitationsSentByStmt != nil {
+		if cerr := q.clearInvitationsSentByStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing clearInvitationsSentByStmt: %w", cerr)
+		}
+	}
+	if q.completeDataExportStmt != nil {
+		if cerr := q.completeDataExportStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing completeDataExportStmt: %w", cerr)
+		}
+	}
+	if q.completeErasureRequestStmt != nil {
+		if cerr := q.completeErasureRequestStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing completeErasureRequestStmt: %w", cerr)
+		}
+	}
+	if q.createDataExportStmt != nil {
+		if cerr := q.createDataExportStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createDataExportStmt: %w", cerr)
+		}
+	}
+	if q.createErasureRequestStmt != nil {
+		if cerr := q.createErasureRequestStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createErasureRequestStmt: %w", cerr)
+		}
+	}
+	if q.createInvitationStmt != nil {
+		if cerr := q.createInvitationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createInvitationStmt: %w", cerr)
+		}
+	}
+	if q.createOrganizationStmt != nil {
+		if cerr := q.createOrganizationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createOrganizationStmt: %w", cerr)
+		}
+	}
+	if q.createOutboxEventStmt != nil {
+		if cerr := q.createOutboxEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createOutboxEventStmt: %w", cerr)
+		}
+	}
 	if q.createUserStmt != nil {
 		if cerr := q.createUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createUserStmt: %w", cerr)
 		}
 	}
+	if q.deadLetterOutboxEventStmt != nil {
+		if cerr := q.deadLetterOutboxEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deadLetterOutboxEventStmt: %w", cerr)
+		}
+	}
+	if q.deleteInvitationsForUserStmt != nil {
+		if cerr := q.deleteInvitationsForUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteInvitationsForUserStmt: %w", cerr)
+		}
+	}
+	if q.deleteMembershipStmt != nil {
+		if cerr := q.deleteMembershipStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteMembershipStmt: %w", cerr)
+		}
+	}
+	if q.deleteOrganizationStmt != nil {
+		if cerr := q.deleteOrganizationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrganizationStmt: %w", cerr)
+		}
+	}
+	if q.deletePublishedOutboxEventsStmt != nil {
+		if cerr := q.deletePublishedOutboxEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deletePublishedOutboxEventsStmt: %w", cerr)
+		}
+	}
 	if q.deleteUserStmt != nil {
 		if cerr := q.deleteUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteUserStmt: %w", cerr)
 		}
 	}
+	if q.estimateUsersCountStmt != nil {
+		if cerr := q.estimateUsersCountStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing estimateUsersCountStmt: %w", cerr)
+		}
+	}
+	if q.expireDataExportStmt != nil {
+		if cerr := q.expireDataExportStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing expireDataExportStmt: %w", cerr)
+		}
+	}
+	if q.expireInvitationsForEmailStmt != nil {
+		if cerr := q.expireInvitationsForEmailStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing expireInvitationsForEmailStmt: %w", cerr)
+		}
+	}
+	if q.failDataExportStmt != nil {
+		if cerr := q.failDataExportStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing failDataExportStmt: %w", cerr)
+		}
+	}
+	if q.getInvitationByTokenHashStmt != nil {
+		if cerr := q.getInvitationByTokenHashStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getInvitationByTokenHashStmt: %w", cerr)
+		}
+	}
+	if q.getLatestDataExportStmt != nil {
+		if cerr := q.getLatestDataExportStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getLatestDataExportStmt: %w", cerr)
+		}
+	}
+	if q.getMembershipStmt != nil {
+		if cerr := q.getMembershipStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getMembershipStmt: %w", cerr)
+		}
+	}
+	if q.getOrganizationStmt != nil {
+		if cerr := q.getOrganizationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getOrganizationStmt: %w", cerr)
+		}
+	}
+	if q.getPendingErasureRequestStmt != nil {
+		if cerr := q.getPendingErasureRequestStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getPendingErasureRequestStmt: %w", cerr)
+		}
+	}
+	if q.getTenantBySlugStmt != nil {
+		if cerr := q.getTenantBySlugStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTenantBySlugStmt: %w", cerr)
+		}
+	}
 	if q.getUserStmt != nil {
 		if cerr := q.getUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getUserStmt: %w", cerr)
 		}
 	}
+	if q.getUserAvatarKeyStmt != nil {
+		if cerr := q.getUserAvatarKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserAvatarKeyStmt: %w", cerr)
+		}
+	}
 	if q.getUserByUsernameStmt != nil {
 		if cerr := q.getUserByUsernameStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getUserByUsernameStmt: %w", cerr)
 		}
 	}
-	if q.searchUsersStmt != nil {
-		if cerr := q.searchUsersStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing searchUsersStmt: %w", cerr)
+	if q.getUserSettingsStmt != nil {
+		if cerr := q.getUserSettingsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserSettingsStmt: %w", cerr)
+		}
+	}
+	if q.getUserSettingsForUpdateStmt != nil {
+		if cerr := q.getUserSettingsForUpdateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserSettingsForUpdateStmt: %w", cerr)
+		}
+	}
+	if q.getUserTenantStmt != nil {
+		if cerr := q.getUserTenantStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserTenantStmt: %w", cerr)
+		}
+	}
+	if q.getUsersByIDsStmt != nil {
+		if cerr := q.getUsersByIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUsersByIDsStmt: %w", cerr)
+		}
+	}
+	if q.listDeadOutboxEventsStmt != nil {
+		if cerr := q.listDeadOutboxEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listDeadOutboxEventsStmt: %w", cerr)
+		}
+	}
+	if q.listDueErasureRequestsStmt != nil {
+		if cerr := q.listDueErasureRequestsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listDueErasureRequestsStmt: %w", cerr)
+		}
+	}
+	if q.listExpiredDataExportsStmt != nil {
+		if cerr := q.listExpiredDataExportsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listExpiredDataExportsStmt: %w", cerr)
+		}
+	}
+	if q.listInvitationsForUserStmt != nil {
+		if cerr := q.listInvitationsForUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInvitationsForUserStmt: %w", cerr)
+		}
+	}
+	if q.listInvitationsSentByStmt != nil {
+		if cerr := q.listInvitationsSentByStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInvitationsSentByStmt: %w", cerr)
+		}
+	}
+	if q.listMembersStmt != nil {
+		if cerr := q.listMembersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listMembersStmt: %w", cerr)
+		}
+	}
+	if q.listOrganizationsByUserIDsStmt != nil {
+		if cerr := q.listOrganizationsByUserIDsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listOrganizationsByUserIDsStmt: %w", cerr)
+		}
+	}
+	if q.listPendingInvitationsStmt != nil {
+		if cerr := q.listPendingInvitationsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listPendingInvitationsStmt: %w", cerr)
+		}
+	}
+	if q.listTakenUsernamesAndEmailsStmt != nil {
+		if cerr := q.listTakenUsernamesAndEmailsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTakenUsernamesAndEmailsStmt: %w", cerr)
+		}
+	}
+	if q.listUserOrganizationsStmt != nil {
+		if cerr := q.listUserOrganizationsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUserOrganizationsStmt: %w", cerr)
+		}
+	}
+	if q.markOutboxEventPublishedStmt != nil {
+		if cerr := q.markOutboxEventPublishedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing markOutboxEventPublishedStmt: %w", cerr)
+		}
+	}
+	if q.purgeDeletedUsersStmt != nil {
+		if cerr := q.purgeDeletedUsersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeDeletedUsersStmt: %w", cerr)
+		}
+	}
+	if q.requeueDeadOutboxEventStmt != nil {
+		if cerr := q.requeueDeadOutboxEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing requeueDeadOutboxEventStmt: %w", cerr)
+		}
+	}
+	if q.requeueDeadOutboxEventsStmt != nil {
+		if cerr := q.requeueDeadOutboxEventsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing requeueDeadOutboxEventsStmt: %w", cerr)
+		}
+	}
+	if q.respondToInvitationStmt != nil {
+		if cerr := q.respondToInvitationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing respondToInvitationStmt: %w", cerr)
+		}
+	}
+	if q.restoreUserStmt != nil {
+		if cerr := q.restoreUserStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing restoreUserStmt: %w", cerr)
+		}
+	}
+	if q.retryOutboxEventStmt != nil {
+		if cerr := q.retryOutboxEventStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing retryOutboxEventStmt: %w", cerr)
+		}
+	}
+	if q.setUserAdminStmt != nil {
+		if cerr := q.setUserAdminStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserAdminStmt: %w", cerr)
+		}
+	}
+	if q.setUserAvatarStmt != nil {
+		if cerr := q.setUserAvatarStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserAvatarStmt: %w", cerr)
+		}
+	}
+	if q.setUserSettingsStmt != nil {
+		if cerr := q.setUserSettingsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserSettingsStmt: %w", cerr)
+		}
+	}
+	if q.updateMembershipRoleStmt != nil {
+		if cerr := q.updateMembershipRoleStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateMembershipRoleStmt: %w", cerr)
+		}
+	}
+	if q.updateOrganizationStmt != nil {
+		if cerr := q.updateOrganizationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateOrganizationStmt: %w", cerr)
 		}
 	}
 	if q.updateUserStmt != nil {
@@ -77,6 +512,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing updateUserStmt: %w", cerr)
 		}
 	}
+	if q.upsertTenantStmt != nil {
+		if cerr := q.upsertTenantStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertTenantStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -114,25 +554,135 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                    DBTX
-	tx                    *sql.Tx
-	createUserStmt        *sql.Stmt
-	deleteUserStmt        *sql.Stmt
-	getUserStmt           *sql.Stmt
-	getUserByUsernameStmt *sql.Stmt
-	searchUsersStmt       *sql.Stmt
-	updateUserStmt        *sql.Stmt
+	db                              DBTX
+	tx                              *sql.Tx
+	addMembershipStmt               *sql.Stmt
+	anonymizeUserStmt               *sql.Stmt
+	cancelErasureRequestStmt        *sql.Stmt
+	claimDataExportStmt             *sql.Stmt
+	claimOutboxEventsStmt           *sql.Stmt
+	clearInvitationsSentByStmt      *sql.Stmt
+	completeDataExportStmt          *sql.Stmt
+	completeErasureRequestStmt      *sql.Stmt
+	createDataExportStmt            *sql.Stmt
+	createErasureRequestStmt        *sql.Stmt
+	createInvitationStmt            *sql.Stmt
+	createOrganizationStmt          *sql.Stmt
+	createOutboxEventStmt           *sql.Stmt
+	createUserStmt                  *sql.Stmt
+	deadLetterOutboxEventStmt       *sql.Stmt
+	deleteInvitationsForUserStmt    *sql.Stmt
+	deleteMembershipStmt            *sql.Stmt
+	deleteOrganizationStmt          *sql.Stmt
+	deletePublishedOutboxEventsStmt *sql.Stmt
+	deleteUserStmt                  *sql.Stmt
+	estimateUsersCountStmt          *sql.Stmt
+	expireDataExportStmt            *sql.Stmt
+	expireInvitationsForEmailStmt   *sql.Stmt
+	failDataExportStmt              *sql.Stmt
+	getInvitationByTokenHashStmt    *sql.Stmt
+	getLatestDataExportStmt         *sql.Stmt
+	getMembershipStmt               *sql.Stmt
+	getOrganizationStmt             *sql.Stmt
+	getPendingErasureRequestStmt    *sql.Stmt
+	getTenantBySlugStmt             *sql.Stmt
+	getUserStmt                     *sql.Stmt
+	getUserAvatarKeyStmt            *sql.Stmt
+	getUserByUsernameStmt           *sql.Stmt
+	getUserSettingsStmt             *sql.Stmt
+	getUserSettingsForUpdateStmt    *sql.Stmt
+	getUserTenantStmt               *sql.Stmt
+	getUsersByIDsStmt               *sql.Stmt
+	listDeadOutboxEventsStmt        *sql.Stmt
+	listDueErasureRequestsStmt      *sql.Stmt
+	listExpiredDataExportsStmt      *sql.Stmt
+	listInvitationsForUserStmt      *sql.Stmt
+	listInvitationsSentByStmt       *sql.Stmt
+	listMembersStmt                 *sql.Stmt
+	listOrganizationsByUserIDsStmt  *sql.Stmt
+	listPendingInvitationsStmt      *sql.Stmt
+	listTakenUsernamesAndEmailsStmt *sql.Stmt
+	listUserOrganizationsStmt       *sql.Stmt
+	markOutboxEventPublishedStmt    *sql.Stmt
+	purgeDeletedUsersStmt           *sql.Stmt
+	requeueDeadOutboxEventStmt      *sql.Stmt
+	requeueDeadOutboxEventsStmt     *sql.Stmt
+	respondToInvitationStmt         *sql.Stmt
+	restoreUserStmt                 *sql.Stmt
+	retryOutboxEventStmt            *sql.Stmt
+	setUserAdminStmt                *sql.Stmt
+	setUserAvatarStmt               *sql.Stmt
+	setUserSettingsStmt             *sql.Stmt
+	updateMembershipRoleStmt        *sql.Stmt
+	updateOrganizationStmt          *sql.Stmt
+	updateUserStmt                  *sql.Stmt
+	upsertTenantStmt                *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                    tx,
-		tx:                    tx,
-		createUserStmt:        q.createUserStmt,
-		deleteUserStmt:        q.deleteUserStmt,
-		getUserStmt:           q.getUserStmt,
-		getUserByUsernameStmt: q.getUserByUsernameStmt,
-		searchUsersStmt:       q.searchUsersStmt,
-		updateUserStmt:        q.updateUserStmt,
+		db:                              tx,
+		tx:                              tx,
+		addMembershipStmt:               q.addMembershipStmt,
+		anonymizeUserStmt:               q.anonymizeUserStmt,
+		cancelErasureRequestStmt:        q.cancelErasureRequestStmt,
+		claimDataExportStmt:             q.claimDataExportStmt,
+		claimOutboxEventsStmt:           q.claimOutboxEventsStmt,
+		clearInvitationsSentByStmt:      q.clearInvitationsSentByStmt,
+		completeDataExportStmt:          q.completeDataExportStmt,
+		completeErasureRequestStmt:      q.completeErasureRequestStmt,
+		createDataExportStmt:            q.createDataExportStmt,
+		createErasureRequestStmt:        q.createErasureRequestStmt,
+		createInvitationStmt:            q.createInvitationStmt,
+		createOrganizationStmt:          q.createOrganizationStmt,
+		createOutboxEventStmt:           q.createOutboxEventStmt,
+		createUserStmt:                  q.createUserStmt,
+		deadLetterOutboxEventStmt:       q.deadLetterOutboxEventStmt,
+		deleteInvitationsForUserStmt:    q.deleteInvitationsForUserStmt,
+		deleteMembershipStmt:            q.deleteMembershipStmt,
+		deleteOrganizationStmt:          q.deleteOrganizationStmt,
+		deletePublishedOutboxEventsStmt: q.deletePublishedOutboxEventsStmt,
+		deleteUserStmt:                  q.deleteUserStmt,
+		estimateUsersCountStmt:          q.estimateUsersCountStmt,
+		expireDataExportStmt:            q.expireDataExportStmt,
+		expireInvitationsForEmailStmt:   q.expireInvitationsForEmailStmt,
+		failDataExportStmt:              q.failDataExportStmt,
+		getInvitationByTokenHashStmt:    q.getInvitationByTokenHashStmt,
+		getLatestDataExportStmt:         q.getLatestDataExportStmt,
+		getMembershipStmt:               q.getMembershipStmt,
+		getOrganizationStmt:             q.getOrganizationStmt,
+		getPendingErasureRequestStmt:    q.getPendingErasureRequestStmt,
+		getTenantBySlugStmt:             q.getTenantBySlugStmt,
+		getUserStmt:                     q.getUserStmt,
+		getUserAvatarKeyStmt:            q.getUserAvatarKeyStmt,
+		getUserByUsernameStmt:           q.getUserByUsernameStmt,
+		getUserSettingsStmt:             q.getUserSettingsStmt,
+		getUserSettingsForUpdateStmt:    q.getUserSettingsForUpdateStmt,
+		getUserTenantStmt:               q.getUserTenantStmt,
+		getUsersByIDsStmt:               q.getUsersByIDsStmt,
+		listDeadOutboxEventsStmt:        q.listDeadOutboxEventsStmt,
+		listDueErasureRequestsStmt:      q.listDueErasureRequestsStmt,
+		listExpiredDataExportsStmt:      q.listExpiredDataExportsStmt,
+		listInvitationsForUserStmt:      q.listInvitationsForUserStmt,
+		listInvitationsSentByStmt:       q.listInvitationsSentByStmt,
+		listMembersStmt:                 q.listMembersStmt,
+		listOrganizationsByUserIDsStmt:  q.listOrganizationsByUserIDsStmt,
+		listPendingInvitationsStmt:      q.listPendingInvitationsStmt,
+		listTakenUsernamesAndEmailsStmt: q.listTakenUsernamesAndEmailsStmt,
+		listUserOrganizationsStmt:       q.listUserOrganizationsStmt,
+		markOutboxEventPublishedStmt:    q.markOutboxEventPublishedStmt,
+		purgeDeletedUsersStmt:           q.purgeDeletedUsersStmt,
+		requeueDeadOutboxEventStmt:      q.requeueDeadOutboxEventStmt,
+		requeueDeadOutboxEventsStmt:     q.requeueDeadOutboxEventsStmt,
+		respondToInvitationStmt:         q.respondToInvitationStmt,
+		restoreUserStmt:                 q.restoreUserStmt,
+		retryOutboxEventStmt:            q.retryOutboxEventStmt,
+		setUserAdminStmt:                q.setUserAdminStmt,
+		setUserAvatarStmt:               q.setUserAvatarStmt,
+		setUserSettingsStmt:             q.setUserSettingsStmt,
+		updateMembershipRoleStmt:        q.updateMembershipRoleStmt,
+		updateOrganizationStmt:          q.updateOrganizationStmt,
+		updateUserStmt:                  q.updateUserStmt,
+		upsertTenantStmt:                q.upsertTenantStmt,
 	}
 }
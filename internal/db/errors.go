@@ -0,0 +1,33 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostgreSQL error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	InvalidParameter      = "22023"
+	NotNullViolation      = "23502"
+	UniqueViolation       = "23505"
+	SerializationFailure  = "40001"
+	DeadlockDetected      = "40P01"
+	InsufficientPrivilege = "42501"
+)
+
+// HasErrorCode reports whether err is a PostgreSQL error with the given code
+func HasErrorCode(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+func IsUniqueViolation(err error) bool {
+	return HasErrorCode(err, UniqueViolation)
+}
+
+// IsRetryable reports whether err aborted a transaction that may succeed
+// when run again from the start
+func IsRetryable(err error) bool {
+	return HasErrorCode(err, SerializationFailure) || HasErrorCode(err, DeadlockDetected)
+}
@@ -2,49 +2,71 @@ package db
 
 import (
 	"context"
-	"database/sql"
-	"os"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/spf13/viper"
 )
 
+const (
+	defaultStartupTimeout    = 30 * time.Second
+	startupRetryInitialDelay = 250 * time.Millisecond
+	startupRetryMaxDelay     = 5 * time.Second
+)
+
+// NewPostgresPool opens the pool of the primary and waits until the database
+// answers, so that a wrong DATABASE_URL or an unreachable server stops the
+// application at startup; see waitForDatabase.
 func NewPostgresPool(databaseURL string) (*pgxpool.Pool, error) {
-	config, err := pgxpool.ParseConfig(databaseURL)
+	pool, err := newPool(databaseURL)
 	if err != nil {
 		return nil, err
 	}
-
-	// Используем стандартные типы Go вместо pgtype
-	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
-		conn.TypeMap().RegisterType(&pgtype.Type{
-			Name:  "timestamp",
-			OID:   pgtype.TimestampOID,
-			Codec: &pgtype.TimestampCodec{},
-		})
-		return nil
-	}
-
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
-	if err != nil {
+	if err := waitForDatabase(pool); err != nil {
+		pool.Close()
 		return nil, err
 	}
-
 	return pool, nil
 }
 
-func NewSQLDB(pool *pgxpool.Pool) *sql.DB {
-	return stdlib.OpenDB(*pool.Config().ConnConfig)
-}
-
-func InitDB() (*pgxpool.Pool, error) {
-	config, err := pgxpool.ParseConfig(os.Getenv("DATABASE_URL"))
+// newPool configures a pool without connecting. The pool settings come from
+// DB_MAX_CONNS, DB_MIN_CONNS, DB_MAX_CONN_LIFETIME, DB_MAX_CONN_IDLE_TIME and
+// DB_HEALTH_CHECK_PERIOD, and DB_STATEMENT_TIMEOUT bounds every statement;
+// unset values keep the defaults of pgxpool and the server.
+func newPool(databaseURL string) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, err
 	}
 
+	if maxConns := viper.GetInt32("DB_MAX_CONNS"); maxConns > 0 {
+		config.MaxConns = maxConns
+	}
+	if minConns := viper.GetInt32("DB_MIN_CONNS"); minConns > 0 {
+		config.MinConns = min(minConns, config.MaxConns)
+	}
+	if lifetime := viper.GetDuration("DB_MAX_CONN_LIFETIME"); lifetime > 0 {
+		config.MaxConnLifetime = lifetime
+	}
+	if idleTime := viper.GetDuration("DB_MAX_CONN_IDLE_TIME"); idleTime > 0 {
+		config.MaxConnIdleTime = idleTime
+	}
+	if period := viper.GetDuration("DB_HEALTH_CHECK_PERIOD"); period > 0 {
+		config.HealthCheckPeriod = period
+	}
+	if timeout := viper.GetDuration("DB_STATEMENT_TIMEOUT"); timeout > 0 {
+		config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(timeout.Milliseconds(), 10)
+	}
+
+	// Используем стандартные типы Go вместо pgtype
 	config.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
 		conn.TypeMap().RegisterType(&pgtype.Type{
 			Name:  "timestamp",
@@ -54,10 +76,62 @@ func InitDB() (*pgxpool.Pool, error) {
 		return nil
 	}
 
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
-	if err != nil {
-		return nil, err
+	// Every acquired connection is scoped to the tenant of the acquiring
+	// context, see WithTenant. DB keeps no idle connections of its own, so
+	// its statements are scoped the same way.
+	scoper := &tenantScoper{}
+	config.BeforeAcquire = scoper.beforeAcquire
+	config.BeforeClose = scoper.beforeClose
+
+	return pgxpool.NewWithConfig(context.Background(), config)
+}
+
+// waitForDatabase pings the database until it answers, backing off between
+// attempts, for at most DB_STARTUP_TIMEOUT (30s by default). A server that is
+// starting up, shutting down or out of connections is waited for, while
+// errors of the configuration, such as a wrong password or an unknown
+// database, do not go away by waiting and fail at once.
+func waitForDatabase(pool *pgxpool.Pool) error {
+	timeout := viper.GetDuration("DB_STARTUP_TIMEOUT")
+	if timeout <= 0 {
+		timeout = defaultStartupTimeout
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	return pool, nil
+	delay := startupRetryInitialDelay
+	for attempt := 1; ; attempt++ {
+		err := pool.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && isConfigError(pgErr) {
+			return fmt.Errorf("database rejected the connection: %w", err)
+		}
+		log.Printf("Database is not ready (attempt %d): %v", attempt, err)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database is not reachable after %d attempts in %s: %w", attempt, timeout, err)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, startupRetryMaxDelay)
+	}
+}
+
+// isConfigError reports whether the server refused the connection because of
+// the connection settings: the credentials or authentication method (class
+// 28), an unknown database (class 3D), an invalid startup parameter or a
+// missing CONNECT privilege. Other errors, such as 57P03 while the server is
+// starting, class 08 connection failures or 53300 when all connection slots
+// are taken, may go away by waiting.
+func isConfigError(err *pgconn.PgError) bool {
+	switch {
+	case strings.HasPrefix(err.Code, "28"), strings.HasPrefix(err.Code, "3D"):
+		return true
+	case err.Code == InvalidParameter, err.Code == InsufficientPrivilege:
+		return true
+	}
+	return false
 }
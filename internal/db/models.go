@@ -6,17 +6,101 @@ package db
 
 import (
 	"database/sql"
-
+	"encoding/json"
 	"time"
 )
 
+type DataExports struct {
+	ID          int32          `json:"id"`
+	UserID      int32          `json:"user_id"`
+	TenantID    int32          `json:"tenant_id"`
+	Status      string         `json:"status"`
+	FilePath    sql.NullString `json:"file_path"`
+	Error       sql.NullString `json:"error"`
+	CreatedAt   time.Time      `json:"created_at"`
+	CompletedAt sql.NullTime   `json:"completed_at"`
+	ExpiresAt   sql.NullTime   `json:"expires_at"`
+	ClaimedAt   sql.NullTime   `json:"claimed_at"`
+}
+
+type ErasureRequests struct {
+	ID           int32        `json:"id"`
+	UserID       int32        `json:"user_id"`
+	TenantID     int32        `json:"tenant_id"`
+	Status       string       `json:"status"`
+	RequestedAt  time.Time    `json:"requested_at"`
+	ScheduledFor time.Time    `json:"scheduled_for"`
+	CompletedAt  sql.NullTime `json:"completed_at"`
+	CancelledAt  sql.NullTime `json:"cancelled_at"`
+}
+
+type OrgInvitations struct {
+	ID          int32         `json:"id"`
+	OrgID       int32         `json:"org_id"`
+	TenantID    int32         `json:"tenant_id"`
+	Email       string        `json:"email"`
+	Role        string        `json:"role"`
+	TokenHash   string        `json:"token_hash"`
+	InvitedBy   sql.NullInt32 `json:"invited_by"`
+	Status      string        `json:"status"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+	RespondedAt sql.NullTime  `json:"responded_at"`
+}
+
+type OrgMemberships struct {
+	OrgID     int32     `json:"org_id"`
+	UserID    int32     `json:"user_id"`
+	TenantID  int32     `json:"tenant_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Organizations struct {
+	ID        int32     `json:"id"`
+	TenantID  int32     `json:"tenant_id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Outbox struct {
+	ID            int64           `json:"id"`
+	TenantID      sql.NullInt32   `json:"tenant_id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int32           `json:"attempts"`
+	LastError     sql.NullString  `json:"last_error"`
+	CreatedAt     time.Time       `json:"created_at"`
+	AvailableAt   time.Time       `json:"available_at"`
+	LockedUntil   sql.NullTime    `json:"locked_until"`
+	PublishedAt   sql.NullTime    `json:"published_at"`
+}
+
+type Tenants struct {
+	ID        int32     `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Users struct {
-	ID           int32          `json:"id"`
-	Username     string         `json:"username"`
-	Email        string         `json:"email"`
-	PasswordHash string         `json:"password_hash"`
-	FullName     sql.NullString `json:"full_name"`
-	Bio          sql.NullString `json:"bio"`
-	CreatedAt    **time.Time    `json:"created_at"`
-	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	ID           int32           `json:"id"`
+	Username     string          `json:"username"`
+	Email        string          `json:"email"`
+	PasswordHash string          `json:"password_hash"`
+	FullName     sql.NullString  `json:"full_name"`
+	Bio          sql.NullString  `json:"bio"`
+	CreatedAt    **time.Time     `json:"created_at"`
+	UpdatedAt    sql.NullTime    `json:"updated_at"`
+	SearchVector interface{}     `json:"search_vector"`
+	DeletedAt    sql.NullTime    `json:"deleted_at"`
+	IsAdmin      bool            `json:"is_admin"`
+	AvatarKey    sql.NullString  `json:"avatar_key"`
+	TenantID     int32           `json:"tenant_id"`
+	Settings     json.RawMessage `json:"settings"`
 }
@@ -0,0 +1,602 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: org.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const AddMembership = `-- name: AddMembership :one
+INSERT INTO org_memberships (org_id, user_id, role)
+VALUES ($1, $2, $3)
+RETURNING org_id, user_id, tenant_id, role, created_at
+`
+
+type AddMembershipParams struct {
+	OrgID  int32  `json:"org_id"`
+	UserID int32  `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// Adds a user to an organization with the given role
+func (q *Queries) AddMembership(ctx context.Context, arg AddMembershipParams) (OrgMemberships, error) {
+	row := q.queryRow(ctx, q.addMembershipStmt, AddMembership, arg.OrgID, arg.UserID, arg.Role)
+	var i OrgMemberships
+	err := row.Scan(
+		&i.OrgID,
+		&i.UserID,
+		&i.TenantID,
+		&i.Role,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const ClearInvitationsSentBy = `-- name: ClearInvitationsSentBy :exec
+UPDATE org_invitations
+SET invited_by = NULL
+WHERE invited_by = $1
+`
+
+// Forgets who sent the invitations of the user
+func (q *Queries) ClearInvitationsSentBy(ctx context.Context, invitedBy sql.NullInt32) error {
+	_, err := q.exec(ctx, q.clearInvitationsSentByStmt, ClearInvitationsSentBy, invitedBy)
+	return err
+}
+
+const CreateInvitation = `-- name: CreateInvitation :one
+INSERT INTO org_invitations (org_id, email, role, token_hash, invited_by, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, org_id, tenant_id, email, role, token_hash, invited_by, status, expires_at, created_at, responded_at
+`
+
+type CreateInvitationParams struct {
+	OrgID     int32         `json:"org_id"`
+	Email     string        `json:"email"`
+	Role      string        `json:"role"`
+	TokenHash string        `json:"token_hash"`
+	InvitedBy sql.NullInt32 `json:"invited_by"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+// Creates a pending invitation into an organization
+func (q *Queries) CreateInvitation(ctx context.Context, arg CreateInvitationParams) (OrgInvitations, error) {
+	row := q.queryRow(ctx, q.createInvitationStmt, CreateInvitation,
+		arg.OrgID,
+		arg.Email,
+		arg.Role,
+		arg.TokenHash,
+		arg.InvitedBy,
+		arg.ExpiresAt,
+	)
+	var i OrgInvitations
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.TenantID,
+		&i.Email,
+		&i.Role,
+		&i.TokenHash,
+		&i.InvitedBy,
+		&i.Status,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.RespondedAt,
+	)
+	return i, err
+}
+
+const CreateOrganization = `-- name: CreateOrganization :one
+INSERT INTO organizations (name, slug)
+VALUES ($1, $2)
+RETURNING id, tenant_id, name, slug, created_at, updated_at
+`
+
+type CreateOrganizationParams struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// Creates a new organization; the creator is added as owner separately
+func (q *Queries) CreateOrganization(ctx context.Context, arg CreateOrganizationParams) (Organizations, error) {
+	row := q.queryRow(ctx, q.createOrganizationStmt, CreateOrganization, arg.Name, arg.Slug)
+	var i Organizations
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Name,
+		&i.Slug,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const DeleteInvitationsForUser = `-- name: DeleteInvitationsForUser :exec
+DELETE FROM org_invitations i
+USING users u
+WHERE u.id = $1 AND u.tenant_id = i.tenant_id AND lower(u.email) = lower(i.email)
+`
+
+// Deletes the invitations sent to the email of the user in the user's tenant
+// Must run before the user's email is anonymized
+func (q *Queries) DeleteInvitationsForUser(ctx context.Context, id int32) error {
+	_, err := q.exec(ctx, q.deleteInvitationsForUserStmt, DeleteInvitationsForUser, id)
+	return err
+}
+
+const DeleteMembership = `-- name: DeleteMembership :execrows
+DELETE FROM org_memberships
+WHERE org_id = $1 AND user_id = $2
+`
+
+type DeleteMembershipParams struct {
+	OrgID  int32 `json:"org_id"`
+	UserID int32 `json:"user_id"`
+}
+
+// Removes a user from an organization
+func (q *Queries) DeleteMembership(ctx context.Context, arg DeleteMembershipParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteMembershipStmt, DeleteMembership, arg.OrgID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteOrganization = `-- name: DeleteOrganization :exec
+DELETE FROM organizations
+WHERE id = $1
+`
+
+// Deletes an organization together with its memberships and invitations
+func (q *Queries) DeleteOrganization(ctx context.Context, id int32) error {
+	_, err := q.exec(ctx, q.deleteOrganizationStmt, DeleteOrganization, id)
+	return err
+}
+
+const ExpireInvitationsForEmail = `-- name: ExpireInvitationsForEmail :exec
+UPDATE org_invitations
+SET status = 'expired'
+WHERE org_id = $1
+    AND lower(email) = lower($2)
+    AND status = 'pending'
+    AND expires_at <= CURRENT_TIMESTAMP
+`
+
+type ExpireInvitationsForEmailParams struct {
+	OrgID int32  `json:"org_id"`
+	Email string `json:"email"`
+}
+
+// Marks expired pending invitations of an email as expired
+// so that the email can be invited again
+func (q *Queries) ExpireInvitationsForEmail(ctx context.Context, arg ExpireInvitationsForEmailParams) error {
+	_, err := q.exec(ctx, q.expireInvitationsForEmailStmt, ExpireInvitationsForEmail, arg.OrgID, arg.Email)
+	return err
+}
+
+const GetInvitationByTokenHash = `-- name: GetInvitationByTokenHash :one
+SELECT id, org_id, tenant_id, email, role, token_hash, invited_by, status, expires_at, created_at, responded_at FROM org_invitations
+WHERE token_hash = $1
+`
+
+// Retrieves an invitation by the hash of the token sent by email
+func (q *Queries) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (OrgInvitations, error) {
+	row := q.queryRow(ctx, q.getInvitationByTokenHashStmt, GetInvitationByTokenHash, tokenHash)
+	var i OrgInvitations
+	err := row.Scan(
+		&i.ID,
+		&i.OrgID,
+		&i.TenantID,
+		&i.Email,
+		&i.Role,
+		&i.TokenHash,
+		&i.InvitedBy,
+		&i.Status,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.RespondedAt,
+	)
+	return i, err
+}
+
+const GetMembership = `-- name: GetMembership :one
+SELECT org_id, user_id, tenant_id, role, created_at FROM org_memberships
+WHERE org_id = $1 AND user_id = $2
+`
+
+type GetMembershipParams struct {
+	OrgID  int32 `json:"org_id"`
+	UserID int32 `json:"user_id"`
+}
+
+// Retrieves the membership of a user in an organization
+func (q *Queries) GetMembership(ctx context.Context, arg GetMembershipParams) (OrgMemberships, error) {
+	row := q.queryRow(ctx, q.getMembershipStmt, GetMembership, arg.OrgID, arg.UserID)
+	var i OrgMemberships
+	err := row.Scan(
+		&i.OrgID,
+		&i.UserID,
+		&i.TenantID,
+		&i.Role,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const GetOrganization = `-- name: GetOrganization :one
+SELECT id, tenant_id, name, slug, created_at, updated_at FROM organizations
+WHERE id = $1
+`
+
+// Retrieves an organization by its ID
+func (q *Queries) GetOrganization(ctx context.Context, id int32) (Organizations, error) {
+	row := q.queryRow(ctx, q.getOrganizationStmt, GetOrganization, id)
+	var i Organizations
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Name,
+		&i.Slug,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const ListInvitationsForUser = `-- name: ListInvitationsForUser :many
+SELECT i.id, i.org_id, i.tenant_id, i.email, i.role, i.token_hash, i.invited_by, i.status, i.expires_at, i.created_at, i.responded_at FROM org_invitations i
+JOIN users u ON u.tenant_id = i.tenant_id AND lower(u.email) = lower(i.email)
+WHERE u.id = $1
+ORDER BY i.id
+`
+
+// Lists the invitations sent to the email of the user in the user's tenant,
+// including deleted users
+func (q *Queries) ListInvitationsForUser(ctx context.Context, id int32) ([]OrgInvitations, error) {
+	rows, err := q.query(ctx, q.listInvitationsForUserStmt, ListInvitationsForUser, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OrgInvitations{}
+	for rows.Next() {
+		var i OrgInvitations
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrgID,
+			&i.TenantID,
+			&i.Email,
+			&i.Role,
+			&i.TokenHash,
+			&i.InvitedBy,
+			&i.Status,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.RespondedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListInvitationsSentBy = `-- name: ListInvitationsSentBy :many
+SELECT id, org_id, tenant_id, email, role, token_hash, invited_by, status, expires_at, created_at, responded_at FROM org_invitations
+WHERE invited_by = $1
+ORDER BY id
+`
+
+// Lists the invitations the user has sent
+func (q *Queries) ListInvitationsSentBy(ctx context.Context, invitedBy sql.NullInt32) ([]OrgInvitations, error) {
+	rows, err := q.query(ctx, q.listInvitationsSentByStmt, ListInvitationsSentBy, invitedBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OrgInvitations{}
+	for rows.Next() {
+		var i OrgInvitations
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrgID,
+			&i.TenantID,
+			&i.Email,
+			&i.Role,
+			&i.TokenHash,
+			&i.InvitedBy,
+			&i.Status,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.RespondedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListMembers = `-- name: ListMembers :many
+SELECT m.user_id, m.role, m.created_at, u.username, u.full_name
+FROM org_memberships m
+JOIN users u ON u.id = m.user_id
+WHERE m.org_id = $1 AND u.deleted_at IS NULL
+ORDER BY m.created_at, m.user_id
+`
+
+type ListMembersRow struct {
+	UserID    int32          `json:"user_id"`
+	Role      string         `json:"role"`
+	CreatedAt time.Time      `json:"created_at"`
+	Username  string         `json:"username"`
+	FullName  sql.NullString `json:"full_name"`
+}
+
+// Lists the members of an organization with their public profile data
+// Soft-deleted users are not listed
+func (q *Queries) ListMembers(ctx context.Context, orgID int32) ([]ListMembersRow, error) {
+	rows, err := q.query(ctx, q.listMembersStmt, ListMembers, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMembersRow{}
+	for rows.Next() {
+		var i ListMembersRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Role,
+			&i.CreatedAt,
+			&i.Username,
+			&i.FullName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListOrganizationsByUserIDs = `-- name: ListOrganizationsByUserIDs :many
+SELECT m.user_id, o.id, o.name, o.slug, m.role
+FROM org_memberships m
+JOIN organizations o ON o.id = m.org_id
+WHERE m.user_id = ANY($1::int[])
+ORDER BY m.user_id, o.name, o.id
+`
+
+type ListOrganizationsByUserIDsRow struct {
+	UserID int32  `json:"user_id"`
+	ID     int32  `json:"id"`
+	Name   string `json:"name"`
+	Slug   string `json:"slug"`
+	Role   string `json:"role"`
+}
+
+// Lists the organizations of several users at once
+// Used to embed organizations into user listings
+func (q *Queries) ListOrganizationsByUserIDs(ctx context.Context, userIds []int32) ([]ListOrganizationsByUserIDsRow, error) {
+	rows, err := q.query(ctx, q.listOrganizationsByUserIDsStmt, ListOrganizationsByUserIDs, pq.Array(userIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListOrganizationsByUserIDsRow{}
+	for rows.Next() {
+		var i ListOrganizationsByUserIDsRow
+		if err := rows.Scan(
+			&i.UserID,
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListPendingInvitations = `-- name: ListPendingInvitations :many
+SELECT id, org_id, tenant_id, email, role, token_hash, invited_by, status, expires_at, created_at, responded_at FROM org_invitations
+WHERE org_id = $1 AND status = 'pending' AND expires_at > CURRENT_TIMESTAMP
+ORDER BY created_at DESC, id DESC
+`
+
+// Lists the invitations of an organization awaiting a response
+func (q *Queries) ListPendingInvitations(ctx context.Context, orgID int32) ([]OrgInvitations, error) {
+	rows, err := q.query(ctx, q.listPendingInvitationsStmt, ListPendingInvitations, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OrgInvitations{}
+	for rows.Next() {
+		var i OrgInvitations
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrgID,
+			&i.TenantID,
+			&i.Email,
+			&i.Role,
+			&i.TokenHash,
+			&i.InvitedBy,
+			&i.Status,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.RespondedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListUserOrganizations = `-- name: ListUserOrganizations :many
+SELECT o.id, o.name, o.slug, o.created_at, o.updated_at, m.role
+FROM organizations o
+JOIN org_memberships m ON m.org_id = o.id
+WHERE m.user_id = $1
+ORDER BY o.name, o.id
+`
+
+type ListUserOrganizationsRow struct {
+	ID        int32     `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Role      string    `json:"role"`
+}
+
+// Lists the organizations the user is a member of, with the user's role
+func (q *Queries) ListUserOrganizations(ctx context.Context, userID int32) ([]ListUserOrganizationsRow, error) {
+	rows, err := q.query(ctx, q.listUserOrganizationsStmt, ListUserOrganizations, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserOrganizationsRow{}
+	for rows.Next() {
+		var i ListUserOrganizationsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Slug,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const RespondToInvitation = `-- name: RespondToInvitation :execrows
+UPDATE org_invitations
+SET
+    status = $1,
+    responded_at = CURRENT_TIMESTAMP
+WHERE id = $2 AND org_id = $3 AND status = 'pending'
+`
+
+type RespondToInvitationParams struct {
+	Status string `json:"status"`
+	ID     int32  `json:"id"`
+	OrgID  int32  `json:"org_id"`
+}
+
+// Moves a pending invitation to its final status: accepted, declined or revoked
+func (q *Queries) RespondToInvitation(ctx context.Context, arg RespondToInvitationParams) (int64, error) {
+	result, err := q.exec(ctx, q.respondToInvitationStmt, RespondToInvitation, arg.Status, arg.ID, arg.OrgID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const UpdateMembershipRole = `-- name: UpdateMembershipRole :one
+UPDATE org_memberships
+SET role = $3
+WHERE org_id = $1 AND user_id = $2
+RETURNING org_id, user_id, tenant_id, role, created_at
+`
+
+type UpdateMembershipRoleParams struct {
+	OrgID  int32  `json:"org_id"`
+	UserID int32  `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// Changes the role of a member
+func (q *Queries) UpdateMembershipRole(ctx context.Context, arg UpdateMembershipRoleParams) (OrgMemberships, error) {
+	row := q.queryRow(ctx, q.updateMembershipRoleStmt, UpdateMembershipRole, arg.OrgID, arg.UserID, arg.Role)
+	var i OrgMemberships
+	err := row.Scan(
+		&i.OrgID,
+		&i.UserID,
+		&i.TenantID,
+		&i.Role,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const UpdateOrganization = `-- name: UpdateOrganization :one
+UPDATE organizations
+SET
+    name = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, tenant_id, name, slug, created_at, updated_at
+`
+
+type UpdateOrganizationParams struct {
+	ID   int32  `json:"id"`
+	Name string `json:"name"`
+}
+
+// Renames an organization
+func (q *Queries) UpdateOrganization(ctx context.Context, arg UpdateOrganizationParams) (Organizations, error) {
+	row := q.queryRow(ctx, q.updateOrganizationStmt, UpdateOrganization, arg.ID, arg.Name)
+	var i Organizations
+	err := row.Scan(
+		&i.ID,
+		&i.TenantID,
+		&i.Name,
+		&i.Slug,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,260 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: outbox.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+const ClaimOutboxEvents = `-- name: ClaimOutboxEvents :many
+UPDATE outbox
+SET
+    locked_until = CURRENT_TIMESTAMP + make_interval(secs => $1::DOUBLE PRECISION),
+    attempts = attempts + 1
+WHERE id IN (
+    SELECT o.id FROM outbox o
+    WHERE o.status = 'pending'
+        AND o.available_at <= CURRENT_TIMESTAMP
+        AND (o.locked_until IS NULL OR o.locked_until < CURRENT_TIMESTAMP)
+        AND NOT EXISTS (
+            SELECT 1 FROM outbox earlier
+            WHERE earlier.aggregate_type = o.aggregate_type
+                AND earlier.aggregate_id = o.aggregate_id
+                AND earlier.status = 'pending'
+                AND earlier.id < o.id
+        )
+    ORDER BY o.id
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, tenant_id, aggregate_type, aggregate_id, event_type, payload, status, attempts, last_error, created_at, available_at, locked_until, published_at
+`
+
+type ClaimOutboxEventsParams struct {
+	LeaseSeconds float64 `json:"lease_seconds"`
+	BatchSize    int32   `json:"batch_size"`
+}
+
+// Leases the events that are due for publishing, taking only the oldest
+// pending event of each aggregate, so that the events of an aggregate are
+// published one after another in order. An event whose lease expired, because
+// its relay stopped, is claimed again.
+// SKIP LOCKED lets several relays run concurrently
+func (q *Queries) ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]Outbox, error) {
+	rows, err := q.query(ctx, q.claimOutboxEventsStmt, ClaimOutboxEvents, arg.LeaseSeconds, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Outbox{}
+	for rows.Next() {
+		var i Outbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.AggregateType,
+			&i.AggregateID,
+			&i.EventType,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.AvailableAt,
+			&i.LockedUntil,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const CreateOutboxEvent = `-- name: CreateOutboxEvent :exec
+INSERT INTO outbox (tenant_id, aggregate_type, aggregate_id, event_type, payload)
+VALUES (COALESCE($1::INTEGER, current_tenant_id()), $2, $3, $4, $5)
+`
+
+type CreateOutboxEventParams struct {
+	TenantID      sql.NullInt32   `json:"tenant_id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// Writes an event to the outbox; the tenant defaults to the tenant of the connection
+func (q *Queries) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) error {
+	_, err := q.exec(ctx, q.createOutboxEventStmt, CreateOutboxEvent,
+		arg.TenantID,
+		arg.AggregateType,
+		arg.AggregateID,
+		arg.EventType,
+		arg.Payload,
+	)
+	return err
+}
+
+const DeadLetterOutboxEvent = `-- name: DeadLetterOutboxEvent :exec
+UPDATE outbox
+SET
+    status = 'dead',
+    locked_until = NULL,
+    last_error = $1
+WHERE id = $2
+`
+
+type DeadLetterOutboxEventParams struct {
+	LastError sql.NullString `json:"last_error"`
+	ID        int64          `json:"id"`
+}
+
+// Gives up on the event; the next event of the aggregate is published next
+func (q *Queries) DeadLetterOutboxEvent(ctx context.Context, arg DeadLetterOutboxEventParams) error {
+	_, err := q.exec(ctx, q.deadLetterOutboxEventStmt, DeadLetterOutboxEvent, arg.LastError, arg.ID)
+	return err
+}
+
+const DeletePublishedOutboxEvents = `-- name: DeletePublishedOutboxEvents :execrows
+DELETE FROM outbox
+WHERE status = 'published' AND published_at < $1
+`
+
+// Removes events published before the cutoff
+func (q *Queries) DeletePublishedOutboxEvents(ctx context.Context, publishedBefore sql.NullTime) (int64, error) {
+	result, err := q.exec(ctx, q.deletePublishedOutboxEventsStmt, DeletePublishedOutboxEvents, publishedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const ListDeadOutboxEvents = `-- name: ListDeadOutboxEvents :many
+SELECT id, tenant_id, aggregate_type, aggregate_id, event_type, payload, status, attempts, last_error, created_at, available_at, locked_until, published_at FROM outbox
+WHERE status = 'dead'
+ORDER BY id
+LIMIT $1
+`
+
+// Lists the events the relay gave up on, oldest first
+func (q *Queries) ListDeadOutboxEvents(ctx context.Context, limit int32) ([]Outbox, error) {
+	rows, err := q.query(ctx, q.listDeadOutboxEventsStmt, ListDeadOutboxEvents, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Outbox{}
+	for rows.Next() {
+		var i Outbox
+		if err := rows.Scan(
+			&i.ID,
+			&i.TenantID,
+			&i.AggregateType,
+			&i.AggregateID,
+			&i.EventType,
+			&i.Payload,
+			&i.Status,
+			&i.Attempts,
+			&i.LastError,
+			&i.CreatedAt,
+			&i.AvailableAt,
+			&i.LockedUntil,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const MarkOutboxEventPublished = `-- name: MarkOutboxEventPublished :exec
+UPDATE outbox
+SET
+    status = 'published',
+    published_at = CURRENT_TIMESTAMP,
+    locked_until = NULL,
+    last_error = NULL
+WHERE id = $1
+`
+
+// Marks the event as published by every sink
+func (q *Queries) MarkOutboxEventPublished(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.markOutboxEventPublishedStmt, MarkOutboxEventPublished, id)
+	return err
+}
+
+const RequeueDeadOutboxEvent = `-- name: RequeueDeadOutboxEvent :execrows
+UPDATE outbox
+SET
+    status = 'pending',
+    attempts = 0,
+    available_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'dead'
+`
+
+// Queues a dead event for publishing again with a fresh number of attempts
+func (q *Queries) RequeueDeadOutboxEvent(ctx context.Context, id int64) (int64, error) {
+	result, err := q.exec(ctx, q.requeueDeadOutboxEventStmt, RequeueDeadOutboxEvent, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const RequeueDeadOutboxEvents = `-- name: RequeueDeadOutboxEvents :execrows
+UPDATE outbox
+SET
+    status = 'pending',
+    attempts = 0,
+    available_at = CURRENT_TIMESTAMP
+WHERE status = 'dead'
+`
+
+// Queues all dead events for publishing again
+func (q *Queries) RequeueDeadOutboxEvents(ctx context.Context) (int64, error) {
+	result, err := q.exec(ctx, q.requeueDeadOutboxEventsStmt, RequeueDeadOutboxEvents)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const RetryOutboxEvent = `-- name: RetryOutboxEvent :exec
+UPDATE outbox
+SET
+    available_at = CURRENT_TIMESTAMP + make_interval(secs => $1::DOUBLE PRECISION),
+    locked_until = NULL,
+    last_error = $2
+WHERE id = $3
+`
+
+type RetryOutboxEventParams struct {
+	DelaySeconds float64        `json:"delay_seconds"`
+	LastError    sql.NullString `json:"last_error"`
+	ID           int64          `json:"id"`
+}
+
+// Releases the event after a failed attempt and postpones the next one
+func (q *Queries) RetryOutboxEvent(ctx context.Context, arg RetryOutboxEventParams) error {
+	_, err := q.exec(ctx, q.retryOutboxEventStmt, RetryOutboxEvent, arg.DelaySeconds, arg.LastError, arg.ID)
+	return err
+}
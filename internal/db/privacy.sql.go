@@ -0,0 +1,328 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: privacy.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const CancelErasureRequest = `-- name: CancelErasureRequest :one
+UPDATE erasure_requests
+SET
+    status = 'cancelled',
+    cancelled_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND status = 'pending'
+RETURNING id, user_id, tenant_id, status, requested_at, scheduled_for, completed_at, cancelled_at
+`
+
+// Cancels the pending erasure request of the user during the cooling-off period
+func (q *Queries) CancelErasureRequest(ctx context.Context, userID int32) (ErasureRequests, error) {
+	row := q.queryRow(ctx, q.cancelErasureRequestStmt, CancelErasureRequest, userID)
+	var i ErasureRequests
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TenantID,
+		&i.Status,
+		&i.RequestedAt,
+		&i.ScheduledFor,
+		&i.CompletedAt,
+		&i.CancelledAt,
+	)
+	return i, err
+}
+
+const ClaimDataExport = `-- name: ClaimDataExport :one
+UPDATE data_exports
+SET
+    status = 'processing',
+    claimed_at = CURRENT_TIMESTAMP
+WHERE id = (
+    SELECT id FROM data_exports
+    WHERE status = 'pending'
+        OR (status = 'processing' AND claimed_at < CURRENT_TIMESTAMP - INTERVAL '1 hour')
+    ORDER BY id
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id, tenant_id, status, file_path, error, created_at, completed_at, expires_at, claimed_at
+`
+
+// Marks the oldest pending export as processing and returns it
+// Exports claimed over an hour ago and still processing are picked up again
+// SKIP LOCKED lets several workers run concurrently
+func (q *Queries) ClaimDataExport(ctx context.Context) (DataExports, error) {
+	row := q.queryRow(ctx, q.claimDataExportStmt, ClaimDataExport)
+	var i DataExports
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TenantID,
+		&i.Status,
+		&i.FilePath,
+		&i.Error,
+		&i.CreatedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+		&i.ClaimedAt,
+	)
+	return i, err
+}
+
+const CompleteDataExport = `-- name: CompleteDataExport :exec
+UPDATE data_exports
+SET
+    status = 'ready',
+    file_path = $1,
+    completed_at = CURRENT_TIMESTAMP,
+    expires_at = $2
+WHERE id = $3
+`
+
+type CompleteDataExportParams struct {
+	FilePath  sql.NullString `json:"file_path"`
+	ExpiresAt sql.NullTime   `json:"expires_at"`
+	ID        int32          `json:"id"`
+}
+
+// Marks the export as ready for download until it expires
+func (q *Queries) CompleteDataExport(ctx context.Context, arg CompleteDataExportParams) error {
+	_, err := q.exec(ctx, q.completeDataExportStmt, CompleteDataExport, arg.FilePath, arg.ExpiresAt, arg.ID)
+	return err
+}
+
+const CompleteErasureRequest = `-- name: CompleteErasureRequest :exec
+UPDATE erasure_requests
+SET
+    status = 'completed',
+    completed_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+// Marks the erasure request as completed
+func (q *Queries) CompleteErasureRequest(ctx context.Context, id int32) error {
+	_, err := q.exec(ctx, q.completeErasureRequestStmt, CompleteErasureRequest, id)
+	return err
+}
+
+const CreateDataExport = `-- name: CreateDataExport :one
+INSERT INTO data_exports (user_id)
+VALUES ($1)
+RETURNING id, user_id, tenant_id, status, file_path, error, created_at, completed_at, expires_at, claimed_at
+`
+
+// Creates a pending personal data export for the user
+func (q *Queries) CreateDataExport(ctx context.Context, userID int32) (DataExports, error) {
+	row := q.queryRow(ctx, q.createDataExportStmt, CreateDataExport, userID)
+	var i DataExports
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TenantID,
+		&i.Status,
+		&i.FilePath,
+		&i.Error,
+		&i.CreatedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+		&i.ClaimedAt,
+	)
+	return i, err
+}
+
+const CreateErasureRequest = `-- name: CreateErasureRequest :one
+INSERT INTO erasure_requests (user_id, scheduled_for)
+VALUES ($1, $2)
+RETURNING id, user_id, tenant_id, status, requested_at, scheduled_for, completed_at, cancelled_at
+`
+
+type CreateErasureRequestParams struct {
+	UserID       int32     `json:"user_id"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+}
+
+// Schedules erasure of the user's personal data
+// Fails with a unique violation if a pending request already exists
+func (q *Queries) CreateErasureRequest(ctx context.Context, arg CreateErasureRequestParams) (ErasureRequests, error) {
+	row := q.queryRow(ctx, q.createErasureRequestStmt, CreateErasureRequest, arg.UserID, arg.ScheduledFor)
+	var i ErasureRequests
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TenantID,
+		&i.Status,
+		&i.RequestedAt,
+		&i.ScheduledFor,
+		&i.CompletedAt,
+		&i.CancelledAt,
+	)
+	return i, err
+}
+
+const ExpireDataExport = `-- name: ExpireDataExport :exec
+UPDATE data_exports
+SET
+    status = 'expired',
+    file_path = NULL
+WHERE id = $1
+`
+
+// Marks the export as expired once its archive has been removed
+func (q *Queries) ExpireDataExport(ctx context.Context, id int32) error {
+	_, err := q.exec(ctx, q.expireDataExportStmt, ExpireDataExport, id)
+	return err
+}
+
+const FailDataExport = `-- name: FailDataExport :exec
+UPDATE data_exports
+SET
+    status = 'failed',
+    error = $1,
+    completed_at = CURRENT_TIMESTAMP
+WHERE id = $2
+`
+
+type FailDataExportParams struct {
+	Error sql.NullString `json:"error"`
+	ID    int32          `json:"id"`
+}
+
+// Marks the export as failed with the given error
+func (q *Queries) FailDataExport(ctx context.Context, arg FailDataExportParams) error {
+	_, err := q.exec(ctx, q.failDataExportStmt, FailDataExport, arg.Error, arg.ID)
+	return err
+}
+
+const GetLatestDataExport = `-- name: GetLatestDataExport :one
+SELECT id, user_id, tenant_id, status, file_path, error, created_at, completed_at, expires_at, claimed_at FROM data_exports
+WHERE user_id = $1
+ORDER BY id DESC
+LIMIT 1
+`
+
+// Retrieves the most recent data export of the user
+func (q *Queries) GetLatestDataExport(ctx context.Context, userID int32) (DataExports, error) {
+	row := q.queryRow(ctx, q.getLatestDataExportStmt, GetLatestDataExport, userID)
+	var i DataExports
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TenantID,
+		&i.Status,
+		&i.FilePath,
+		&i.Error,
+		&i.CreatedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+		&i.ClaimedAt,
+	)
+	return i, err
+}
+
+const GetPendingErasureRequest = `-- name: GetPendingErasureRequest :one
+SELECT id, user_id, tenant_id, status, requested_at, scheduled_for, completed_at, cancelled_at FROM erasure_requests
+WHERE user_id = $1 AND status = 'pending'
+LIMIT 1
+`
+
+// Retrieves the pending erasure request of the user
+func (q *Queries) GetPendingErasureRequest(ctx context.Context, userID int32) (ErasureRequests, error) {
+	row := q.queryRow(ctx, q.getPendingErasureRequestStmt, GetPendingErasureRequest, userID)
+	var i ErasureRequests
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TenantID,
+		&i.Status,
+		&i.RequestedAt,
+		&i.ScheduledFor,
+		&i.CompletedAt,
+		&i.CancelledAt,
+	)
+	return i, err
+}
+
+const ListDueErasureRequests = `-- name: ListDueErasureRequests :many
+SELECT id, user_id, tenant_id, status, requested_at, scheduled_for, completed_at, cancelled_at FROM erasure_requests
+WHERE status = 'pending' AND scheduled_for <= CURRENT_TIMESTAMP
+ORDER BY scheduled_for
+LIMIT $1
+`
+
+// Lists pending erasure requests whose cooling-off period has ended
+func (q *Queries) ListDueErasureRequests(ctx context.Context, limit int32) ([]ErasureRequests, error) {
+	rows, err := q.query(ctx, q.listDueErasureRequestsStmt, ListDueErasureRequests, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ErasureRequests{}
+	for rows.Next() {
+		var i ErasureRequests
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.TenantID,
+			&i.Status,
+			&i.RequestedAt,
+			&i.ScheduledFor,
+			&i.CompletedAt,
+			&i.CancelledAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListExpiredDataExports = `-- name: ListExpiredDataExports :many
+SELECT id, user_id, tenant_id, status, file_path, error, created_at, completed_at, expires_at, claimed_at FROM data_exports
+WHERE status = 'ready' AND expires_at < CURRENT_TIMESTAMP
+`
+
+// Lists ready exports whose download window has passed
+func (q *Queries) ListExpiredDataExports(ctx context.Context) ([]DataExports, error) {
+	rows, err := q.query(ctx, q.listExpiredDataExportsStmt, ListExpiredDataExports)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DataExports{}
+	for rows.Next() {
+		var i DataExports
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.TenantID,
+			&i.Status,
+			&i.FilePath,
+			&i.Error,
+			&i.CreatedAt,
+			&i.CompletedAt,
+			&i.ExpiresAt,
+			&i.ClaimedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -6,30 +6,168 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 )
 
 type Querier interface {
+	// Adds a user to an organization with the given role
+	AddMembership(ctx context.Context, arg AddMembershipParams) (OrgMemberships, error)
+	// Replaces the user's personal data with placeholders and marks the user deleted
+	// The empty password hash never matches, so the account cannot sign in again
+	AnonymizeUser(ctx context.Context, id int32) error
+	// Cancels the pending erasure request of the user during the cooling-off period
+	CancelErasureRequest(ctx context.Context, userID int32) (ErasureRequests, error)
+	// Marks the oldest pending export as processing and returns it
+	// Exports claimed over an hour ago and still processing are picked up again
+	// SKIP LOCKED lets several workers run concurrently
+	ClaimDataExport(ctx context.Context) (DataExports, error)
+	// Leases the events that are due for publishing, taking only the oldest
+	// pending event of each aggregate, so that the events of an aggregate are
+	// published one after another in order. An event whose lease expired, because
+	// its relay stopped, is claimed again.
+	// SKIP LOCKED lets several relays run concurrently
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]Outbox, error)
+	// Forgets who sent the invitations of the user
+	ClearInvitationsSentBy(ctx context.Context, invitedBy sql.NullInt32) error
+	// Marks the export as ready for download until it expires
+	CompleteDataExport(ctx context.Context, arg CompleteDataExportParams) error
+	// Marks the erasure request as completed
+	CompleteErasureRequest(ctx context.Context, id int32) error
+	// Creates a pending personal data export for the user
+	CreateDataExport(ctx context.Context, userID int32) (DataExports, error)
+	// Schedules erasure of the user's personal data
+	// Fails with a unique violation if a pending request already exists
+	CreateErasureRequest(ctx context.Context, arg CreateErasureRequestParams) (ErasureRequests, error)
+	// Creates a pending invitation into an organization
+	CreateInvitation(ctx context.Context, arg CreateInvitationParams) (OrgInvitations, error)
+	// Creates a new organization; the creator is added as owner separately
+	CreateOrganization(ctx context.Context, arg CreateOrganizationParams) (Organizations, error)
+	// Writes an event to the outbox; the tenant defaults to the tenant of the connection
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) error
 	// Creates a new user with the provided information
 	// Returns the newly created user
 	CreateUser(ctx context.Context, arg CreateUserParams) (Users, error)
-	// Deletes a user with the specified ID
-	// This operation is irreversible
+	// Gives up on the event; the next event of the aggregate is published next
+	DeadLetterOutboxEvent(ctx context.Context, arg DeadLetterOutboxEventParams) error
+	// Deletes the invitations sent to the email of the user in the user's tenant
+	// Must run before the user's email is anonymized
+	DeleteInvitationsForUser(ctx context.Context, id int32) error
+	// Removes a user from an organization
+	DeleteMembership(ctx context.Context, arg DeleteMembershipParams) (int64, error)
+	// Deletes an organization together with its memberships and invitations
+	DeleteOrganization(ctx context.Context, id int32) error
+	// Removes events published before the cutoff
+	DeletePublishedOutboxEvents(ctx context.Context, publishedBefore sql.NullTime) (int64, error)
+	// Soft-deletes a user with the specified ID
+	// The user can be restored until it is purged
 	DeleteUser(ctx context.Context, id int32) error
-	// Retrieves a user by their ID
-	// Returns a single user or null if not found
+	// User listings (search, count) are built dynamically in
+	// internal/user/search_query.go to support field selection
+	// Returns the planner's row estimate for the users table from pg_class
+	// Much cheaper than COUNT(*) on large tables, but approximate and unfiltered
+	// Returns -1 if the table has never been analyzed
+	EstimateUsersCount(ctx context.Context) (int64, error)
+	// Marks the export as expired once its archive has been removed
+	ExpireDataExport(ctx context.Context, id int32) error
+	// Marks expired pending invitations of an email as expired
+	// so that the email can be invited again
+	ExpireInvitationsForEmail(ctx context.Context, arg ExpireInvitationsForEmailParams) error
+	// Marks the export as failed with the given error
+	FailDataExport(ctx context.Context, arg FailDataExportParams) error
+	// Retrieves an invitation by the hash of the token sent by email
+	GetInvitationByTokenHash(ctx context.Context, tokenHash string) (OrgInvitations, error)
+	// Retrieves the most recent data export of the user
+	GetLatestDataExport(ctx context.Context, userID int32) (DataExports, error)
+	// Retrieves the membership of a user in an organization
+	GetMembership(ctx context.Context, arg GetMembershipParams) (OrgMemberships, error)
+	// Retrieves an organization by its ID
+	GetOrganization(ctx context.Context, id int32) (Organizations, error)
+	// Retrieves the pending erasure request of the user
+	GetPendingErasureRequest(ctx context.Context, userID int32) (ErasureRequests, error)
+	// Retrieves a tenant by the slug used as subdomain or X-Tenant header
+	GetTenantBySlug(ctx context.Context, slug string) (Tenants, error)
+	// Retrieves an active user by their ID
+	// Returns a single user or null if not found or deleted
 	GetUser(ctx context.Context, id int32) (Users, error)
-	// Retrieves a user by their username
-	// Returns a single user or null if not found
+	// Returns the avatar key of a user, including deleted ones
+	GetUserAvatarKey(ctx context.Context, id int32) (sql.NullString, error)
+	// Retrieves an active user by their username
+	// Returns a single user or null if not found or deleted
 	GetUserByUsername(ctx context.Context, username string) (Users, error)
-	// Searches for users based on various criteria
-	// Supports partial matching and date range for created_at
-	// Allows sorting by different fields in ascending or descending order
-	// Returns a paginated list of users
-	SearchUsers(ctx context.Context, arg SearchUsersParams) ([]Users, error)
+	// Retrieves the settings the user has changed from the defaults
+	GetUserSettings(ctx context.Context, id int32) (json.RawMessage, error)
+	// Retrieves the user's settings and locks the row until the transaction ends
+	GetUserSettingsForUpdate(ctx context.Context, id int32) (json.RawMessage, error)
+	// Returns the tenant of a user, including deleted ones
+	GetUserTenant(ctx context.Context, id int32) (int32, error)
+	// Retrieves the active users with the given IDs in no particular order
+	// IDs of missing or deleted users are skipped
+	GetUsersByIDs(ctx context.Context, ids []int32) ([]Users, error)
+	// Lists the events the relay gave up on, oldest first
+	ListDeadOutboxEvents(ctx context.Context, limit int32) ([]Outbox, error)
+	// Lists pending erasure requests whose cooling-off period has ended
+	ListDueErasureRequests(ctx context.Context, limit int32) ([]ErasureRequests, error)
+	// Lists ready exports whose download window has passed
+	ListExpiredDataExports(ctx context.Context) ([]DataExports, error)
+	// Lists the invitations sent to the email of the user in the user's tenant,
+	// including deleted users
+	ListInvitationsForUser(ctx context.Context, id int32) ([]OrgInvitations, error)
+	// Lists the invitations the user has sent
+	ListInvitationsSentBy(ctx context.Context, invitedBy sql.NullInt32) ([]OrgInvitations, error)
+	// Lists the members of an organization with their public profile data
+	// Soft-deleted users are not listed
+	ListMembers(ctx context.Context, orgID int32) ([]ListMembersRow, error)
+	// Lists the organizations of several users at once
+	// Used to embed organizations into user listings
+	ListOrganizationsByUserIDs(ctx context.Context, userIds []int32) ([]ListOrganizationsByUserIDsRow, error)
+	// Lists the invitations of an organization awaiting a response
+	ListPendingInvitations(ctx context.Context, orgID int32) ([]OrgInvitations, error)
+	// Returns active users holding any of the given usernames or emails
+	// Used to report conflicts of bulk imports before writing
+	ListTakenUsernamesAndEmails(ctx context.Context, arg ListTakenUsernamesAndEmailsParams) ([]ListTakenUsernamesAndEmailsRow, error)
+	// Lists the organizations the user is a member of, with the user's role
+	ListUserOrganizations(ctx context.Context, userID int32) ([]ListUserOrganizationsRow, error)
+	// Marks the event as published by every sink
+	MarkOutboxEventPublished(ctx context.Context, id int64) error
+	// Permanently deletes users soft-deleted before the given time
+	// Owners of organizations are kept until the ownership is transferred, as
+	// deleting them would leave their organizations without an owner
+	// Returns the deleted users with their tenants, to record an event about
+	// each of them, and their avatar keys so their blobs can be removed
+	// This operation is irreversible
+	PurgeDeletedUsers(ctx context.Context, deletedBefore sql.NullTime) ([]PurgeDeletedUsersRow, error)
+	// Queues a dead event for publishing again with a fresh number of attempts
+	RequeueDeadOutboxEvent(ctx context.Context, id int64) (int64, error)
+	// Queues all dead events for publishing again
+	RequeueDeadOutboxEvents(ctx context.Context) (int64, error)
+	// Moves a pending invitation to its final status: accepted, declined or revoked
+	RespondToInvitation(ctx context.Context, arg RespondToInvitationParams) (int64, error)
+	// Restores a soft-deleted user
+	// Fails with a unique violation if the username or email was taken meanwhile
+	RestoreUser(ctx context.Context, id int32) (Users, error)
+	// Releases the event after a failed attempt and postpones the next one
+	RetryOutboxEvent(ctx context.Context, arg RetryOutboxEventParams) error
+	// Grants or revokes the administrator role of an active user
+	// Returns the updated user
+	SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (Users, error)
+	// Sets or clears (NULL) the avatar of an active user
+	// Returns the updated user information
+	SetUserAvatar(ctx context.Context, arg SetUserAvatarParams) (Users, error)
+	// Replaces the settings the user has changed from the defaults
+	SetUserSettings(ctx context.Context, arg SetUserSettingsParams) error
+	// Changes the role of a member
+	UpdateMembershipRole(ctx context.Context, arg UpdateMembershipRoleParams) (OrgMemberships, error)
+	// Renames an organization
+	UpdateOrganization(ctx context.Context, arg UpdateOrganizationParams) (Organizations, error)
 	// Updates user information for the specified user ID
-	// Only updates non-null fields, leaving others unchanged
+	// Only updates non-null fields and non-empty username, email and password hash,
+	// leaving others unchanged
 	// Returns the updated user information
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (Users, error)
+	// Creates a tenant or renames the existing tenant with the same slug
+	// Used by seeding, which must be repeatable
+	UpsertTenant(ctx context.Context, arg UpsertTenantParams) (Tenants, error)
 }
 
 var _ Querier = (*Queries)(nil)
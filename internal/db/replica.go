@@ -0,0 +1,254 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultReplicaCheckInterval = 5 * time.Second
+	defaultReplicaMaxLag        = 10 * time.Second
+	defaultReadYourWritesWindow = 5 * time.Second
+	maxPinnedSessions           = 100000
+)
+
+// replicaLagQuery reports how far the standby is behind in seconds. A standby
+// that has replayed everything it received is not lagging, even if the last
+// replayed transaction is old because the primary is idle.
+const replicaLagQuery = `SELECT CASE
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END::float8`
+
+type sessionContextKey struct{}
+
+// SessionContextKey is the context key of the client session, a string such
+// as "user:42". Reads of a session that has written recently go to the
+// primary, so the client always sees its own writes; see PinToPrimary.
+var SessionContextKey any = sessionContextKey{}
+
+// replica is a read-only standby with its own pool
+type replica struct {
+	name    string
+	pool    *pgxpool.Pool
+	sqlDB   *sql.DB
+	healthy atomic.Bool
+}
+
+// newReplicas opens a pool per URL in DATABASE_REPLICA_URLS, a comma
+// separated list. Replicas start healthy; StartReplicaChecks keeps their
+// health and lag up to date.
+func newReplicas() ([]*replica, error) {
+	var replicas []*replica
+	for _, url := range strings.Split(viper.GetString("DATABASE_REPLICA_URLS"), ",") {
+		url = strings.TrimSpace(url)
+		if url == "" {
+			continue
+		}
+		// replicas are not waited for: one that is down only takes itself
+		// out of rotation
+		pool, err := newPool(url)
+		if err != nil {
+			return nil, err
+		}
+		r := &replica{
+			name:  pool.Config().ConnConfig.Host,
+			pool:  pool,
+			sqlDB: stdlib.OpenDBFromPool(pool),
+		}
+		r.healthy.Store(true)
+		replicas = append(replicas, r)
+	}
+	return replicas, nil
+}
+
+// replicaFor picks the replica that serves the reads of ctx, or nil if they
+// must go to the primary: there are no healthy replicas, or the session has
+// written within the read-your-writes window. Among healthy replicas the one
+// with the fewest connections in use wins, starting the scan at a rotating
+// offset so that idle replicas share the load.
+func (d *DB) replicaFor(ctx context.Context) *replica {
+	if len(d.replicas) == 0 || d.pinnedToPrimary(ctx) {
+		return nil
+	}
+	// the counter is reduced while unsigned, so that its wrap-around never
+	// yields a negative index
+	start := int(d.nextReplica.Add(1) % uint32(len(d.replicas)))
+	var best *replica
+	var bestInUse int32
+	for i := range d.replicas {
+		r := d.replicas[(start+i)%len(d.replicas)]
+		if !r.healthy.Load() {
+			continue
+		}
+		if inUse := r.pool.Stat().AcquiredConns(); best == nil || inUse < bestInUse {
+			best, bestInUse = r, inUse
+		}
+	}
+	return best
+}
+
+// PinToPrimary sends the reads of the session in ctx to the primary for the
+// DB_READ_YOUR_WRITES_WINDOW (5s by default), long enough for the replicas to
+// catch up with what the session has just written. Sessions are remembered
+// by this process only.
+func (d *DB) PinToPrimary(ctx context.Context) {
+	if session, ok := ctx.Value(SessionContextKey).(string); ok && session != "" && len(d.replicas) > 0 {
+		d.pinned.Set(session, struct{}{})
+	}
+}
+
+func (d *DB) pinnedToPrimary(ctx context.Context) bool {
+	session, ok := ctx.Value(SessionContextKey).(string)
+	if !ok || session == "" {
+		return false
+	}
+	_, pinned := d.pinned.Get(session)
+	return pinned
+}
+
+// StartReplicaChecks checks every DB_REPLICA_CHECK_INTERVAL that the replicas
+// answer and lag behind the primary by at most DB_REPLICA_MAX_LAG (0 turns
+// the lag check off). Unhealthy replicas get no reads until they recover.
+func (d *DB) StartReplicaChecks(ctx context.Context) {
+	if len(d.replicas) == 0 {
+		return
+	}
+	interval := viper.GetDuration("DB_REPLICA_CHECK_INTERVAL")
+	if interval <= 0 {
+		interval = defaultReplicaCheckInterval
+	}
+	maxLag := defaultReplicaMaxLag
+	if viper.IsSet("DB_REPLICA_MAX_LAG") {
+		maxLag = viper.GetDuration("DB_REPLICA_MAX_LAG")
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			for _, r := range d.replicas {
+				r.check(ctx, interval, maxLag)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (r *replica) check(ctx context.Context, timeout, maxLag time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var lagSeconds float64
+	err := r.pool.QueryRow(ctx, replicaLagQuery).Scan(&lagSeconds)
+	lag := time.Duration(lagSeconds * float64(time.Second))
+	healthy := err == nil && (maxLag <= 0 || lag <= maxLag)
+
+	if healthy != r.healthy.Swap(healthy) {
+		switch {
+		case err != nil:
+			log.Printf("Replica %s is down: %v", r.name, err)
+		case !healthy:
+			log.Printf("Replica %s lags behind by %s", r.name, lag)
+		default:
+			log.Printf("Replica %s is back", r.name)
+		}
+	}
+}
+
+// markDown takes a replica out of rotation after a connection failure, until
+// the next check finds it healthy again
+func (r *replica) markDown(err error) {
+	if r.healthy.Swap(false) {
+		log.Printf("Replica %s is down: %v", r.name, err)
+	}
+}
+
+// isConnectionError tells failures of the replica itself from errors of the
+// statement, which the primary would return as well
+func isConnectionError(ctx context.Context, err error) bool {
+	var pgErr *pgconn.PgError
+	return err != nil && ctx.Err() == nil && !errors.As(err, &pgErr) && !errors.Is(err, sql.ErrNoRows)
+}
+
+// replicaRouter is the DBTX returned by Reader. Statements run in the ambient
+// transaction if there is one, otherwise on a replica picked by replicaFor.
+// A replica that fails to answer is marked down and the statement is retried
+// on the primary.
+type replicaRouter struct {
+	db *DB
+}
+
+// Reader returns a handle that serves reads from the replicas. Use it for
+// reads that tolerate the replication lag of a client that has not written
+// recently; everything else goes through DB itself.
+func (d *DB) Reader() DBTX {
+	return replicaRouter{db: d}
+}
+
+// ReadQueries returns the sqlc queries bound to Reader
+func (d *DB) ReadQueries() *Queries {
+	return New(d.Reader())
+}
+
+// ExecContext always runs on the primary: replicas cannot write
+func (r replicaRouter) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.db.ExecContext(ctx, query, args...)
+}
+
+func (r replicaRouter) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if InTx(ctx) {
+		return r.db.PrepareContext(ctx, query)
+	}
+	if rep := r.db.replicaFor(ctx); rep != nil {
+		stmt, err := rep.sqlDB.PrepareContext(ctx, query)
+		if !isConnectionError(ctx, err) {
+			return stmt, err
+		}
+		rep.markDown(err)
+	}
+	return r.db.PrepareContext(ctx, query)
+}
+
+func (r replicaRouter) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if InTx(ctx) {
+		return r.db.QueryContext(ctx, query, args...)
+	}
+	if rep := r.db.replicaFor(ctx); rep != nil {
+		rows, err := rep.sqlDB.QueryContext(ctx, query, args...)
+		if !isConnectionError(ctx, err) {
+			return rows, err
+		}
+		rep.markDown(err)
+	}
+	return r.db.QueryContext(ctx, query, args...)
+}
+
+func (r replicaRouter) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if InTx(ctx) {
+		return r.db.QueryRowContext(ctx, query, args...)
+	}
+	if rep := r.db.replicaFor(ctx); rep != nil {
+		row := rep.sqlDB.QueryRowContext(ctx, query, args...)
+		err := row.Err()
+		if !isConnectionError(ctx, err) {
+			return row
+		}
+		rep.markDown(err)
+	}
+	return r.db.QueryRowContext(ctx, query, args...)
+}
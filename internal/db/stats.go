@@ -0,0 +1,81 @@
+package db
+
+import (
+	"expvar"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PoolStats is a snapshot of a connection pool
+type PoolStats struct {
+	// Name is the host of a replica; empty for the primary
+	Name string `json:"name,omitempty"`
+	// Healthy tells whether a replica receives reads
+	Healthy *bool `json:"healthy,omitempty"`
+
+	MaxConns          int32 `json:"max_conns"`
+	TotalConns        int32 `json:"total_conns"`
+	AcquiredConns     int32 `json:"acquired_conns"`
+	IdleConns         int32 `json:"idle_conns"`
+	ConstructingConns int32 `json:"constructing_conns"`
+	// AcquireCount counts successful acquires; EmptyAcquireCount those that
+	// had to wait for a connection, a sign that the pool is too small
+	AcquireCount            int64 `json:"acquire_count"`
+	EmptyAcquireCount       int64 `json:"empty_acquire_count"`
+	CanceledAcquireCount    int64 `json:"canceled_acquire_count"`
+	AcquireDurationMs       int64 `json:"acquire_duration_ms"`
+	NewConnsCount           int64 `json:"new_conns_count"`
+	MaxLifetimeDestroyCount int64 `json:"max_lifetime_destroy_count"`
+	MaxIdleDestroyCount     int64 `json:"max_idle_destroy_count"`
+}
+
+// Stats describes the pools of the primary and of the replicas
+type Stats struct {
+	Primary  PoolStats   `json:"primary"`
+	Replicas []PoolStats `json:"replicas,omitempty"`
+}
+
+// monitoredDB is the DB whose pools are published with expvar under
+// "db_pool"; the most recently opened one wins
+var monitoredDB atomic.Pointer[DB]
+
+func init() {
+	expvar.Publish("db_pool", expvar.Func(func() any {
+		if d := monitoredDB.Load(); d != nil {
+			return d.Stats()
+		}
+		return nil
+	}))
+}
+
+// Stats returns a snapshot of the connection pools
+func (d *DB) Stats() Stats {
+	stats := Stats{Primary: poolStats(d.pool)}
+	for _, r := range d.replicas {
+		replicaStats := poolStats(r.pool)
+		healthy := r.healthy.Load()
+		replicaStats.Name = r.name
+		replicaStats.Healthy = &healthy
+		stats.Replicas = append(stats.Replicas, replicaStats)
+	}
+	return stats
+}
+
+func poolStats(pool *pgxpool.Pool) PoolStats {
+	stat := pool.Stat()
+	return PoolStats{
+		MaxConns:                stat.MaxConns(),
+		TotalConns:              stat.TotalConns(),
+		AcquiredConns:           stat.AcquiredConns(),
+		IdleConns:               stat.IdleConns(),
+		ConstructingConns:       stat.ConstructingConns(),
+		AcquireCount:            stat.AcquireCount(),
+		EmptyAcquireCount:       stat.EmptyAcquireCount(),
+		CanceledAcquireCount:    stat.CanceledAcquireCount(),
+		AcquireDurationMs:       stat.AcquireDuration().Milliseconds(),
+		NewConnsCount:           stat.NewConnsCount(),
+		MaxLifetimeDestroyCount: stat.MaxLifetimeDestroyCount(),
+		MaxIdleDestroyCount:     stat.MaxIdleDestroyCount(),
+	}
+}
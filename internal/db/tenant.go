@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// tenantSetting is the connection setting the row-level security policies read
+const tenantSetting = "app.tenant_id"
+
+// allTenantsSetting lifts tenant isolation for system work such as background jobs
+const allTenantsSetting = "*"
+
+type tenantContextKey struct{}
+
+type allTenants struct{}
+
+// TenantContextKey is the context key of the current tenant ID. fasthttp
+// request contexts expose fiber locals through Value, so setting this key
+// with Locals scopes the queries made with ctx.Context() to the tenant.
+var TenantContextKey any = tenantContextKey{}
+
+// WithTenant returns a context whose queries only see the tenant's rows
+func WithTenant(ctx context.Context, tenantID int32) context.Context {
+	return context.WithValue(ctx, TenantContextKey, tenantID)
+}
+
+// WithAllTenants returns a context whose queries see the rows of every
+// tenant. It is meant for background jobs and maintenance commands only.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, TenantContextKey, allTenants{})
+}
+
+// TenantFromContext returns the tenant the context is scoped to
+func TenantFromContext(ctx context.Context) (int32, bool) {
+	tenantID, ok := ctx.Value(TenantContextKey).(int32)
+	return tenantID, ok
+}
+
+// AllTenantsFromContext reports whether the context was made by WithAllTenants
+func AllTenantsFromContext(ctx context.Context) bool {
+	_, ok := ctx.Value(TenantContextKey).(allTenants)
+	return ok
+}
+
+// tenantSettingValue renders the tenant scope of ctx as the value of app.tenant_id.
+// An empty value means no tenant, and the policies then hide all rows.
+func tenantSettingValue(ctx context.Context) string {
+	switch scope := ctx.Value(TenantContextKey).(type) {
+	case int32:
+		return strconv.FormatInt(int64(scope), 10)
+	case allTenants:
+		return allTenantsSetting
+	default:
+		return ""
+	}
+}
+
+// tenantScoper sets app.tenant_id on pooled connections as they are acquired.
+// It remembers the value set on each connection to skip the round trip while
+// the tenant stays the same.
+type tenantScoper struct {
+	settings sync.Map // *pgx.Conn -> string
+}
+
+// beforeAcquire is a pgxpool BeforeAcquire hook. A connection that cannot be
+// scoped is destroyed rather than handed out with another tenant's setting.
+func (s *tenantScoper) beforeAcquire(ctx context.Context, conn *pgx.Conn) bool {
+	value := tenantSettingValue(ctx)
+	if current, ok := s.settings.Load(conn); ok && current == value {
+		return true
+	}
+	if _, err := conn.Exec(ctx, "SELECT set_config($1, $2, false)", tenantSetting, value); err != nil {
+		s.settings.Delete(conn)
+		return false
+	}
+	s.settings.Store(conn, value)
+	return true
+}
+
+func (s *tenantScoper) beforeClose(conn *pgx.Conn) {
+	s.settings.Delete(conn)
+}
@@ -0,0 +1,54 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: tenant.sql
+
+package db
+
+import (
+	"context"
+)
+
+const GetTenantBySlug = `-- name: GetTenantBySlug :one
+SELECT id, slug, name, created_at FROM tenants
+WHERE slug = $1
+`
+
+// Retrieves a tenant by the slug used as subdomain or X-Tenant header
+func (q *Queries) GetTenantBySlug(ctx context.Context, slug string) (Tenants, error) {
+	row := q.queryRow(ctx, q.getTenantBySlugStmt, GetTenantBySlug, slug)
+	var i Tenants
+	err := row.Scan(
+		&i.ID,
+		&i.Slug,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const UpsertTenant = `-- name: UpsertTenant :one
+INSERT INTO tenants (slug, name)
+VALUES ($1, $2)
+ON CONFLICT (slug) DO UPDATE SET name = EXCLUDED.name
+RETURNING id, slug, name, created_at
+`
+
+type UpsertTenantParams struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// Creates a tenant or renames the existing tenant with the same slug
+// Used by seeding, which must be repeatable
+func (q *Queries) UpsertTenant(ctx context.Context, arg UpsertTenantParams) (Tenants, error) {
+	row := q.queryRow(ctx, q.upsertTenantStmt, UpsertTenant, arg.Slug, arg.Name)
+	var i Tenants
+	err := row.Scan(
+		&i.ID,
+		&i.Slug,
+		&i.Name,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,327 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultTxMaxAttempts    = 5
+	defaultTxRetryBaseDelay = 10 * time.Millisecond
+	defaultTxRetryMaxDelay  = time.Second
+)
+
+var (
+	// ErrReadOnlyTx is returned when a read-write transaction is requested
+	// inside a read-only one
+	ErrReadOnlyTx = errors.New("db: read-write transaction requested inside a read-only transaction")
+	// ErrTxIsolation is returned when a nested transaction requests a
+	// stricter isolation level than the transaction it joins
+	ErrTxIsolation = errors.New("db: nested transaction requests a stricter isolation level")
+)
+
+// txMetrics are published with expvar under "db_tx"
+var txMetrics = struct {
+	started          *expvar.Int
+	committed        *expvar.Int
+	rolledBack       *expvar.Int
+	retries          *expvar.Map
+	retriesExhausted *expvar.Int
+}{
+	started:          new(expvar.Int),
+	committed:        new(expvar.Int),
+	rolledBack:       new(expvar.Int),
+	retries:          new(expvar.Map).Init(),
+	retriesExhausted: new(expvar.Int),
+}
+
+func init() {
+	metrics := expvar.NewMap("db_tx")
+	metrics.Set("started", txMetrics.started)
+	metrics.Set("committed", txMetrics.committed)
+	metrics.Set("rolled_back", txMetrics.rolledBack)
+	// retries by SQLSTATE: 40001 serialization failure, 40P01 deadlock
+	metrics.Set("retries", txMetrics.retries)
+	metrics.Set("retries_exhausted", txMetrics.retriesExhausted)
+}
+
+// TxOptions configure a transaction started by TxManager.WithinTx
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// MaxAttempts bounds how many times a transaction aborted by a
+	// serialization failure or deadlock is run; 1 disables retries
+	MaxAttempts int
+}
+
+type TxOption func(*TxOptions)
+
+// WithIsolation sets the isolation level of the transaction
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *TxOptions) {
+		o.Isolation = level
+	}
+}
+
+// ReadOnly starts a read-only transaction
+func ReadOnly() TxOption {
+	return func(o *TxOptions) {
+		o.ReadOnly = true
+	}
+}
+
+// WithMaxAttempts overrides the number of attempts of the transaction
+func WithMaxAttempts(attempts int) TxOption {
+	return func(o *TxOptions) {
+		o.MaxAttempts = max(attempts, 1)
+	}
+}
+
+type txContextKey struct{}
+
+// txState is the ambient transaction carried by a context
+type txState struct {
+	conn        *sql.Conn
+	tx          *sql.Tx
+	options     TxOptions
+	afterCommit []func(ctx context.Context) error
+}
+
+func txFromContext(ctx context.Context) *txState {
+	state, _ := ctx.Value(txContextKey{}).(*txState)
+	return state
+}
+
+// InTx reports whether ctx carries a transaction
+func InTx(ctx context.Context) bool {
+	return txFromContext(ctx) != nil
+}
+
+// AfterCommit defers a side effect that must not happen unless the data it
+// depends on is committed, such as sending an email or dropping a cache
+// entry. Inside a transaction fn runs once the outermost transaction commits
+// and never for attempts that are rolled back or retried; its error is then
+// only logged. Outside a transaction fn runs immediately and its error is
+// returned.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context) error) error {
+	if state := txFromContext(ctx); state != nil {
+		state.afterCommit = append(state.afterCommit, fn)
+		return nil
+	}
+	return fn(ctx)
+}
+
+// Transactor runs units of work in transactions. TxManager is the
+// implementation backed by the database; test doubles of repositories may
+// provide their own.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error
+}
+
+// TxManager runs units of work in transactions. The transaction travels in
+// the context passed to the unit of work, and every repository built on DB
+// uses it automatically.
+type TxManager struct {
+	db             *DB
+	defaults       TxOptions
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+}
+
+// NewTxManager reads DB_TX_ISOLATION, the default isolation level: read
+// committed (the default), repeatable read or serializable. Transactions
+// aborted by serialization failures or deadlocks are retried up to
+// DB_TX_MAX_ATTEMPTS times with a full-jitter exponential backoff between
+// DB_TX_RETRY_BASE_DELAY and DB_TX_RETRY_MAX_DELAY.
+func NewTxManager(database *DB) (*TxManager, error) {
+	isolation, err := parseIsolationLevel(viper.GetString("DB_TX_ISOLATION"))
+	if err != nil {
+		return nil, err
+	}
+	maxAttempts := viper.GetInt("DB_TX_MAX_ATTEMPTS")
+	if maxAttempts <= 0 {
+		maxAttempts = defaultTxMaxAttempts
+	}
+	baseDelay := viper.GetDuration("DB_TX_RETRY_BASE_DELAY")
+	if baseDelay <= 0 {
+		baseDelay = defaultTxRetryBaseDelay
+	}
+	maxDelay := viper.GetDuration("DB_TX_RETRY_MAX_DELAY")
+	if maxDelay < baseDelay {
+		maxDelay = max(defaultTxRetryMaxDelay, baseDelay)
+	}
+	return &TxManager{
+		db:             database,
+		defaults:       TxOptions{Isolation: isolation, MaxAttempts: maxAttempts},
+		retryBaseDelay: baseDelay,
+		retryMaxDelay:  maxDelay,
+	}, nil
+}
+
+// WithinTx runs fn in a transaction that is committed if fn returns nil and
+// rolled back otherwise. fn must use the context it is given, and since a
+// transaction aborted by a serialization failure or deadlock is retried from
+// the start, fn may run several times: side effects outside the database
+// belong in AfterCommit. A call inside another transaction joins it instead
+// of starting a new one; it fails if it needs stronger guarantees than the
+// outer transaction provides, and retries are left to the outer transaction.
+// Read-only transactions may be served by a replica; a committed read-write
+// transaction pins the session to the primary, see PinToPrimary.
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	options := m.defaults
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if outer := txFromContext(ctx); outer != nil {
+		if outer.options.ReadOnly && !options.ReadOnly {
+			return ErrReadOnlyTx
+		}
+		if isolationRank(options.Isolation) > isolationRank(outer.options.Isolation) {
+			return ErrTxIsolation
+		}
+		return fn(ctx)
+	}
+
+	for attempt := 1; ; attempt++ {
+		afterCommit, err := m.runTx(ctx, fn, options)
+		if err == nil {
+			if !options.ReadOnly {
+				m.db.PinToPrimary(ctx)
+			}
+			for _, hook := range afterCommit {
+				if err := hook(ctx); err != nil {
+					log.Printf("After-commit hook failed: %v", err)
+				}
+			}
+			return nil
+		}
+		if !IsRetryable(err) {
+			return err
+		}
+		if attempt >= options.MaxAttempts {
+			txMetrics.retriesExhausted.Add(1)
+			return fmt.Errorf("transaction failed after %d attempts: %w", attempt, err)
+		}
+
+		var pgCode string
+		if HasErrorCode(err, DeadlockDetected) {
+			pgCode = DeadlockDetected
+		} else {
+			pgCode = SerializationFailure
+		}
+		txMetrics.retries.Add(pgCode, 1)
+
+		timer := time.NewTimer(m.retryDelay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// runTx makes one attempt and returns the after-commit hooks it registered
+func (m *TxManager) runTx(ctx context.Context, fn func(ctx context.Context) error, options TxOptions) (_ []func(ctx context.Context) error, err error) {
+	conn, tx, err := m.begin(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	txMetrics.started.Add(1)
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			txMetrics.rolledBack.Add(1)
+			panic(p)
+		}
+	}()
+
+	state := &txState{conn: conn, tx: tx, options: options}
+	if err := fn(context.WithValue(ctx, txContextKey{}, state)); err != nil {
+		tx.Rollback()
+		txMetrics.rolledBack.Add(1)
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		txMetrics.rolledBack.Add(1)
+		return nil, err
+	}
+	txMetrics.committed.Add(1)
+	return state.afterCommit, nil
+}
+
+// begin starts a transaction on a dedicated connection, which lets
+// WithPgxConn reach the transaction. Read-only transactions run on a replica
+// if one is available, except serializable ones, which standbys do not
+// support.
+func (m *TxManager) begin(ctx context.Context, options TxOptions) (*sql.Conn, *sql.Tx, error) {
+	txOptions := &sql.TxOptions{Isolation: options.Isolation, ReadOnly: options.ReadOnly}
+	if options.ReadOnly && options.Isolation != sql.LevelSerializable {
+		if rep := m.db.replicaFor(ctx); rep != nil {
+			conn, tx, err := beginOn(ctx, rep.sqlDB, txOptions)
+			if !isConnectionError(ctx, err) {
+				return conn, tx, err
+			}
+			rep.markDown(err)
+		}
+	}
+	return beginOn(ctx, m.db.sqlDB, txOptions)
+}
+
+func beginOn(ctx context.Context, sqlDB *sql.DB, txOptions *sql.TxOptions) (*sql.Conn, *sql.Tx, error) {
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, err := conn.BeginTx(ctx, txOptions)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, tx, nil
+}
+
+// retryDelay picks a random delay up to an exponentially growing cap, so
+// that transactions that collided do not collide again on the next attempt
+func (m *TxManager) retryDelay(attempt int) time.Duration {
+	ceiling := m.retryMaxDelay
+	if shift := attempt - 1; shift < 32 {
+		ceiling = min(m.retryBaseDelay<<shift, m.retryMaxDelay)
+	}
+	return rand.N(ceiling) + 1
+}
+
+// isolationRank orders isolation levels by strength; the server default is
+// read committed in PostgreSQL
+func isolationRank(level sql.IsolationLevel) int {
+	if level == sql.LevelDefault {
+		return int(sql.LevelReadCommitted)
+	}
+	return int(level)
+}
+
+func parseIsolationLevel(name string) (sql.IsolationLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "default":
+		return sql.LevelDefault, nil
+	case "read committed", "read_committed":
+		return sql.LevelReadCommitted, nil
+	case "repeatable read", "repeatable_read":
+		return sql.LevelRepeatableRead, nil
+	case "serializable":
+		return sql.LevelSerializable, nil
+	default:
+		return sql.LevelDefault, fmt.Errorf("db: unsupported isolation level %q", name)
+	}
+}
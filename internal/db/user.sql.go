@@ -8,17 +8,41 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 
-	"time"
+	"github.com/lib/pq"
 )
 
+const AnonymizeUser = `-- name: AnonymizeUser :exec
+UPDATE users
+SET
+    username = 'erased_' || id,
+    email = 'erased_' || id || '@invalid',
+    password_hash = '',
+    full_name = NULL,
+    bio = NULL,
+    avatar_key = NULL,
+    settings = '{}'::jsonb,
+    is_admin = FALSE,
+    deleted_at = COALESCE(deleted_at, CURRENT_TIMESTAMP),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+// Replaces the user's personal data with placeholders and marks the user deleted
+// The empty password hash never matches, so the account cannot sign in again
+func (q *Queries) AnonymizeUser(ctx context.Context, id int32) error {
+	_, err := q.exec(ctx, q.anonymizeUserStmt, AnonymizeUser, id)
+	return err
+}
+
 const CreateUser = `-- name: CreateUser :one
 INSERT INTO users (
     username, email, password_hash, full_name, bio
 ) VALUES (
     $1, $2, $3, $4, $5
 )
-RETURNING id, username, email, password_hash, full_name, bio, created_at, updated_at
+RETURNING id, username, email, password_hash, full_name, bio, created_at, updated_at, search_vector, deleted_at, is_admin, avatar_key, tenant_id, settings
 `
 
 type CreateUserParams struct {
@@ -49,29 +73,55 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (Users,
 		&i.Bio,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SearchVector,
+		&i.DeletedAt,
+		&i.IsAdmin,
+		&i.AvatarKey,
+		&i.TenantID,
+		&i.Settings,
 	)
 	return i, err
 }
 
 const DeleteUser = `-- name: DeleteUser :exec
-DELETE FROM users
-WHERE id = $1
+UPDATE users
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL
 `
 
-// Deletes a user with the specified ID
-// This operation is irreversible
+// Soft-deletes a user with the specified ID
+// The user can be restored until it is purged
 func (q *Queries) DeleteUser(ctx context.Context, id int32) error {
 	_, err := q.exec(ctx, q.deleteUserStmt, DeleteUser, id)
 	return err
 }
 
+const EstimateUsersCount = `-- name: EstimateUsersCount :one
+
+SELECT reltuples::bigint AS estimate
+FROM pg_catalog.pg_class
+WHERE oid = 'users'::regclass
+`
+
+// User listings (search, count) are built dynamically in
+// internal/user/search_query.go to support field selection
+// Returns the planner's row estimate for the users table from pg_class
+// Much cheaper than COUNT(*) on large tables, but approximate and unfiltered
+// Returns -1 if the table has never been analyzed
+func (q *Queries) EstimateUsersCount(ctx context.Context) (int64, error) {
+	row := q.queryRow(ctx, q.estimateUsersCountStmt, EstimateUsersCount)
+	var estimate int64
+	err := row.Scan(&estimate)
+	return estimate, err
+}
+
 const GetUser = `-- name: GetUser :one
-SELECT id, username, email, password_hash, full_name, bio, created_at, updated_at FROM users
-WHERE id = $1 LIMIT 1
+SELECT id, username, email, password_hash, full_name, bio, created_at, updated_at, search_vector, deleted_at, is_admin, avatar_key, tenant_id, settings FROM users
+WHERE id = $1 AND deleted_at IS NULL LIMIT 1
 `
 
-// Retrieves a user by their ID
-// Returns a single user or null if not found
+// Retrieves an active user by their ID
+// Returns a single user or null if not found or deleted
 func (q *Queries) GetUser(ctx context.Context, id int32) (Users, error) {
 	row := q.queryRow(ctx, q.getUserStmt, GetUser, id)
 	var i Users
@@ -84,17 +134,36 @@ func (q *Queries) GetUser(ctx context.Context, id int32) (Users, error) {
 		&i.Bio,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SearchVector,
+		&i.DeletedAt,
+		&i.IsAdmin,
+		&i.AvatarKey,
+		&i.TenantID,
+		&i.Settings,
 	)
 	return i, err
 }
 
+const GetUserAvatarKey = `-- name: GetUserAvatarKey :one
+SELECT avatar_key FROM users
+WHERE id = $1
+`
+
+// Returns the avatar key of a user, including deleted ones
+func (q *Queries) GetUserAvatarKey(ctx context.Context, id int32) (sql.NullString, error) {
+	row := q.queryRow(ctx, q.getUserAvatarKeyStmt, GetUserAvatarKey, id)
+	var avatar_key sql.NullString
+	err := row.Scan(&avatar_key)
+	return avatar_key, err
+}
+
 const GetUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, username, email, password_hash, full_name, bio, created_at, updated_at FROM users
-WHERE username = $1 LIMIT 1
+SELECT id, username, email, password_hash, full_name, bio, created_at, updated_at, search_vector, deleted_at, is_admin, avatar_key, tenant_id, settings FROM users
+WHERE username = $1 AND deleted_at IS NULL LIMIT 1
 `
 
-// Retrieves a user by their username
-// Returns a single user or null if not found
+// Retrieves an active user by their username
+// Returns a single user or null if not found or deleted
 func (q *Queries) GetUserByUsername(ctx context.Context, username string) (Users, error) {
 	row := q.queryRow(ctx, q.getUserByUsernameStmt, GetUserByUsername, username)
 	var i Users
@@ -107,77 +176,65 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (Users
 		&i.Bio,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SearchVector,
+		&i.DeletedAt,
+		&i.IsAdmin,
+		&i.AvatarKey,
+		&i.TenantID,
+		&i.Settings,
 	)
 	return i, err
 }
 
-const SearchUsers = `-- name: SearchUsers :many
-SELECT id, username, email, password_hash, full_name, bio, created_at, updated_at
-FROM users
-WHERE 
-    ($1::text IS NULL OR username ILIKE '%' || $1::text || '%')
-    AND ($2::text IS NULL OR email ILIKE '%' || $2::text || '%')
-    AND ($3::text IS NULL OR full_name ILIKE '%' || $3::text || '%')
-    AND ($4::text IS NULL OR bio ILIKE '%' || $4::text || '%')
-    AND ($5::timestamptz IS NULL OR DATE(created_at) >= $5::date)
-    AND ($6::timestamptz IS NULL OR DATE(created_at) <= $6::date)
-    AND ($7::text IS NULL OR 
-         LOWER(email) LIKE '%' || LOWER($7::text) || '%' OR
-         LOWER(username) LIKE '%' || LOWER($7::text) || '%' OR
-         LOWER(full_name) LIKE '%' || LOWER($7::text) || '%' OR
-         LOWER(bio) LIKE '%' || LOWER($7::text) || '%')
-ORDER BY
-    CASE 
-        WHEN $8::text = 'username_asc' THEN username
-        WHEN $8::text = 'email_asc' THEN email
-        WHEN $8::text = 'created_at_asc' THEN NULL
-        WHEN $8::text = 'id_asc' THEN NULL
-    END ASC,
-    CASE 
-        WHEN $8::text = 'username_desc' THEN username
-        WHEN $8::text = 'email_desc' THEN email
-        WHEN $8::text = 'created_at_desc' THEN NULL
-        WHEN $8::text = 'id_desc' THEN NULL
-    END DESC,
-    CASE WHEN $8::text = 'created_at_asc' THEN created_at END ASC,
-    CASE WHEN $8::text = 'id_asc' THEN id END ASC,
-    CASE WHEN $8::text = 'created_at_desc' THEN created_at END DESC,
-    CASE WHEN $8::text = 'id_desc' THEN id END DESC,
-    id ASC -- Always fallback sort by id
-LIMIT $10::int
-OFFSET $9::int
+const GetUserSettings = `-- name: GetUserSettings :one
+SELECT settings FROM users
+WHERE id = $1 AND deleted_at IS NULL
 `
 
-type SearchUsersParams struct {
-	Username    string        `json:"username"`
-	Email       string        `json:"email"`
-	FullName    string        `json:"full_name"`
-	Bio         string        `json:"bio"`
-	CreatedFrom **time.Time   `json:"created_from"`
-	CreatedTo   **time.Time   `json:"created_to"`
-	Search      string        `json:"search"`
-	SortBy      string        `json:"sort_by"`
-	OffsetParam sql.NullInt32 `json:"offset_param"`
-	LimitParam  sql.NullInt32 `json:"limit_param"`
+// Retrieves the settings the user has changed from the defaults
+func (q *Queries) GetUserSettings(ctx context.Context, id int32) (json.RawMessage, error) {
+	row := q.queryRow(ctx, q.getUserSettingsStmt, GetUserSettings, id)
+	var settings json.RawMessage
+	err := row.Scan(&settings)
+	return settings, err
 }
 
-// Searches for users based on various criteria
-// Supports partial matching and date range for created_at
-// Allows sorting by different fields in ascending or descending order
-// Returns a paginated list of users
-func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]Users, error) {
-	rows, err := q.query(ctx, q.searchUsersStmt, SearchUsers,
-		arg.Username,
-		arg.Email,
-		arg.FullName,
-		arg.Bio,
-		arg.CreatedFrom,
-		arg.CreatedTo,
-		arg.Search,
-		arg.SortBy,
-		arg.OffsetParam,
-		arg.LimitParam,
-	)
+const GetUserSettingsForUpdate = `-- name: GetUserSettingsForUpdate :one
+SELECT settings FROM users
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+// Retrieves the user's settings and locks the row until the transaction ends
+func (q *Queries) GetUserSettingsForUpdate(ctx context.Context, id int32) (json.RawMessage, error) {
+	row := q.queryRow(ctx, q.getUserSettingsForUpdateStmt, GetUserSettingsForUpdate, id)
+	var settings json.RawMessage
+	err := row.Scan(&settings)
+	return settings, err
+}
+
+const GetUserTenant = `-- name: GetUserTenant :one
+SELECT tenant_id FROM users
+WHERE id = $1
+`
+
+// Returns the tenant of a user, including deleted ones
+func (q *Queries) GetUserTenant(ctx context.Context, id int32) (int32, error) {
+	row := q.queryRow(ctx, q.getUserTenantStmt, GetUserTenant, id)
+	var tenant_id int32
+	err := row.Scan(&tenant_id)
+	return tenant_id, err
+}
+
+const GetUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT id, username, email, password_hash, full_name, bio, created_at, updated_at, search_vector, deleted_at, is_admin, avatar_key, tenant_id, settings FROM users
+WHERE id = ANY($1::int[]) AND deleted_at IS NULL
+`
+
+// Retrieves the active users with the given IDs in no particular order
+// IDs of missing or deleted users are skipped
+func (q *Queries) GetUsersByIDs(ctx context.Context, ids []int32) ([]Users, error) {
+	rows, err := q.query(ctx, q.getUsersByIDsStmt, GetUsersByIDs, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
@@ -194,6 +251,12 @@ func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]Use
 			&i.Bio,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.SearchVector,
+			&i.DeletedAt,
+			&i.IsAdmin,
+			&i.AvatarKey,
+			&i.TenantID,
+			&i.Settings,
 		); err != nil {
 			return nil, err
 		}
@@ -208,17 +271,227 @@ func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]Use
 	return items, nil
 }
 
+const ListTakenUsernamesAndEmails = `-- name: ListTakenUsernamesAndEmails :many
+SELECT username, email FROM users
+WHERE deleted_at IS NULL
+  AND (username = ANY($1::text[]) OR email = ANY($2::text[]))
+`
+
+type ListTakenUsernamesAndEmailsParams struct {
+	Usernames []string `json:"usernames"`
+	Emails    []string `json:"emails"`
+}
+
+type ListTakenUsernamesAndEmailsRow struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// Returns active users holding any of the given usernames or emails
+// Used to report conflicts of bulk imports before writing
+func (q *Queries) ListTakenUsernamesAndEmails(ctx context.Context, arg ListTakenUsernamesAndEmailsParams) ([]ListTakenUsernamesAndEmailsRow, error) {
+	rows, err := q.query(ctx, q.listTakenUsernamesAndEmailsStmt, ListTakenUsernamesAndEmails, pq.Array(arg.Usernames), pq.Array(arg.Emails))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTakenUsernamesAndEmailsRow{}
+	for rows.Next() {
+		var i ListTakenUsernamesAndEmailsRow
+		if err := rows.Scan(&i.Username, &i.Email); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const PurgeDeletedUsers = `-- name: PurgeDeletedUsers :many
+DELETE FROM users
+WHERE deleted_at IS NOT NULL AND deleted_at < $1
+    AND NOT EXISTS (
+        SELECT 1 FROM org_memberships m
+        WHERE m.user_id = users.id AND m.role = 'owner'
+    )
+RETURNING id, tenant_id, avatar_key
+`
+
+type PurgeDeletedUsersRow struct {
+	ID        int32          `json:"id"`
+	TenantID  int32          `json:"tenant_id"`
+	AvatarKey sql.NullString `json:"avatar_key"`
+}
+
+// Permanently deletes users soft-deleted before the given time
+// Owners of organizations are kept until the ownership is transferred, as
+// deleting them would leave their organizations without an owner
+// Returns the deleted users with their tenants, to record an event about
+// each of them, and their avatar keys so their blobs can be removed
+// This operation is irreversible
+func (q *Queries) PurgeDeletedUsers(ctx context.Context, deletedBefore sql.NullTime) ([]PurgeDeletedUsersRow, error) {
+	rows, err := q.query(ctx, q.purgeDeletedUsersStmt, PurgeDeletedUsers, deletedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PurgeDeletedUsersRow{}
+	for rows.Next() {
+		var i PurgeDeletedUsersRow
+		if err := rows.Scan(&i.ID, &i.TenantID, &i.AvatarKey); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const RestoreUser = `-- name: RestoreUser :one
+UPDATE users
+SET
+    deleted_at = NULL,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING id, username, email, password_hash, full_name, bio, created_at, updated_at, search_vector, deleted_at, is_admin, avatar_key, tenant_id, settings
+`
+
+// Restores a soft-deleted user
+// Fails with a unique violation if the username or email was taken meanwhile
+func (q *Queries) RestoreUser(ctx context.Context, id int32) (Users, error) {
+	row := q.queryRow(ctx, q.restoreUserStmt, RestoreUser, id)
+	var i Users
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.FullName,
+		&i.Bio,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SearchVector,
+		&i.DeletedAt,
+		&i.IsAdmin,
+		&i.AvatarKey,
+		&i.TenantID,
+		&i.Settings,
+	)
+	return i, err
+}
+
+const SetUserAdmin = `-- name: SetUserAdmin :one
+UPDATE users
+SET is_admin = $1, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING id, username, email, password_hash, full_name, bio, created_at, updated_at, search_vector, deleted_at, is_admin, avatar_key, tenant_id, settings
+`
+
+type SetUserAdminParams struct {
+	IsAdmin bool  `json:"is_admin"`
+	ID      int32 `json:"id"`
+}
+
+// Grants or revokes the administrator role of an active user
+// Returns the updated user
+func (q *Queries) SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (Users, error) {
+	row := q.queryRow(ctx, q.setUserAdminStmt, SetUserAdmin, arg.IsAdmin, arg.ID)
+	var i Users
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.FullName,
+		&i.Bio,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SearchVector,
+		&i.DeletedAt,
+		&i.IsAdmin,
+		&i.AvatarKey,
+		&i.TenantID,
+		&i.Settings,
+	)
+	return i, err
+}
+
+const SetUserAvatar = `-- name: SetUserAvatar :one
+UPDATE users
+SET
+    avatar_key = $2,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, username, email, password_hash, full_name, bio, created_at, updated_at, search_vector, deleted_at, is_admin, avatar_key, tenant_id, settings
+`
+
+type SetUserAvatarParams struct {
+	ID        int32          `json:"id"`
+	AvatarKey sql.NullString `json:"avatar_key"`
+}
+
+// Sets or clears (NULL) the avatar of an active user
+// Returns the updated user information
+func (q *Queries) SetUserAvatar(ctx context.Context, arg SetUserAvatarParams) (Users, error) {
+	row := q.queryRow(ctx, q.setUserAvatarStmt, SetUserAvatar, arg.ID, arg.AvatarKey)
+	var i Users
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.FullName,
+		&i.Bio,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SearchVector,
+		&i.DeletedAt,
+		&i.IsAdmin,
+		&i.AvatarKey,
+		&i.TenantID,
+		&i.Settings,
+	)
+	return i, err
+}
+
+const SetUserSettings = `-- name: SetUserSettings :exec
+UPDATE users
+SET settings = $2
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+type SetUserSettingsParams struct {
+	ID       int32           `json:"id"`
+	Settings json.RawMessage `json:"settings"`
+}
+
+// Replaces the settings the user has changed from the defaults
+func (q *Queries) SetUserSettings(ctx context.Context, arg SetUserSettingsParams) error {
+	_, err := q.exec(ctx, q.setUserSettingsStmt, SetUserSettings, arg.ID, arg.Settings)
+	return err
+}
+
 const UpdateUser = `-- name: UpdateUser :one
 UPDATE users
 SET
-    username = COALESCE($1, username),
-    email = COALESCE($2, email),
-    password_hash = COALESCE($3, password_hash),
+    username = COALESCE(NULLIF($1::text, ''), username),
+    email = COALESCE(NULLIF($2::text, ''), email),
+    password_hash = COALESCE(NULLIF($3::text, ''), password_hash),
     full_name = COALESCE($4, full_name),
     bio = COALESCE($5, bio),
     updated_at = CURRENT_TIMESTAMP
-WHERE id = $6
-RETURNING id, username, email, password_hash, full_name, bio, created_at, updated_at
+WHERE id = $6 AND deleted_at IS NULL
+RETURNING id, username, email, password_hash, full_name, bio, created_at, updated_at, search_vector, deleted_at, is_admin, avatar_key, tenant_id, settings
 `
 
 type UpdateUserParams struct {
@@ -231,7 +504,8 @@ type UpdateUserParams struct {
 }
 
 // Updates user information for the specified user ID
-// Only updates non-null fields, leaving others unchanged
+// Only updates non-null fields and non-empty username, email and password hash,
+// leaving others unchanged
 // Returns the updated user information
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (Users, error) {
 	row := q.queryRow(ctx, q.updateUserStmt, UpdateUser,
@@ -252,6 +526,12 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (Users,
 		&i.Bio,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SearchVector,
+		&i.DeletedAt,
+		&i.IsAdmin,
+		&i.AvatarKey,
+		&i.TenantID,
+		&i.Settings,
 	)
 	return i, err
 }
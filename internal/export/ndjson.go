@@ -0,0 +1,46 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// ndjsonWriter writes one JSON object per line, keeping the column order
+type ndjsonWriter struct {
+	w    io.Writer
+	keys [][]byte
+	buf  bytes.Buffer
+}
+
+func newNDJSONWriter(w io.Writer, columns []string) *ndjsonWriter {
+	keys := make([][]byte, len(columns))
+	for i, column := range columns {
+		keys[i], _ = json.Marshal(column)
+	}
+	return &ndjsonWriter{w: w, keys: keys}
+}
+
+func (nw *ndjsonWriter) WriteRow(values []any) error {
+	nw.buf.Reset()
+	nw.buf.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			nw.buf.WriteByte(',')
+		}
+		nw.buf.Write(nw.keys[i])
+		nw.buf.WriteByte(':')
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		nw.buf.Write(data)
+	}
+	nw.buf.WriteString("}\n")
+	_, err := nw.w.Write(nw.buf.Bytes())
+	return err
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nil
+}
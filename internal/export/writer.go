@@ -0,0 +1,92 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Export formats
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+var ErrInvalidFormat = errors.New("invalid export format: expected csv, ndjson or xlsx")
+
+// RowWriter writes records with a fixed set of columns. Values are aligned
+// with the columns passed to NewRowWriter. Close must be called to finish
+// the output; it does not close the underlying writer.
+type RowWriter interface {
+	WriteRow(values []any) error
+	Close() error
+}
+
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatCSV, FormatNDJSON, FormatXLSX:
+		return true
+	}
+	return false
+}
+
+// ContentType returns the MIME type of an export format
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+// NewRowWriter creates a streaming writer for the format. The CSV and XLSX
+// writers emit the columns as a header row.
+func NewRowWriter(format string, w io.Writer, columns []string) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w, columns)
+	case FormatNDJSON:
+		return newNDJSONWriter(w, columns), nil
+	case FormatXLSX:
+		return newXLSXWriter(w, columns)
+	}
+	return nil, ErrInvalidFormat
+}
+
+// formatCell renders a value for tabular formats; nested values become JSON
+func formatCell(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	if string(data) == "null" {
+		return "", nil
+	}
+	return string(data), nil
+}
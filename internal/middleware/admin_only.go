@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+func IsAdmin(c *fiber.Ctx) bool {
+	return c.Locals("is_admin") == true
+}
+
+func AdminOnly(handler fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !IsAdmin(c) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Admin privileges required"})
+		}
+		return handler(c)
+	}
+}
@@ -25,6 +25,16 @@ func AuthMiddleware() fiber.Handler {
 		}
 
 		c.Locals("user_id", claims.UserID)
+		c.Locals("is_admin", claims.IsAdmin)
+		if claims.TenantID != 0 {
+			c.Locals("tenant_id", claims.TenantID)
+		}
 		return c.Next()
 	}
 }
+
+// CurrentUserID returns the ID of the authenticated user
+func CurrentUserID(c *fiber.Ctx) (int32, bool) {
+	userID, ok := c.Locals("user_id").(int32)
+	return userID, ok
+}
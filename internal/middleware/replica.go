@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+)
+
+// ReadYourWritesMiddleware keeps the reads of a client on the primary while
+// the replicas may not have its latest writes yet. It must run after
+// AuthMiddleware: the client is the authenticated user, or the remote address
+// for anonymous requests such as sign-up. A request with an unsafe method
+// reads from the primary itself and pins the client for
+// DB_READ_YOUR_WRITES_WINDOW after it completes.
+func ReadYourWritesMiddleware(database *db.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		session := fmt.Sprintf("ip:%s", c.IP())
+		if userID, ok := CurrentUserID(c); ok {
+			session = fmt.Sprintf("user:%d", userID)
+		}
+		c.Locals(db.SessionContextKey, session)
+
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+		database.PinToPrimary(c.Context())
+		err := c.Next()
+		database.PinToPrimary(c.Context())
+		return err
+	}
+}
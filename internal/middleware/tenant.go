@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/tenant"
+	"github.com/spf13/viper"
+)
+
+const defaultTenantHeader = "X-Tenant"
+
+var tenantSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+// TenantMiddleware resolves the tenant of the request and scopes the database
+// queries made with ctx.Context() to it. It must run after AuthMiddleware.
+//
+// The tenant comes from the subdomain of TENANT_BASE_DOMAIN, the TENANT_HEADER
+// header (X-Tenant by default) or the tenant_id claim of the token; the
+// sources that are present must agree. TENANT_DEFAULT names the tenant of
+// requests that carry none of them; without it such requests are rejected.
+func TenantMiddleware(resolver *tenant.TenantResolver) fiber.Handler {
+	baseDomain := strings.ToLower(strings.Trim(viper.GetString("TENANT_BASE_DOMAIN"), "."))
+	header := viper.GetString("TENANT_HEADER")
+	if header == "" {
+		header = defaultTenantHeader
+	}
+	defaultSlug := viper.GetString("TENANT_DEFAULT")
+
+	return func(c *fiber.Ctx) error {
+		slug := strings.ToLower(strings.TrimSpace(c.Get(header)))
+		if subdomain := tenantSubdomain(c.Hostname(), baseDomain); subdomain != "" {
+			if slug != "" && slug != subdomain {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tenant header does not match the subdomain"})
+			}
+			slug = subdomain
+		}
+		claimTenantID, hasClaim := c.Locals("tenant_id").(int32)
+		if slug == "" && !hasClaim {
+			slug = defaultSlug
+		}
+
+		var tenantID int32
+		if slug != "" {
+			if !tenantSlugPattern.MatchString(slug) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid tenant"})
+			}
+			var err error
+			tenantID, err = resolver.ResolveTenant(c.Context(), slug)
+			if errors.Is(err, tenant.ErrTenantNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Unknown tenant"})
+			}
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to resolve tenant"})
+			}
+		}
+		if hasClaim {
+			if tenantID != 0 && tenantID != claimTenantID {
+				return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Token was issued for another tenant"})
+			}
+			tenantID = claimTenantID
+		}
+		if tenantID == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Tenant is required"})
+		}
+
+		c.Locals("tenant_id", tenantID)
+		c.Locals(db.TenantContextKey, tenantID)
+		return c.Next()
+	}
+}
+
+// CurrentTenantID returns the ID of the tenant of the request
+func CurrentTenantID(c *fiber.Ctx) (int32, bool) {
+	tenantID, ok := c.Locals("tenant_id").(int32)
+	return tenantID, ok
+}
+
+// tenantSubdomain returns the first label of host when host is a subdomain
+// of baseDomain, e.g. "acme" for acme.example.com
+func tenantSubdomain(host, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	subdomain, ok := strings.CutSuffix(host, "."+baseDomain)
+	if !ok || subdomain == "" || strings.Contains(subdomain, ".") {
+		return ""
+	}
+	return subdomain
+}
@@ -114,18 +114,65 @@ func matchPath(actualPath, registeredPath string) bool {
 func handleValidationError(c *fiber.Ctx, errors validator.ValidationErrors) error {
 	var errorMessages []string
 	for _, err := range errors {
-		switch err.Field() {
-		case "Password":
-			errorMessages = append(errorMessages, getPasswordErrorMessage(err))
-		default:
-			errorMessages = append(errorMessages, getErrorMessage(err))
-		}
+		errorMessages = append(errorMessages, validationMessage(err))
 	}
 	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 		"errors": errorMessages,
 	})
 }
 
+// FieldError describes a validation failure of a single DTO field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidateStruct validates a DTO outside of a request, e.g. rows of a bulk
+// upload, with the same rules and messages as ValidateDTO. Fields are named
+// by their JSON names. Returns nil if the DTO is valid.
+func ValidateStruct(dto interface{}) []FieldError {
+	err := validate.Struct(dto)
+	if err == nil {
+		return nil
+	}
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return []FieldError{{Message: err.Error()}}
+	}
+
+	dtoType := reflect.TypeOf(dto)
+	if dtoType.Kind() == reflect.Ptr {
+		dtoType = dtoType.Elem()
+	}
+	fieldErrors := make([]FieldError, len(validationErrors))
+	for i, err := range validationErrors {
+		fieldErrors[i] = FieldError{
+			Field:   jsonFieldName(dtoType, err.StructField()),
+			Message: validationMessage(err),
+		}
+	}
+	return fieldErrors
+}
+
+func jsonFieldName(dtoType reflect.Type, structField string) string {
+	field, ok := dtoType.FieldByName(structField)
+	if !ok {
+		return structField
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return structField
+	}
+	return name
+}
+
+func validationMessage(err validator.FieldError) string {
+	if err.Field() == "Password" {
+		return getPasswordErrorMessage(err)
+	}
+	return getErrorMessage(err)
+}
+
 // Returns error message for Password field
 func getPasswordErrorMessage(err validator.FieldError) string {
 	switch err.Tag() {
@@ -163,6 +210,12 @@ func getErrorMessage(err validator.FieldError) string {
 		return "Maximum length: " + err.Param()
 	case "alphanum":
 		return "Only letters and numbers are allowed"
+	case "oneof":
+		return "Must be one of: " + strings.ReplaceAll(err.Param(), " ", ", ")
+	case "timezone":
+		return "Invalid IANA time zone"
+	case "bcp47_language_tag":
+		return "Invalid language tag"
 	}
 	return "Does not meet the rule: " + err.Tag()
 }
@@ -0,0 +1,478 @@
+package org
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/malytinKonstantin/go-fiber/internal/middleware"
+)
+
+const (
+	errInvalidID          = "invalid ID"
+	errInvalidDTO         = "invalid input: DTO is nil"
+	errInvalidDTOType     = "internal server error: invalid DTO type"
+	errUnauthorized       = "unauthorized"
+	errInternal           = "internal server error"
+	errFailedToSendInvite = "failed to send invitation email"
+)
+
+type OrgController struct {
+	service *OrgService
+}
+
+func NewOrgController(service *OrgService) *OrgController {
+	return &OrgController{service: service}
+}
+
+func sendErrorResponse(ctx *fiber.Ctx, status int, message string) error {
+	return ctx.Status(status).JSON(fiber.Map{"error": message})
+}
+
+// sendServiceError maps service errors to HTTP statuses
+func sendServiceError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrOrgNotFound),
+		errors.Is(err, ErrMemberNotFound),
+		errors.Is(err, ErrInvitationNotFound):
+		return sendErrorResponse(ctx, fiber.StatusNotFound, err.Error())
+	case errors.Is(err, ErrForbidden),
+		errors.Is(err, ErrInvitationEmailMismatch):
+		return sendErrorResponse(ctx, fiber.StatusForbidden, err.Error())
+	case errors.Is(err, ErrSlugTaken),
+		errors.Is(err, ErrAlreadyMember),
+		errors.Is(err, ErrAlreadyInvited),
+		errors.Is(err, ErrOwnerCannotLeave),
+		errors.Is(err, ErrCannotChangeOwnerRole):
+		return sendErrorResponse(ctx, fiber.StatusConflict, err.Error())
+	case errors.Is(err, ErrInvitationExpired):
+		return sendErrorResponse(ctx, fiber.StatusGone, err.Error())
+	default:
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, errInternal)
+	}
+}
+
+func getDTO[T any](ctx *fiber.Ctx) (*T, error) {
+	dtoInterface := ctx.Locals("dto")
+	if dtoInterface == nil {
+		return nil, errors.New(errInvalidDTO)
+	}
+	dto, ok := dtoInterface.(*T)
+	if !ok {
+		return nil, errors.New(errInvalidDTOType)
+	}
+	return dto, nil
+}
+
+// SetupRoutes sets up the organization-related routes
+func (c *OrgController) SetupRoutes(router fiber.Router) {
+	middleware.RegisterDTO("/orgs", "POST", CreateOrgDto{})
+	router.Post("/orgs", c.CreateOrganization)
+	router.Get("/orgs", c.ListOrganizations)
+	router.Get("/orgs/:id", c.GetOrganization)
+	middleware.RegisterDTO("/orgs/:id", "PATCH", UpdateOrgDto{})
+	router.Patch("/orgs/:id", c.UpdateOrganization)
+	router.Delete("/orgs/:id", c.DeleteOrganization)
+	middleware.RegisterDTO("/orgs/:id/transfer", "POST", TransferOwnershipDto{})
+	router.Post("/orgs/:id/transfer", c.TransferOwnership)
+
+	router.Get("/orgs/:id/members", c.ListMembers)
+	middleware.RegisterDTO("/orgs/:id/members/:userId", "PATCH", UpdateMemberDto{})
+	router.Patch("/orgs/:id/members/:userId", c.UpdateMember)
+	router.Delete("/orgs/:id/members/:userId", c.RemoveMember)
+
+	middleware.RegisterDTO("/orgs/:id/invitations", "POST", InviteDto{})
+	router.Post("/orgs/:id/invitations", c.Invite)
+	router.Get("/orgs/:id/invitations", c.ListInvitations)
+	router.Delete("/orgs/:id/invitations/:invitationId", c.RevokeInvitation)
+	middleware.RegisterDTO("/invitations/accept", "POST", InvitationTokenDto{})
+	router.Post("/invitations/accept", c.AcceptInvitation)
+	middleware.RegisterDTO("/invitations/decline", "POST", InvitationTokenDto{})
+	router.Post("/invitations/decline", c.DeclineInvitation)
+}
+
+// CreateOrganization creates an organization owned by the current user
+// @Summary Create an organization
+// @Tags organizations
+// @Param org body CreateOrgDto true "Organization data"
+// @Success 201 {object} Organization
+// @Failure 400,401,409,500 {object} ErrorResponse
+// @Router /api/v1/orgs [post]
+func (c *OrgController) CreateOrganization(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+	dto, err := getDTO[CreateOrgDto](ctx)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	org, err := c.service.CreateOrganization(ctx.Context(), userID, *dto)
+	if err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(org)
+}
+
+// ListOrganizations lists the organizations of the current user
+// @Summary List my organizations
+// @Tags organizations
+// @Success 200 {array} Organization
+// @Failure 401,500 {object} ErrorResponse
+// @Router /api/v1/orgs [get]
+func (c *OrgController) ListOrganizations(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+
+	orgs, err := c.service.ListOrganizations(ctx.Context(), userID)
+	if err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.JSON(orgs)
+}
+
+// GetOrganization retrieves an organization the current user is a member of
+// @Summary Get an organization
+// @Tags organizations
+// @Param id path int true "Organization ID"
+// @Success 200 {object} Organization
+// @Failure 400,401,404,500 {object} ErrorResponse
+// @Router /api/v1/orgs/{id} [get]
+func (c *OrgController) GetOrganization(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+	orgID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+
+	org, err := c.service.GetOrganization(ctx.Context(), userID, int32(orgID))
+	if err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.JSON(org)
+}
+
+// UpdateOrganization renames an organization
+// @Summary Update an organization
+// @Description Requires the admin or owner role
+// @Tags organizations
+// @Param id path int true "Organization ID"
+// @Param org body UpdateOrgDto true "Organization data"
+// @Success 200 {object} Organization
+// @Failure 400,401,403,404,500 {object} ErrorResponse
+// @Router /api/v1/orgs/{id} [patch]
+func (c *OrgController) UpdateOrganization(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+	orgID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+	dto, err := getDTO[UpdateOrgDto](ctx)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	org, err := c.service.UpdateOrganization(ctx.Context(), userID, int32(orgID), *dto)
+	if err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.JSON(org)
+}
+
+// DeleteOrganization deletes an organization
+// @Summary Delete an organization
+// @Description Requires the owner role
+// @Tags organizations
+// @Param id path int true "Organization ID"
+// @Success 204 "No Content"
+// @Failure 400,401,403,404,500 {object} ErrorResponse
+// @Router /api/v1/orgs/{id} [delete]
+func (c *OrgController) DeleteOrganization(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+	orgID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+
+	if err := c.service.DeleteOrganization(ctx.Context(), userID, int32(orgID)); err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// TransferOwnership makes another member the owner of an organization
+// @Summary Transfer ownership
+// @Description Requires the owner role; the previous owner becomes an admin
+// @Tags organizations
+// @Param id path int true "Organization ID"
+// @Param transfer body TransferOwnershipDto true "New owner"
+// @Success 204 "No Content"
+// @Failure 400,401,403,404,500 {object} ErrorResponse
+// @Router /api/v1/orgs/{id}/transfer [post]
+func (c *OrgController) TransferOwnership(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+	orgID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+	dto, err := getDTO[TransferOwnershipDto](ctx)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := c.service.TransferOwnership(ctx.Context(), userID, int32(orgID), dto.UserID); err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// ListMembers lists the members of an organization
+// @Summary List members
+// @Tags organizations
+// @Param id path int true "Organization ID"
+// @Success 200 {array} Member
+// @Failure 400,401,404,500 {object} ErrorResponse
+// @Router /api/v1/orgs/{id}/members [get]
+func (c *OrgController) ListMembers(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+	orgID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+
+	members, err := c.service.ListMembers(ctx.Context(), userID, int32(orgID))
+	if err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.JSON(members)
+}
+
+// UpdateMember changes the role of a member
+// @Summary Change a member's role
+// @Description Requires the admin or owner role
+// @Tags organizations
+// @Param id path int true "Organization ID"
+// @Param userId path int true "User ID"
+// @Param member body UpdateMemberDto true "New role"
+// @Success 200 {object} Membership
+// @Failure 400,401,403,404,409,500 {object} ErrorResponse
+// @Router /api/v1/orgs/{id}/members/{userId} [patch]
+func (c *OrgController) UpdateMember(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+	orgID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+	memberID, err := ctx.ParamsInt("userId")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+	dto, err := getDTO[UpdateMemberDto](ctx)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	membership, err := c.service.UpdateMemberRole(ctx.Context(), userID, int32(orgID), int32(memberID), dto.Role)
+	if err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.JSON(membership)
+}
+
+// RemoveMember removes a member from an organization or leaves it
+// @Summary Remove a member
+// @Description Admins can remove other members; any member can remove themselves. The owner cannot leave.
+// @Tags organizations
+// @Param id path int true "Organization ID"
+// @Param userId path int true "User ID"
+// @Success 204 "No Content"
+// @Failure 400,401,403,404,409,500 {object} ErrorResponse
+// @Router /api/v1/orgs/{id}/members/{userId} [delete]
+func (c *OrgController) RemoveMember(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+	orgID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+	memberID, err := ctx.ParamsInt("userId")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+
+	if err := c.service.RemoveMember(ctx.Context(), userID, int32(orgID), int32(memberID)); err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// Invite sends an email invitation into an organization
+// @Summary Invite by email
+// @Description Requires the admin or owner role
+// @Tags organizations
+// @Param id path int true "Organization ID"
+// @Param invitation body InviteDto true "Invitation data"
+// @Success 201 {object} Invitation
+// @Failure 400,401,403,404,409,500,502 {object} ErrorResponse
+// @Router /api/v1/orgs/{id}/invitations [post]
+func (c *OrgController) Invite(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+	orgID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+	dto, err := getDTO[InviteDto](ctx)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	invitation, err := c.service.Invite(ctx.Context(), userID, int32(orgID), *dto)
+	if errors.Is(err, ErrSendInvitation) {
+		return sendErrorResponse(ctx, fiber.StatusBadGateway, errFailedToSendInvite)
+	}
+	if err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(invitation)
+}
+
+// ListInvitations lists the pending invitations of an organization
+// @Summary List pending invitations
+// @Description Requires the admin or owner role
+// @Tags organizations
+// @Param id path int true "Organization ID"
+// @Success 200 {array} Invitation
+// @Failure 400,401,403,404,500 {object} ErrorResponse
+// @Router /api/v1/orgs/{id}/invitations [get]
+func (c *OrgController) ListInvitations(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+	orgID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+
+	invitations, err := c.service.ListInvitations(ctx.Context(), userID, int32(orgID))
+	if err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.JSON(invitations)
+}
+
+// RevokeInvitation cancels a pending invitation
+// @Summary Revoke an invitation
+// @Description Requires the admin or owner role
+// @Tags organizations
+// @Param id path int true "Organization ID"
+// @Param invitationId path int true "Invitation ID"
+// @Success 204 "No Content"
+// @Failure 400,401,403,404,500 {object} ErrorResponse
+// @Router /api/v1/orgs/{id}/invitations/{invitationId} [delete]
+func (c *OrgController) RevokeInvitation(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+	orgID, err := ctx.ParamsInt("id")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+	invitationID, err := ctx.ParamsInt("invitationId")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+
+	if err := c.service.RevokeInvitation(ctx.Context(), userID, int32(orgID), int32(invitationID)); err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// AcceptInvitation joins the organization of an invitation
+// @Summary Accept an invitation
+// @Description The invitation must have been sent to the current user's email
+// @Tags organizations
+// @Param invitation body InvitationTokenDto true "Invitation token"
+// @Success 200 {object} Membership
+// @Failure 400,401,403,404,409,410,500 {object} ErrorResponse
+// @Router /api/v1/invitations/accept [post]
+func (c *OrgController) AcceptInvitation(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+	dto, err := getDTO[InvitationTokenDto](ctx)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	membership, err := c.service.AcceptInvitation(ctx.Context(), userID, dto.Token)
+	if err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.JSON(membership)
+}
+
+// DeclineInvitation rejects an invitation
+// @Summary Decline an invitation
+// @Tags organizations
+// @Param invitation body InvitationTokenDto true "Invitation token"
+// @Success 204 "No Content"
+// @Failure 400,401,403,404,410,500 {object} ErrorResponse
+// @Router /api/v1/invitations/decline [post]
+func (c *OrgController) DeclineInvitation(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+	dto, err := getDTO[InvitationTokenDto](ctx)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := c.service.DeclineInvitation(ctx.Context(), userID, dto.Token); err != nil {
+		return sendServiceError(ctx, err)
+	}
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,80 @@
+package org
+
+// CreateOrgDto represents the data for creating an organization
+// swagger:model
+type CreateOrgDto struct {
+	// Display name of the organization
+	// required: true
+	// max: 100
+	// example: Acme Inc.
+	Name string `json:"name" validate:"required,max=100"`
+
+	// Unique URL-friendly identifier
+	// required: true
+	// min: 3
+	// max: 50
+	// example: acme
+	Slug string `json:"slug" validate:"required,min=3,max=50,lowercase,alphanum"`
+}
+
+// UpdateOrgDto represents the data for renaming an organization
+// swagger:model
+type UpdateOrgDto struct {
+	// Display name of the organization
+	// required: true
+	// max: 100
+	// example: Acme Corporation
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// UpdateMemberDto represents the data for changing a member's role
+// swagger:model
+type UpdateMemberDto struct {
+	// New role of the member
+	// required: true
+	// enum: admin,member
+	// example: admin
+	Role string `json:"role" validate:"required,oneof=admin member"`
+}
+
+// TransferOwnershipDto represents the data for transferring ownership
+// swagger:model
+type TransferOwnershipDto struct {
+	// ID of the member who becomes the owner
+	// required: true
+	// example: 2
+	UserID int32 `json:"user_id" validate:"required,gt=0"`
+}
+
+// InviteDto represents the data for inviting someone into an organization
+// swagger:model
+type InviteDto struct {
+	// Email the invitation is sent to
+	// required: true
+	// max: 100
+	// example: jane@example.com
+	Email string `json:"email" validate:"required,email,max=100"`
+
+	// Role granted on acceptance
+	// required: true
+	// enum: admin,member
+	// example: member
+	Role string `json:"role" validate:"required,oneof=admin member"`
+}
+
+// InvitationTokenDto represents the token received in an invitation email
+// swagger:model
+type InvitationTokenDto struct {
+	// Invitation token
+	// required: true
+	// example: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+	Token string `json:"token" validate:"required,len=64,hexadecimal"`
+}
+
+// ErrorResponse represents the structure of an error response
+// swagger:model
+type ErrorResponse struct {
+	// Error message
+	// example: organization not found
+	Error string `json:"error"`
+}
@@ -0,0 +1,15 @@
+package org
+
+import "github.com/gofiber/fiber/v2"
+
+type Module struct {
+	Controller *OrgController
+}
+
+func NewModule(controller *OrgController) *Module {
+	return &Module{Controller: controller}
+}
+
+func (m *Module) SetupRoutes(router fiber.Router) {
+	m.Controller.SetupRoutes(router)
+}
@@ -0,0 +1,54 @@
+package org
+
+import (
+	"context"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/privacy"
+)
+
+// PrivacyParticipant exports and erases the memberships and invitations of
+// a user. It has to be registered before the user module, whose eraser
+// replaces the email the invitations are matched by.
+type PrivacyParticipant struct {
+	repo *OrgRepository
+	tx   *db.TxManager
+}
+
+func NewPrivacyParticipant(repo *OrgRepository, tx *db.TxManager) *PrivacyParticipant {
+	return &PrivacyParticipant{repo: repo, tx: tx}
+}
+
+func (p *PrivacyParticipant) Export(ctx context.Context, userID int32, w *privacy.ArchiveWriter) error {
+	orgs, err := p.repo.ListUserOrganizations(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteJSON("organizations.json", orgs); err != nil {
+		return err
+	}
+	received, err := p.repo.ListInvitationsForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteJSON("invitations_received.json", received); err != nil {
+		return err
+	}
+	sent, err := p.repo.ListInvitationsSentBy(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return w.WriteJSON("invitations_sent.json", sent)
+}
+
+// Erase deletes the invitations sent to the user's email and forgets who
+// sent the user's invitations. Memberships stay, as they only refer to the
+// anonymized user and organizations must keep their owner.
+func (p *PrivacyParticipant) Erase(ctx context.Context, userID int32) error {
+	return p.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := p.repo.DeleteInvitationsForUser(ctx, userID); err != nil {
+			return err
+		}
+		return p.repo.ClearInvitationsSentBy(ctx, userID)
+	})
+}
@@ -0,0 +1,285 @@
+package org
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+)
+
+// Membership roles, from the most to the least privileged
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+	InvitationRevoked  = "revoked"
+	InvitationExpired  = "expired"
+)
+
+type Organization struct {
+	ID        int32     `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Role of the current user; set when listing the user's organizations
+	Role string `json:"role,omitempty"`
+}
+
+type Membership struct {
+	OrgID     int32     `json:"org_id"`
+	UserID    int32     `json:"user_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Member struct {
+	UserID   int32     `json:"user_id"`
+	Username string    `json:"username"`
+	FullName string    `json:"full_name"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// UserOrganization is an organization embedded into user listings
+type UserOrganization struct {
+	ID   int32  `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+	Role string `json:"role"`
+}
+
+type Invitation struct {
+	ID        int32     `json:"id"`
+	OrgID     int32     `json:"org_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	InvitedBy *int32    `json:"invited_by,omitempty"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	TenantID  int32     `json:"-"`
+}
+
+type OrgRepository struct {
+	q *db.Queries
+}
+
+func NewOrgRepository(database *db.DB) *OrgRepository {
+	return &OrgRepository{q: database.Queries()}
+}
+
+func (r *OrgRepository) CreateOrganization(ctx context.Context, name, slug string) (Organization, error) {
+	dbOrg, err := r.q.CreateOrganization(ctx, db.CreateOrganizationParams{Name: name, Slug: slug})
+	if err != nil {
+		return Organization{}, err
+	}
+	return convertDbOrganization(dbOrg), nil
+}
+
+func (r *OrgRepository) GetOrganization(ctx context.Context, id int32) (Organization, error) {
+	dbOrg, err := r.q.GetOrganization(ctx, id)
+	if err != nil {
+		return Organization{}, err
+	}
+	return convertDbOrganization(dbOrg), nil
+}
+
+func (r *OrgRepository) UpdateOrganization(ctx context.Context, id int32, name string) (Organization, error) {
+	dbOrg, err := r.q.UpdateOrganization(ctx, db.UpdateOrganizationParams{ID: id, Name: name})
+	if err != nil {
+		return Organization{}, err
+	}
+	return convertDbOrganization(dbOrg), nil
+}
+
+func (r *OrgRepository) DeleteOrganization(ctx context.Context, id int32) error {
+	return r.q.DeleteOrganization(ctx, id)
+}
+
+func (r *OrgRepository) ListUserOrganizations(ctx context.Context, userID int32) ([]Organization, error) {
+	rows, err := r.q.ListUserOrganizations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	orgs := make([]Organization, len(rows))
+	for i, row := range rows {
+		orgs[i] = Organization{
+			ID:        row.ID,
+			Name:      row.Name,
+			Slug:      row.Slug,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Role:      row.Role,
+		}
+	}
+	return orgs, nil
+}
+
+// ListOrganizationsByUserIDs returns the organizations of each of the users
+func (r *OrgRepository) ListOrganizationsByUserIDs(ctx context.Context, userIDs []int32) (map[int32][]UserOrganization, error) {
+	rows, err := r.q.ListOrganizationsByUserIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	orgs := make(map[int32][]UserOrganization, len(userIDs))
+	for _, row := range rows {
+		orgs[row.UserID] = append(orgs[row.UserID], UserOrganization{
+			ID:   row.ID,
+			Name: row.Name,
+			Slug: row.Slug,
+			Role: row.Role,
+		})
+	}
+	return orgs, nil
+}
+
+func (r *OrgRepository) AddMembership(ctx context.Context, orgID, userID int32, role string) (Membership, error) {
+	dbMembership, err := r.q.AddMembership(ctx, db.AddMembershipParams{OrgID: orgID, UserID: userID, Role: role})
+	if err != nil {
+		return Membership{}, err
+	}
+	return convertDbMembership(dbMembership), nil
+}
+
+func (r *OrgRepository) GetMembership(ctx context.Context, orgID, userID int32) (Membership, error) {
+	dbMembership, err := r.q.GetMembership(ctx, db.GetMembershipParams{OrgID: orgID, UserID: userID})
+	if err != nil {
+		return Membership{}, err
+	}
+	return convertDbMembership(dbMembership), nil
+}
+
+func (r *OrgRepository) ListMembers(ctx context.Context, orgID int32) ([]Member, error) {
+	rows, err := r.q.ListMembers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]Member, len(rows))
+	for i, row := range rows {
+		members[i] = Member{
+			UserID:   row.UserID,
+			Username: row.Username,
+			FullName: row.FullName.String,
+			Role:     row.Role,
+			JoinedAt: row.CreatedAt,
+		}
+	}
+	return members, nil
+}
+
+func (r *OrgRepository) UpdateMembershipRole(ctx context.Context, orgID, userID int32, role string) (Membership, error) {
+	dbMembership, err := r.q.UpdateMembershipRole(ctx, db.UpdateMembershipRoleParams{OrgID: orgID, UserID: userID, Role: role})
+	if err != nil {
+		return Membership{}, err
+	}
+	return convertDbMembership(dbMembership), nil
+}
+
+func (r *OrgRepository) DeleteMembership(ctx context.Context, orgID, userID int32) (int64, error) {
+	return r.q.DeleteMembership(ctx, db.DeleteMembershipParams{OrgID: orgID, UserID: userID})
+}
+
+func (r *OrgRepository) ExpireInvitationsForEmail(ctx context.Context, orgID int32, email string) error {
+	return r.q.ExpireInvitationsForEmail(ctx, db.ExpireInvitationsForEmailParams{OrgID: orgID, Email: email})
+}
+
+func (r *OrgRepository) CreateInvitation(ctx context.Context, params db.CreateInvitationParams) (Invitation, error) {
+	dbInvitation, err := r.q.CreateInvitation(ctx, params)
+	if err != nil {
+		return Invitation{}, err
+	}
+	return convertDbInvitation(dbInvitation), nil
+}
+
+func (r *OrgRepository) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (Invitation, error) {
+	dbInvitation, err := r.q.GetInvitationByTokenHash(ctx, tokenHash)
+	if err != nil {
+		return Invitation{}, err
+	}
+	return convertDbInvitation(dbInvitation), nil
+}
+
+func (r *OrgRepository) ListPendingInvitations(ctx context.Context, orgID int32) ([]Invitation, error) {
+	return convertDbInvitations(r.q.ListPendingInvitations(ctx, orgID))
+}
+
+// ListInvitationsForUser returns the invitations sent to the user's email
+func (r *OrgRepository) ListInvitationsForUser(ctx context.Context, userID int32) ([]Invitation, error) {
+	return convertDbInvitations(r.q.ListInvitationsForUser(ctx, userID))
+}
+
+func (r *OrgRepository) ListInvitationsSentBy(ctx context.Context, userID int32) ([]Invitation, error) {
+	return convertDbInvitations(r.q.ListInvitationsSentBy(ctx, sql.NullInt32{Int32: userID, Valid: true}))
+}
+
+// DeleteInvitationsForUser deletes the invitations sent to the user's email
+func (r *OrgRepository) DeleteInvitationsForUser(ctx context.Context, userID int32) error {
+	return r.q.DeleteInvitationsForUser(ctx, userID)
+}
+
+func (r *OrgRepository) ClearInvitationsSentBy(ctx context.Context, userID int32) error {
+	return r.q.ClearInvitationsSentBy(ctx, sql.NullInt32{Int32: userID, Valid: true})
+}
+
+// RespondToInvitation sets the final status of a pending invitation and
+// reports whether it was still pending
+func (r *OrgRepository) RespondToInvitation(ctx context.Context, orgID, id int32, status string) (bool, error) {
+	updated, err := r.q.RespondToInvitation(ctx, db.RespondToInvitationParams{ID: id, OrgID: orgID, Status: status})
+	return updated > 0, err
+}
+
+func convertDbOrganization(dbOrg db.Organizations) Organization {
+	return Organization{
+		ID:        dbOrg.ID,
+		Name:      dbOrg.Name,
+		Slug:      dbOrg.Slug,
+		CreatedAt: dbOrg.CreatedAt,
+		UpdatedAt: dbOrg.UpdatedAt,
+	}
+}
+
+func convertDbMembership(dbMembership db.OrgMemberships) Membership {
+	return Membership{
+		OrgID:     dbMembership.OrgID,
+		UserID:    dbMembership.UserID,
+		Role:      dbMembership.Role,
+		CreatedAt: dbMembership.CreatedAt,
+	}
+}
+
+func convertDbInvitation(dbInvitation db.OrgInvitations) Invitation {
+	invitation := Invitation{
+		ID:        dbInvitation.ID,
+		OrgID:     dbInvitation.OrgID,
+		Email:     dbInvitation.Email,
+		Role:      dbInvitation.Role,
+		Status:    dbInvitation.Status,
+		ExpiresAt: dbInvitation.ExpiresAt,
+		CreatedAt: dbInvitation.CreatedAt,
+		TenantID:  dbInvitation.TenantID,
+	}
+	if dbInvitation.InvitedBy.Valid {
+		invitation.InvitedBy = &dbInvitation.InvitedBy.Int32
+	}
+	return invitation
+}
+
+func convertDbInvitations(dbInvitations []db.OrgInvitations, err error) ([]Invitation, error) {
+	if err != nil {
+		return nil, err
+	}
+	invitations := make([]Invitation, len(dbInvitations))
+	for i, dbInvitation := range dbInvitations {
+		invitations[i] = convertDbInvitation(dbInvitation)
+	}
+	return invitations, nil
+}
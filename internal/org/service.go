@@ -0,0 +1,476 @@
+package org
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/mail"
+	"github.com/malytinKonstantin/go-fiber/internal/user"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultInvitationTTL = 7 * 24 * time.Hour
+	invitationTokenBytes = 32
+	organizationsInclude = "organizations"
+)
+
+var (
+	ErrOrgNotFound             = errors.New("organization not found")
+	ErrForbidden               = errors.New("insufficient role in the organization")
+	ErrSlugTaken               = errors.New("slug is already taken by another organization")
+	ErrMemberNotFound          = errors.New("member not found")
+	ErrAlreadyMember           = errors.New("user is already a member of the organization")
+	ErrAlreadyInvited          = errors.New("email already has a pending invitation")
+	ErrOwnerCannotLeave        = errors.New("owner must transfer ownership before leaving")
+	ErrCannotChangeOwnerRole   = errors.New("owner role can only be changed by transferring ownership")
+	ErrInvitationNotFound      = errors.New("invitation not found")
+	ErrInvitationExpired       = errors.New("invitation has expired")
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to another email")
+	ErrSendInvitation          = errors.New("failed to send invitation email")
+)
+
+// roleRank orders roles so that a higher rank includes the lower ones
+var roleRank = map[string]int{
+	RoleMember: 1,
+	RoleAdmin:  2,
+	RoleOwner:  3,
+}
+
+type OrgService struct {
+	repo          *OrgRepository
+	tx            *db.TxManager
+	users         *user.UserService
+	mailer        mail.Mailer
+	invitationTTL time.Duration
+	inviteURL     string
+}
+
+// NewOrgService reads ORG_INVITATION_TTL as a Go duration and ORG_INVITE_URL,
+// the page that receives the invitation token as ?token=. It also makes
+// include=organizations available on user listings.
+func NewOrgService(repo *OrgRepository, tx *db.TxManager, users *user.UserService, mailer mail.Mailer) *OrgService {
+	invitationTTL := viper.GetDuration("ORG_INVITATION_TTL")
+	if invitationTTL <= 0 {
+		invitationTTL = defaultInvitationTTL
+	}
+	s := &OrgService{
+		repo:          repo,
+		tx:            tx,
+		users:         users,
+		mailer:        mailer,
+		invitationTTL: invitationTTL,
+		inviteURL:     viper.GetString("ORG_INVITE_URL"),
+	}
+	users.RegisterInclude(organizationsInclude, s.loadUserOrganizations)
+	return s
+}
+
+func (s *OrgService) loadUserOrganizations(ctx context.Context, ids []int32) (map[int32]any, error) {
+	orgs, err := s.repo.ListOrganizationsByUserIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[int32]any, len(orgs))
+	for userID, userOrgs := range orgs {
+		result[userID] = userOrgs
+	}
+	return result, nil
+}
+
+// CreateOrganization creates an organization owned by the user
+func (s *OrgService) CreateOrganization(ctx context.Context, userID int32, dto CreateOrgDto) (Organization, error) {
+	if err := ctx.Err(); err != nil {
+		return Organization{}, err
+	}
+	var org Organization
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		org, err = s.repo.CreateOrganization(ctx, dto.Name, dto.Slug)
+		if err != nil {
+			return err
+		}
+		_, err = s.repo.AddMembership(ctx, org.ID, userID, RoleOwner)
+		return err
+	})
+	if db.IsUniqueViolation(err) {
+		return Organization{}, ErrSlugTaken
+	}
+	if err != nil {
+		return Organization{}, err
+	}
+	org.Role = RoleOwner
+	return org, nil
+}
+
+// ListOrganizations returns the organizations the user is a member of
+func (s *OrgService) ListOrganizations(ctx context.Context, userID int32) ([]Organization, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.repo.ListUserOrganizations(ctx, userID)
+}
+
+// GetOrganization returns an organization to one of its members
+func (s *OrgService) GetOrganization(ctx context.Context, userID, orgID int32) (Organization, error) {
+	if err := ctx.Err(); err != nil {
+		return Organization{}, err
+	}
+	membership, err := s.authorize(ctx, userID, orgID, RoleMember)
+	if err != nil {
+		return Organization{}, err
+	}
+	org, err := s.repo.GetOrganization(ctx, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Organization{}, ErrOrgNotFound
+	}
+	if err != nil {
+		return Organization{}, err
+	}
+	org.Role = membership.Role
+	return org, nil
+}
+
+// UpdateOrganization renames an organization; admins and the owner only
+func (s *OrgService) UpdateOrganization(ctx context.Context, userID, orgID int32, dto UpdateOrgDto) (Organization, error) {
+	if err := ctx.Err(); err != nil {
+		return Organization{}, err
+	}
+	membership, err := s.authorize(ctx, userID, orgID, RoleAdmin)
+	if err != nil {
+		return Organization{}, err
+	}
+	org, err := s.repo.UpdateOrganization(ctx, orgID, dto.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Organization{}, ErrOrgNotFound
+	}
+	if err != nil {
+		return Organization{}, err
+	}
+	org.Role = membership.Role
+	return org, nil
+}
+
+// DeleteOrganization deletes an organization; the owner only
+func (s *OrgService) DeleteOrganization(ctx context.Context, userID, orgID int32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := s.authorize(ctx, userID, orgID, RoleOwner); err != nil {
+		return err
+	}
+	return s.repo.DeleteOrganization(ctx, orgID)
+}
+
+// ListMembers returns the members of an organization to one of its members
+func (s *OrgService) ListMembers(ctx context.Context, userID, orgID int32) ([]Member, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := s.authorize(ctx, userID, orgID, RoleMember); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, orgID)
+}
+
+// UpdateMemberRole switches a member between admin and member.
+// The owner role changes hands only through TransferOwnership.
+func (s *OrgService) UpdateMemberRole(ctx context.Context, userID, orgID, memberID int32, role string) (Membership, error) {
+	if err := ctx.Err(); err != nil {
+		return Membership{}, err
+	}
+	if _, err := s.authorize(ctx, userID, orgID, RoleAdmin); err != nil {
+		return Membership{}, err
+	}
+	member, err := s.repo.GetMembership(ctx, orgID, memberID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Membership{}, ErrMemberNotFound
+	}
+	if err != nil {
+		return Membership{}, err
+	}
+	if member.Role == RoleOwner {
+		return Membership{}, ErrCannotChangeOwnerRole
+	}
+	return s.repo.UpdateMembershipRole(ctx, orgID, memberID, role)
+}
+
+// RemoveMember removes a member from an organization. Admins can remove
+// others except the owner; any member can leave except the owner.
+func (s *OrgService) RemoveMember(ctx context.Context, userID, orgID, memberID int32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	minRole := RoleAdmin
+	if memberID == userID {
+		minRole = RoleMember
+	}
+	if _, err := s.authorize(ctx, userID, orgID, minRole); err != nil {
+		return err
+	}
+	member, err := s.repo.GetMembership(ctx, orgID, memberID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrMemberNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if member.Role == RoleOwner {
+		return ErrOwnerCannotLeave
+	}
+	_, err = s.repo.DeleteMembership(ctx, orgID, memberID)
+	return err
+}
+
+// TransferOwnership makes another member the owner; the previous owner
+// stays in the organization as an admin
+func (s *OrgService) TransferOwnership(ctx context.Context, userID, orgID, newOwnerID int32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := s.authorize(ctx, userID, orgID, RoleOwner); err != nil {
+		return err
+	}
+	if newOwnerID == userID {
+		return nil
+	}
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := s.repo.GetMembership(ctx, orgID, newOwnerID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrMemberNotFound
+			}
+			return err
+		}
+		// demote first: only one owner per organization is allowed
+		if _, err := s.repo.UpdateMembershipRole(ctx, orgID, userID, RoleAdmin); err != nil {
+			return err
+		}
+		_, err := s.repo.UpdateMembershipRole(ctx, orgID, newOwnerID, RoleOwner)
+		return err
+	})
+}
+
+// Invite sends an email invitation into an organization; admins and the owner only
+func (s *OrgService) Invite(ctx context.Context, userID, orgID int32, dto InviteDto) (Invitation, error) {
+	if err := ctx.Err(); err != nil {
+		return Invitation{}, err
+	}
+	if _, err := s.authorize(ctx, userID, orgID, RoleAdmin); err != nil {
+		return Invitation{}, err
+	}
+	org, err := s.repo.GetOrganization(ctx, orgID)
+	if err != nil {
+		return Invitation{}, err
+	}
+
+	token, tokenHash, err := newInvitationToken()
+	if err != nil {
+		return Invitation{}, err
+	}
+	email := strings.TrimSpace(dto.Email)
+	var invitation Invitation
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.ExpireInvitationsForEmail(ctx, orgID, email); err != nil {
+			return err
+		}
+		created, err := s.repo.CreateInvitation(ctx, db.CreateInvitationParams{
+			OrgID:     orgID,
+			Email:     email,
+			Role:      dto.Role,
+			TokenHash: tokenHash,
+			InvitedBy: sql.NullInt32{Int32: userID, Valid: true},
+			ExpiresAt: time.Now().Add(s.invitationTTL),
+		})
+		if db.IsUniqueViolation(err) {
+			return ErrAlreadyInvited
+		}
+		invitation = created
+		return err
+	})
+	if err != nil {
+		return Invitation{}, err
+	}
+
+	// the email goes out only once the invitation is committed, so a retried
+	// transaction never sends a token that does not exist
+	err = db.AfterCommit(ctx, func(ctx context.Context) error {
+		if err := s.mailer.Send(ctx, s.invitationMessage(org, invitation, token)); err != nil {
+			// without the email the token is lost, so the invitation is useless
+			if _, revokeErr := s.repo.RespondToInvitation(ctx, orgID, invitation.ID, InvitationRevoked); revokeErr != nil {
+				log.Printf("failed to revoke invitation %d: %v", invitation.ID, revokeErr)
+			}
+			return fmt.Errorf("%w: %v", ErrSendInvitation, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Invitation{}, err
+	}
+	return invitation, nil
+}
+
+// ListInvitations returns the pending invitations of an organization
+func (s *OrgService) ListInvitations(ctx context.Context, userID, orgID int32) ([]Invitation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := s.authorize(ctx, userID, orgID, RoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.repo.ListPendingInvitations(ctx, orgID)
+}
+
+// RevokeInvitation cancels a pending invitation
+func (s *OrgService) RevokeInvitation(ctx context.Context, userID, orgID, invitationID int32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := s.authorize(ctx, userID, orgID, RoleAdmin); err != nil {
+		return err
+	}
+	revoked, err := s.repo.RespondToInvitation(ctx, orgID, invitationID, InvitationRevoked)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation adds the user to the organization the token invites into.
+// The invitation must have been sent to the user's email.
+func (s *OrgService) AcceptInvitation(ctx context.Context, userID int32, token string) (Membership, error) {
+	if err := ctx.Err(); err != nil {
+		return Membership{}, err
+	}
+	invitation, err := s.pendingInvitation(ctx, userID, token)
+	if err != nil {
+		return Membership{}, err
+	}
+
+	var membership Membership
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		accepted, err := s.repo.RespondToInvitation(ctx, invitation.OrgID, invitation.ID, InvitationAccepted)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			return ErrInvitationNotFound
+		}
+		membership, err = s.repo.AddMembership(ctx, invitation.OrgID, userID, invitation.Role)
+		if db.IsUniqueViolation(err) {
+			return ErrAlreadyMember
+		}
+		return err
+	})
+	return membership, err
+}
+
+// DeclineInvitation rejects an invitation sent to the user's email
+func (s *OrgService) DeclineInvitation(ctx context.Context, userID int32, token string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	invitation, err := s.pendingInvitation(ctx, userID, token)
+	if err != nil {
+		return err
+	}
+	declined, err := s.repo.RespondToInvitation(ctx, invitation.OrgID, invitation.ID, InvitationDeclined)
+	if err != nil {
+		return err
+	}
+	if !declined {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// pendingInvitation finds the invitation of the token and checks that it
+// can still be answered by the user. Row-level security already hides the
+// invitations of other tenants; the tenant is compared as well, so that a
+// user of another tenant with the same email can never answer it.
+func (s *OrgService) pendingInvitation(ctx context.Context, userID int32, token string) (Invitation, error) {
+	invitation, err := s.repo.GetInvitationByTokenHash(ctx, hashInvitationToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Invitation{}, ErrInvitationNotFound
+	}
+	if err != nil {
+		return Invitation{}, err
+	}
+	if invitation.Status != InvitationPending {
+		return Invitation{}, ErrInvitationNotFound
+	}
+	if !invitation.ExpiresAt.After(time.Now()) {
+		return Invitation{}, ErrInvitationExpired
+	}
+
+	u, err := s.users.GetUser(ctx, userID)
+	if err != nil {
+		return Invitation{}, err
+	}
+	if u.TenantID != invitation.TenantID {
+		return Invitation{}, ErrInvitationNotFound
+	}
+	if !strings.EqualFold(u.Email, invitation.Email) {
+		return Invitation{}, ErrInvitationEmailMismatch
+	}
+	return invitation, nil
+}
+
+// authorize checks that the user is a member of the organization with at
+// least minRole. Non-members get ErrOrgNotFound so that the existence of
+// an organization is not disclosed.
+func (s *OrgService) authorize(ctx context.Context, userID, orgID int32, minRole string) (Membership, error) {
+	membership, err := s.repo.GetMembership(ctx, orgID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Membership{}, ErrOrgNotFound
+	}
+	if err != nil {
+		return Membership{}, err
+	}
+	if roleRank[membership.Role] < roleRank[minRole] {
+		return Membership{}, ErrForbidden
+	}
+	return membership, nil
+}
+
+func (s *OrgService) invitationMessage(org Organization, invitation Invitation, token string) mail.Message {
+	link := token
+	if s.inviteURL != "" {
+		link = s.inviteURL + "?token=" + token
+	}
+	return mail.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You are invited to join %s", org.Name),
+		Body: fmt.Sprintf(
+			"You have been invited to join %s as %s.\n\nAccept the invitation: %s\n\nThe invitation expires on %s.\n",
+			org.Name, invitation.Role, link, invitation.ExpiresAt.UTC().Format(time.RFC1123),
+		),
+	}
+}
+
+// newInvitationToken returns a random token to send by email and the hash
+// stored in the database
+func newInvitationToken() (string, string, error) {
+	buf := make([]byte, invitationTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(buf)
+	return token, hashInvitationToken(token), nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
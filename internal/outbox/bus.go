@@ -0,0 +1,56 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// AllEvents subscribes a handler to every event type
+const AllEvents = "*"
+
+// Handler consumes an event. Events are redelivered after a failure, also
+// to the handlers that succeeded, so handlers must be idempotent.
+type Handler func(ctx context.Context, event Event) error
+
+// Bus is the in-process sink: other modules subscribe to events with
+// handlers that run in the relay, after the change is committed
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers a handler for an event type, or AllEvents
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+func (b *Bus) Name() string {
+	return SinkBus
+}
+
+// Publish runs the handlers of the event one after another and fails if any
+// of them did
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler(nil), b.handlers[event.Type]...), b.handlers[AllEvents]...)
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := handler(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s handler: %w", event.Type, err))
+		}
+	}
+	return errors.Join(errs...)
+}
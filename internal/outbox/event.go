@@ -0,0 +1,52 @@
+// Package outbox publishes domain events reliably. Services record events
+// in the transaction that makes the change they describe, so an event exists
+// if and only if the change is committed, and the Relay publishes them to
+// the configured sinks afterwards.
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event statuses
+const (
+	StatusPending   = "pending"
+	StatusPublished = "published"
+	StatusDead      = "dead"
+)
+
+// Event is a domain event, such as user.created. Events of the same
+// aggregate are published in the order they were recorded. Delivery is at
+// least once, so consumers should skip IDs they have already handled.
+type Event struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	TenantID      int32           `json:"tenant_id,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// NewEvent describes a change of an aggregate with payload encoded as JSON.
+// The ID and the creation time are assigned when the event is recorded.
+func NewEvent(eventType, aggregateType, aggregateID string, payload any) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       data,
+	}, nil
+}
+
+// DeadEvent is an event the relay gave up on
+type DeadEvent struct {
+	Event
+	Attempts  int32  `json:"attempts"`
+	LastError string `json:"last_error"`
+}
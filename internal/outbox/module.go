@@ -0,0 +1,24 @@
+package outbox
+
+import (
+	"context"
+)
+
+type Module struct {
+	Outbox *Outbox
+	Bus    *Bus
+	Relay  *Relay
+}
+
+func NewModule(outbox *Outbox, bus *Bus, relay *Relay) *Module {
+	return &Module{
+		Outbox: outbox,
+		Bus:    bus,
+		Relay:  relay,
+	}
+}
+
+// StartJobs launches the relay
+func (m *Module) StartJobs(ctx context.Context) {
+	go m.Relay.Run(ctx)
+}
@@ -0,0 +1,105 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+)
+
+const defaultDeadEventsLimit = 100
+
+// ErrEventNotDead is returned when requeueing an event that is not dead
+var ErrEventNotDead = errors.New("outbox: event does not exist or is not dead")
+
+// Recorder records events in the transaction carried by the context. The
+// change an event describes has to be written before it in the same
+// transaction: the row lock taken by the change then orders the events of
+// concurrent transactions on the aggregate.
+type Recorder interface {
+	Record(ctx context.Context, events ...Event) error
+}
+
+// Outbox is the Recorder backed by the outbox table. It also manages the
+// events the relay gave up on.
+type Outbox struct {
+	repo *OutboxRepository
+	// wake tells the relay that events were committed
+	wake chan struct{}
+}
+
+var _ Recorder = (*Outbox)(nil)
+
+func NewOutbox(repo *OutboxRepository) *Outbox {
+	return &Outbox{repo: repo, wake: make(chan struct{}, 1)}
+}
+
+// Record writes the events in the transaction of ctx; outside a transaction
+// each event is committed on its own. The relay is woken up once the events
+// are committed.
+func (o *Outbox) Record(ctx context.Context, events ...Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, event := range events {
+		if err := o.repo.CreateEvent(ctx, event); err != nil {
+			return err
+		}
+	}
+	return db.AfterCommit(ctx, func(context.Context) error {
+		o.notify()
+		return nil
+	})
+}
+
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// ListDeadEvents returns the oldest dead events, up to limit or 100
+func (o *Outbox) ListDeadEvents(ctx context.Context, limit int32) ([]DeadEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultDeadEventsLimit
+	}
+	return o.repo.ListDeadEvents(ctx, limit)
+}
+
+// Requeue queues a dead event for publishing again. It takes back its place
+// in the order of its aggregate, ahead of newer pending events, but events of
+// the aggregate published while it was dead have already gone out.
+func (o *Outbox) Requeue(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	requeued, err := o.repo.RequeueDeadEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if requeued == 0 {
+		return ErrEventNotDead
+	}
+	o.notify()
+	return nil
+}
+
+// RequeueAll queues every dead event for publishing again and returns how
+// many there were
+func (o *Outbox) RequeueAll(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	requeued, err := o.repo.RequeueDeadEvents(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if requeued > 0 {
+		o.notify()
+	}
+	return requeued, nil
+}
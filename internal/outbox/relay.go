@@ -0,0 +1,221 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultRelayInterval   = 5 * time.Second
+	defaultRelayBatchSize  = 100
+	defaultRelayConcurrent = 8
+	defaultRelayLease      = time.Minute
+	defaultMaxAttempts     = 10
+	defaultRetryBaseDelay  = time.Second
+	defaultRetryMaxDelay   = 10 * time.Minute
+	defaultRetention       = 7 * 24 * time.Hour
+	// cleanupInterval spaces out the removal of old published events
+	cleanupInterval = time.Hour
+)
+
+// relayMetrics are published with expvar under "outbox"
+var relayMetrics = struct {
+	published    *expvar.Int
+	retried      *expvar.Int
+	deadLettered *expvar.Int
+}{
+	published:    new(expvar.Int),
+	retried:      new(expvar.Int),
+	deadLettered: new(expvar.Int),
+}
+
+func init() {
+	metrics := expvar.NewMap("outbox")
+	metrics.Set("published", relayMetrics.published)
+	metrics.Set("retried", relayMetrics.retried)
+	metrics.Set("dead_lettered", relayMetrics.deadLettered)
+}
+
+// Relay publishes the recorded events to every sink. An event is marked as
+// published only after all sinks accepted it, so a crash in between
+// publishes it again: delivery is at least once. The events of an aggregate
+// are published one at a time in order; a failed event is retried with an
+// exponential backoff and holds back the newer events of its aggregate
+// until it is published or dead-lettered after the last attempt. Several
+// relays, in other instances of the application, may run at once.
+type Relay struct {
+	outbox         *Outbox
+	sinks          []Sink
+	interval       time.Duration
+	batchSize      int
+	concurrency    int
+	lease          time.Duration
+	maxAttempts    int32
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+	retention      time.Duration
+	lastCleanup    time.Time
+}
+
+// NewRelay reads the OUTBOX_* settings:
+//   - OUTBOX_RELAY_INTERVAL, how often the outbox is polled for events of
+//     other instances and retries; events committed by this instance are
+//     published right away
+//   - OUTBOX_BATCH_SIZE, how many events are claimed at once, and
+//     OUTBOX_CONCURRENCY, how many of them are published in parallel
+//   - OUTBOX_LEASE, how long a relay may take to publish a claimed event
+//     before another relay claims it again
+//   - OUTBOX_MAX_ATTEMPTS, after which an event is dead-lettered, and
+//     OUTBOX_RETRY_BASE_DELAY and OUTBOX_RETRY_MAX_DELAY, the backoff bounds
+//   - OUTBOX_RETENTION, how long published events are kept
+func NewRelay(outbox *Outbox, sinks []Sink) *Relay {
+	interval := viper.GetDuration("OUTBOX_RELAY_INTERVAL")
+	if interval <= 0 {
+		interval = defaultRelayInterval
+	}
+	batchSize := viper.GetInt("OUTBOX_BATCH_SIZE")
+	if batchSize <= 0 {
+		batchSize = defaultRelayBatchSize
+	}
+	concurrency := viper.GetInt("OUTBOX_CONCURRENCY")
+	if concurrency <= 0 {
+		concurrency = defaultRelayConcurrent
+	}
+	lease := viper.GetDuration("OUTBOX_LEASE")
+	if lease <= 0 {
+		lease = defaultRelayLease
+	}
+	maxAttempts := viper.GetInt32("OUTBOX_MAX_ATTEMPTS")
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	baseDelay := viper.GetDuration("OUTBOX_RETRY_BASE_DELAY")
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+	maxDelay := viper.GetDuration("OUTBOX_RETRY_MAX_DELAY")
+	if maxDelay < baseDelay {
+		maxDelay = max(defaultRetryMaxDelay, baseDelay)
+	}
+	retention := viper.GetDuration("OUTBOX_RETENTION")
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+	return &Relay{
+		outbox:         outbox,
+		sinks:          sinks,
+		interval:       interval,
+		batchSize:      batchSize,
+		concurrency:    concurrency,
+		lease:          lease,
+		maxAttempts:    maxAttempts,
+		retryBaseDelay: baseDelay,
+		retryMaxDelay:  maxDelay,
+		retention:      retention,
+	}
+}
+
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		r.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.outbox.wake:
+		}
+	}
+}
+
+func (r *Relay) runOnce(ctx context.Context) {
+	for {
+		claimed, err := r.PublishBatch(ctx)
+		if err != nil {
+			log.Printf("Failed to relay outbox events: %v", err)
+		}
+		if claimed == 0 || err != nil || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if time.Since(r.lastCleanup) < cleanupInterval {
+		return
+	}
+	r.lastCleanup = time.Now()
+	deleted, err := r.outbox.repo.DeletePublishedEvents(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		log.Printf("Failed to delete published outbox events: %v", err)
+	} else if deleted > 0 {
+		log.Printf("Deleted %d published outbox events", deleted)
+	}
+}
+
+// PublishBatch claims the events that are due and publishes them, and
+// returns how many it claimed. ctx must see the events of all tenants.
+func (r *Relay) PublishBatch(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	events, err := r.outbox.repo.ClaimEvents(ctx, r.batchSize, r.lease)
+	if err != nil {
+		return 0, err
+	}
+
+	// a batch holds at most one event per aggregate, so they can be
+	// published in any order
+	var group errgroup.Group
+	group.SetLimit(r.concurrency)
+	for _, event := range events {
+		group.Go(func() error {
+			return r.publish(ctx, event)
+		})
+	}
+	return len(events), group.Wait()
+}
+
+// publish hands the event to every sink and records the outcome. Sinks get
+// the event even after another one failed, as it is redelivered to all of
+// them anyway.
+func (r *Relay) publish(ctx context.Context, event claimedEvent) error {
+	// the lease ends the attempt, as another relay may claim the event then
+	publishCtx, cancel := context.WithTimeout(ctx, r.lease)
+	var errs []error
+	for _, sink := range r.sinks {
+		if err := sink.Publish(publishCtx, event.Event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+		}
+	}
+	cancel()
+
+	publishErr := errors.Join(errs...)
+	if publishErr == nil {
+		relayMetrics.published.Add(1)
+		return r.outbox.repo.MarkPublished(ctx, event.ID)
+	}
+	if event.attempts >= r.maxAttempts {
+		relayMetrics.deadLettered.Add(1)
+		log.Printf("Dead-lettered outbox event %d (%s) after %d attempts: %v", event.ID, event.Type, event.attempts, publishErr)
+		return r.outbox.repo.DeadLetter(ctx, event.ID, publishErr.Error())
+	}
+	relayMetrics.retried.Add(1)
+	return r.outbox.repo.Retry(ctx, event.ID, r.retryDelay(event.attempts), publishErr.Error())
+}
+
+// retryDelay doubles the delay with every failed attempt
+func (r *Relay) retryDelay(attempt int32) time.Duration {
+	if shift := attempt - 1; shift < 32 {
+		return min(r.retryBaseDelay<<shift, r.retryMaxDelay)
+	}
+	return r.retryMaxDelay
+}
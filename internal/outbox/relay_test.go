@@ -0,0 +1,142 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/outbox"
+	"github.com/malytinKonstantin/go-fiber/internal/testutil"
+	"github.com/spf13/viper"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// recordingSink records the events it accepts and fails the events whose
+// type is in failing
+type recordingSink struct {
+	mu        sync.Mutex
+	failing   map[string]bool
+	published []string
+}
+
+func (s *recordingSink) Name() string {
+	return "recording"
+}
+
+func (s *recordingSink) Publish(ctx context.Context, event outbox.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing[event.Type] {
+		return errors.New("unavailable")
+	}
+	s.published = append(s.published, event.Type)
+	return nil
+}
+
+func (s *recordingSink) take() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	published := s.published
+	s.published = nil
+	return published
+}
+
+func newTestOutbox(t *testing.T) *outbox.Outbox {
+	t.Helper()
+	ctx := db.WithAllTenants(context.Background())
+	pool, err := db.NewPostgresPool(testutil.DatabaseURL(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	if _, err := pool.Exec(ctx, `TRUNCATE outbox RESTART IDENTITY`); err != nil {
+		t.Fatal(err)
+	}
+	database, err := db.NewDB(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return outbox.NewOutbox(outbox.NewOutboxRepository(database))
+}
+
+// TestRelay checks that a failing event holds back the newer events of its
+// aggregate until it is dead-lettered, and that a requeued event is
+// published
+func TestRelay(t *testing.T) {
+	ctx := db.WithAllTenants(context.Background())
+	box := newTestOutbox(t)
+	viper.Set("OUTBOX_MAX_ATTEMPTS", 2)
+	viper.Set("OUTBOX_RETRY_BASE_DELAY", time.Millisecond)
+	t.Cleanup(func() {
+		viper.Set("OUTBOX_MAX_ATTEMPTS", nil)
+		viper.Set("OUTBOX_RETRY_BASE_DELAY", nil)
+	})
+	sink := &recordingSink{failing: map[string]bool{"test.failing": true}}
+	relay := outbox.NewRelay(box, []outbox.Sink{sink})
+
+	for _, spec := range [][2]string{{"test.failing", "a"}, {"test.after-failing", "a"}, {"test.other", "b"}} {
+		event, err := outbox.NewEvent(spec[0], "test", spec[1], map[string]string{"aggregate": spec[1]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := box.Record(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	publishBatch := func(wantClaimed int) {
+		t.Helper()
+		// lets the backoff of the retried events pass
+		time.Sleep(20 * time.Millisecond)
+		claimed, err := relay.PublishBatch(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if claimed != wantClaimed {
+			t.Fatalf("claimed %d events, want %d", claimed, wantClaimed)
+		}
+	}
+
+	// the heads of both aggregates
+	publishBatch(2)
+	if published := sink.take(); !slices.Equal(published, []string{"test.other"}) {
+		t.Fatalf("published %v", published)
+	}
+	// the second attempt dead-letters the failing event
+	publishBatch(1)
+	publishBatch(1)
+	if published := sink.take(); !slices.Equal(published, []string{"test.after-failing"}) {
+		t.Fatalf("published %v", published)
+	}
+	publishBatch(0)
+
+	dead, err := box.ListDeadEvents(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].Type != "test.failing" || dead[0].Attempts != 2 || !strings.Contains(dead[0].LastError, "unavailable") {
+		t.Fatalf("dead events = %+v", dead)
+	}
+
+	sink.mu.Lock()
+	sink.failing = nil
+	sink.mu.Unlock()
+	if err := box.Requeue(ctx, dead[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	publishBatch(1)
+	if published := sink.take(); !slices.Equal(published, []string{"test.failing"}) {
+		t.Fatalf("published %v", published)
+	}
+	if err := box.Requeue(ctx, dead[0].ID); !errors.Is(err, outbox.ErrEventNotDead) {
+		t.Fatalf("Requeue of a published event = %v, want ErrEventNotDead", err)
+	}
+}
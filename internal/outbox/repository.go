@@ -0,0 +1,116 @@
+package outbox
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+)
+
+// claimedEvent is an event leased by the relay for one publishing attempt
+type claimedEvent struct {
+	Event
+	attempts int32
+}
+
+type OutboxRepository struct {
+	q *db.Queries
+}
+
+func NewOutboxRepository(database *db.DB) *OutboxRepository {
+	return &OutboxRepository{q: database.Queries()}
+}
+
+// CreateEvent writes the event in the transaction of ctx. Without a tenant
+// ID the event belongs to the tenant ctx is scoped to.
+func (r *OutboxRepository) CreateEvent(ctx context.Context, event Event) error {
+	return r.q.CreateOutboxEvent(ctx, db.CreateOutboxEventParams{
+		TenantID:      sql.NullInt32{Int32: event.TenantID, Valid: event.TenantID != 0},
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		EventType:     event.Type,
+		Payload:       event.Payload,
+	})
+}
+
+// ClaimEvents leases up to limit events, in the order they were recorded
+func (r *OutboxRepository) ClaimEvents(ctx context.Context, limit int, lease time.Duration) ([]claimedEvent, error) {
+	dbEvents, err := r.q.ClaimOutboxEvents(ctx, db.ClaimOutboxEventsParams{
+		LeaseSeconds: lease.Seconds(),
+		BatchSize:    int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	events := make([]claimedEvent, len(dbEvents))
+	for i, dbEvent := range dbEvents {
+		events[i] = claimedEvent{Event: convertDbEvent(dbEvent), attempts: dbEvent.Attempts}
+	}
+	// UPDATE ... RETURNING does not keep the order of the subquery
+	slices.SortFunc(events, func(a, b claimedEvent) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return events, nil
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id int64) error {
+	return r.q.MarkOutboxEventPublished(ctx, id)
+}
+
+func (r *OutboxRepository) Retry(ctx context.Context, id int64, delay time.Duration, reason string) error {
+	return r.q.RetryOutboxEvent(ctx, db.RetryOutboxEventParams{
+		ID:           id,
+		DelaySeconds: delay.Seconds(),
+		LastError:    sql.NullString{String: reason, Valid: true},
+	})
+}
+
+func (r *OutboxRepository) DeadLetter(ctx context.Context, id int64, reason string) error {
+	return r.q.DeadLetterOutboxEvent(ctx, db.DeadLetterOutboxEventParams{
+		ID:        id,
+		LastError: sql.NullString{String: reason, Valid: true},
+	})
+}
+
+func (r *OutboxRepository) ListDeadEvents(ctx context.Context, limit int32) ([]DeadEvent, error) {
+	dbEvents, err := r.q.ListDeadOutboxEvents(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]DeadEvent, len(dbEvents))
+	for i, dbEvent := range dbEvents {
+		events[i] = DeadEvent{
+			Event:     convertDbEvent(dbEvent),
+			Attempts:  dbEvent.Attempts,
+			LastError: dbEvent.LastError.String,
+		}
+	}
+	return events, nil
+}
+
+func (r *OutboxRepository) RequeueDeadEvent(ctx context.Context, id int64) (int64, error) {
+	return r.q.RequeueDeadOutboxEvent(ctx, id)
+}
+
+func (r *OutboxRepository) RequeueDeadEvents(ctx context.Context) (int64, error) {
+	return r.q.RequeueDeadOutboxEvents(ctx)
+}
+
+func (r *OutboxRepository) DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	return r.q.DeletePublishedOutboxEvents(ctx, sql.NullTime{Time: before, Valid: true})
+}
+
+func convertDbEvent(dbEvent db.Outbox) Event {
+	return Event{
+		ID:            dbEvent.ID,
+		Type:          dbEvent.EventType,
+		AggregateType: dbEvent.AggregateType,
+		AggregateID:   dbEvent.AggregateID,
+		TenantID:      dbEvent.TenantID.Int32,
+		Payload:       dbEvent.Payload,
+		CreatedAt:     dbEvent.CreatedAt,
+	}
+}
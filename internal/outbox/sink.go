@@ -0,0 +1,63 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Sinks selectable with OUTBOX_SINKS
+const (
+	SinkBus     = "bus"
+	SinkWebhook = "webhook"
+	SinkNATS    = "nats"
+)
+
+// Sink delivers events to their consumers. Publish returns once the event
+// is handed over; an error makes the relay publish it again later, to every
+// sink, so sinks may see an event more than once.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event Event) error
+}
+
+// NewSinks creates the sinks listed in OUTBOX_SINKS, separated by commas:
+// bus, the in-process Bus (the default), webhook and nats
+func NewSinks(bus *Bus) ([]Sink, error) {
+	names := viper.GetString("OUTBOX_SINKS")
+	if strings.TrimSpace(names) == "" {
+		names = SinkBus
+	}
+
+	var sinks []Sink
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		switch name {
+		case SinkBus:
+			sinks = append(sinks, bus)
+		case SinkWebhook:
+			sink, err := NewWebhookSinkFromConfig()
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		case SinkNATS:
+			sink, err := NewNATSSinkFromConfig()
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+	return sinks, nil
+}
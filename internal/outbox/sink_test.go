@@ -0,0 +1,306 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func testEvent(t *testing.T) Event {
+	t.Helper()
+	event, err := NewEvent("user.created", "user", "7", map[string]any{"id": 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.ID = 42
+	event.TenantID = 1
+	return event
+}
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	bus.Subscribe("user.created", func(ctx context.Context, event Event) error {
+		got = append(got, "created")
+		return nil
+	})
+	bus.Subscribe("user.deleted", func(ctx context.Context, event Event) error {
+		got = append(got, "deleted")
+		return nil
+	})
+	bus.Subscribe(AllEvents, func(ctx context.Context, event Event) error {
+		got = append(got, "all")
+		return errors.New("boom")
+	})
+
+	err := bus.Publish(context.Background(), testEvent(t))
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("Publish = %v, want the handler error", err)
+	}
+	if strings.Join(got, ",") != "created,all" {
+		t.Fatalf("handlers ran: %v", got)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	secret := "s3cret"
+	var received Event
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(HeaderSignature) != "sha256="+Sign([]byte(secret), body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(HeaderEventID) != "42" || r.Header.Get(HeaderEventType) != "user.created" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	event := testEvent(t)
+	if err := NewWebhookSink([]string{ok.URL}, secret, time.Second).Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if received.ID != 42 || received.AggregateID != "7" || string(received.Payload) != `{"id":7}` {
+		t.Fatalf("received %+v", received)
+	}
+
+	err := NewWebhookSink([]string{failing.URL, ok.URL}, secret, time.Second).Publish(context.Background(), event)
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("Publish = %v, want the status of the failing webhook", err)
+	}
+}
+
+// fakeNATS speaks enough of the NATS client protocol to acknowledge
+// JetStream publishes like a stream bound to every subject, and reports the
+// messages it acknowledges. Messages on subjects starting with full are
+// rejected by the stream, and those starting with missing have no stream.
+// Connections with wrong credentials are refused.
+type fakeNATS struct {
+	listener net.Listener
+	messages chan string
+}
+
+func newFakeNATS(t *testing.T) *fakeNATS {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeNATS{listener: listener, messages: make(chan string, 10)}
+	t.Cleanup(func() { listener.Close() })
+	go server.serve()
+	return server
+}
+
+func (s *fakeNATS) url(userinfo string) string {
+	return "nats://" + userinfo + s.listener.Addr().String()
+}
+
+func (s *fakeNATS) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeNATS) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	fmt.Fprint(conn, "INFO {\"server_id\":\"fake\",\"version\":\"2.10.0\",\"proto\":1,\"headers\":true,\"jetstream\":true,\"max_payload\":1048576}\r\n")
+	// sid of the subscription that receives the replies
+	var inboxSID string
+	var seq int
+	seen := map[string]bool{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "CONNECT":
+			var connect struct {
+				User string `json:"user"`
+				Pass string `json:"pass"`
+			}
+			json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "CONNECT ")), &connect)
+			if connect.User != "app" || connect.Pass != "secret" {
+				fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		case "SUB":
+			inboxSID = fields[len(fields)-1]
+		case "HPUB":
+			if len(fields) != 5 {
+				fmt.Fprint(conn, "-ERR 'Expected a reply subject'\r\n")
+				return
+			}
+			subject, reply := fields[1], fields[2]
+			headerLen, _ := strconv.Atoi(fields[3])
+			total, _ := strconv.Atoi(fields[4])
+			data := make([]byte, total+2)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return
+			}
+			header, body := string(data[:headerLen]), string(data[headerLen:total])
+
+			switch {
+			case strings.HasPrefix(subject, "missing."):
+				noResponders := "NATS/1.0 503\r\n\r\n"
+				fmt.Fprintf(conn, "HMSG %s %s %d %d\r\n%s\r\n", reply, inboxSID, len(noResponders), len(noResponders), noResponders)
+				continue
+			case strings.HasPrefix(subject, "full."):
+				ack := `{"error":{"code":503,"err_code":10077,"description":"maximum messages exceeded"}}`
+				fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", reply, inboxSID, len(ack), ack)
+				continue
+			}
+			var msgID string
+			for _, headerLine := range strings.Split(header, "\r\n") {
+				if value, ok := strings.CutPrefix(headerLine, "Nats-Msg-Id: "); ok {
+					msgID = value
+				}
+			}
+			duplicate := seen[msgID]
+			if !duplicate {
+				seen[msgID] = true
+				seq++
+				s.messages <- subject + " " + msgID + " " + body
+			}
+			ack := fmt.Sprintf(`{"stream":"EVENTS","seq":%d,"duplicate":%t}`, seq, duplicate)
+			fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", reply, inboxSID, len(ack), ack)
+		}
+	}
+}
+
+func TestNATSSink(t *testing.T) {
+	server := newFakeNATS(t)
+	event := testEvent(t)
+
+	sink, err := NewNATSSink(server.url("app:secret@"), "events", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	message := <-server.messages
+	subject, rest, _ := strings.Cut(message, " ")
+	msgID, body, _ := strings.Cut(rest, " ")
+	if subject != "events.user.created" || msgID != "42" {
+		t.Fatalf("published on %s with the message ID %q", subject, msgID)
+	}
+	var published Event
+	if err := json.Unmarshal([]byte(body), &published); err != nil || published.ID != 42 {
+		t.Fatalf("body = %q, %v", body, err)
+	}
+	// a redelivered event is acknowledged as a duplicate, which is success
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish of a duplicate = %v", err)
+	}
+	select {
+	case message := <-server.messages:
+		t.Fatalf("duplicate stored: %s", message)
+	default:
+	}
+
+	full, _ := NewNATSSink(server.url("app:secret@"), "full", time.Second)
+	if err := full.Publish(context.Background(), event); err == nil || !strings.Contains(err.Error(), "maximum messages exceeded") {
+		t.Fatalf("Publish = %v, want the error of the stream", err)
+	}
+	missing, _ := NewNATSSink(server.url("app:secret@"), "missing", time.Second)
+	if err := missing.Publish(context.Background(), event); !errors.Is(err, jetstream.ErrNoStreamResponse) {
+		t.Fatalf("Publish without a stream = %v, want ErrNoStreamResponse", err)
+	}
+	unauthorized, _ := NewNATSSink(server.url("app:wrong@"), "events", time.Second)
+	for range 2 {
+		if err := unauthorized.Publish(context.Background(), event); !errors.Is(err, nats.ErrAuthorization) {
+			t.Fatalf("Publish = %v, want the authorization error", err)
+		}
+	}
+	if _, err := NewNATSSink("http://localhost:4222", "events", time.Second); err == nil {
+		t.Fatal("NewNATSSink accepted an http URL")
+	}
+}
+
+// TestNATSSinkAgainstServer publishes to the JetStream server of
+// TEST_NATS_URL, such as a local nats-server -js, through a stream it
+// creates for the test
+func TestNATSSinkAgainstServer(t *testing.T) {
+	serverURL := os.Getenv("TEST_NATS_URL")
+	if serverURL == "" {
+		t.Skip("TEST_NATS_URL is not set")
+	}
+	ctx := context.Background()
+	conn, err := nats.Connect(serverURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	js, err := jetstream.New(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     "OUTBOX_TEST",
+		Subjects: []string{"outbox-test.>"},
+		Storage:  jetstream.MemoryStorage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { js.DeleteStream(ctx, "OUTBOX_TEST") })
+
+	sink, err := NewNATSSink(serverURL, "outbox-test", 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := testEvent(t)
+	for range 2 {
+		if err := sink.Publish(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 1 {
+		t.Fatalf("the stream holds %d messages, want 1 without the duplicate", info.State.Msgs)
+	}
+	msg, err := stream.GetLastMsgForSubject(ctx, "outbox-test.user.created")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get(jetstream.MsgIDHeader) != "42" {
+		t.Fatalf("message ID = %q", msg.Header.Get(jetstream.MsgIDHeader))
+	}
+}
@@ -0,0 +1,114 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+// Headers of webhook requests
+const (
+	HeaderEventID   = "X-Event-ID"
+	HeaderEventType = "X-Event-Type"
+	HeaderSignature = "X-Signature"
+)
+
+// WebhookSink posts every event as JSON to a list of URLs. With a secret,
+// requests carry X-Signature: sha256=<hex HMAC-SHA256 of the body>. A
+// response other than 2xx fails the delivery.
+type WebhookSink struct {
+	urls   []string
+	secret []byte
+	client *http.Client
+}
+
+func NewWebhookSink(urls []string, secret string, timeout time.Duration) *WebhookSink {
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	return &WebhookSink{
+		urls:   urls,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// NewWebhookSinkFromConfig reads OUTBOX_WEBHOOK_URLS, separated by commas,
+// OUTBOX_WEBHOOK_SECRET and OUTBOX_WEBHOOK_TIMEOUT
+func NewWebhookSinkFromConfig() (*WebhookSink, error) {
+	var urls []string
+	for _, url := range strings.Split(viper.GetString("OUTBOX_WEBHOOK_URLS"), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, errors.New("OUTBOX_WEBHOOK_URLS is required by the webhook sink")
+	}
+	return NewWebhookSink(urls, viper.GetString("OUTBOX_WEBHOOK_SECRET"), viper.GetDuration("OUTBOX_WEBHOOK_TIMEOUT")), nil
+}
+
+func (s *WebhookSink) Name() string {
+	return SinkWebhook
+}
+
+// Publish posts the event to every URL, also after one of them failed
+func (s *WebhookSink) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, url := range s.urls {
+		if err := s.post(ctx, url, event, body); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *WebhookSink) post(ctx context.Context, url string, event Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, strconv.FormatInt(event.ID, 10))
+	req.Header.Set(HeaderEventType, event.Type)
+	if len(s.secret) > 0 {
+		req.Header.Set(HeaderSignature, "sha256="+Sign(s.secret, body))
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook %s responded with status %d", url, res.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of body, which receivers compare with
+// the X-Signature header
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
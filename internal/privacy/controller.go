@@ -0,0 +1,178 @@
+package privacy
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/malytinKonstantin/go-fiber/internal/middleware"
+)
+
+const (
+	errUnauthorized          = "unauthorized"
+	errExportNotFound        = "no data export requested"
+	errErasureNotFound       = "no pending erasure request"
+	errFailedToRequestExport = "failed to request data export"
+	errFailedToRequestErase  = "failed to request erasure"
+	exportDownloadName       = "personal-data.zip"
+)
+
+type PrivacyController struct {
+	service *PrivacyService
+}
+
+func NewPrivacyController(service *PrivacyService) *PrivacyController {
+	return &PrivacyController{service: service}
+}
+
+func sendErrorResponse(ctx *fiber.Ctx, status int, message string) error {
+	return ctx.Status(status).JSON(fiber.Map{"error": message})
+}
+
+// SetupRoutes sets up the privacy-related routes
+func (c *PrivacyController) SetupRoutes(router fiber.Router) {
+	router.Post("/me/export", c.RequestExport)
+	router.Get("/me/export", c.GetExport)
+	router.Get("/me/export/download", c.DownloadExport)
+	router.Post("/me/erasure", c.RequestErasure)
+	router.Get("/me/erasure", c.GetErasure)
+	router.Delete("/me/erasure", c.CancelErasure)
+}
+
+// RequestExport queues an export of all personal data of the current user
+// @Summary Request a personal data export
+// @Tags privacy
+// @Success 202 {object} DataExport
+// @Failure 401,500 {object} ErrorResponse
+// @Router /api/v1/me/export [post]
+func (c *PrivacyController) RequestExport(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+
+	export, err := c.service.RequestExport(ctx.Context(), userID)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, errFailedToRequestExport)
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(export)
+}
+
+// GetExport returns the status of the latest data export
+// @Summary Get data export status
+// @Tags privacy
+// @Success 200 {object} DataExport
+// @Failure 401,404,500 {object} ErrorResponse
+// @Router /api/v1/me/export [get]
+func (c *PrivacyController) GetExport(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+
+	export, err := c.service.GetLatestExport(ctx.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sendErrorResponse(ctx, fiber.StatusNotFound, errExportNotFound)
+	}
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.JSON(export)
+}
+
+// DownloadExport sends the archive of the latest ready data export
+// @Summary Download data export
+// @Tags privacy
+// @Produce application/zip
+// @Success 200 {file} file
+// @Failure 401,404,409,410,500 {object} ErrorResponse
+// @Router /api/v1/me/export/download [get]
+func (c *PrivacyController) DownloadExport(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+
+	filePath, err := c.service.ExportFile(ctx.Context(), userID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return sendErrorResponse(ctx, fiber.StatusNotFound, errExportNotFound)
+	case errors.Is(err, ErrExportNotReady):
+		return sendErrorResponse(ctx, fiber.StatusConflict, err.Error())
+	case errors.Is(err, ErrExportExpired):
+		return sendErrorResponse(ctx, fiber.StatusGone, err.Error())
+	case err != nil:
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.Download(filePath, exportDownloadName)
+}
+
+// RequestErasure schedules erasure of all personal data of the current user
+// @Summary Request erasure of personal data
+// @Description Data is anonymized after a cooling-off period during which the request can be cancelled
+// @Tags privacy
+// @Success 202 {object} ErasureRequest
+// @Failure 401,500 {object} ErrorResponse
+// @Router /api/v1/me/erasure [post]
+func (c *PrivacyController) RequestErasure(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+
+	request, err := c.service.RequestErasure(ctx.Context(), userID)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, errFailedToRequestErase)
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(request)
+}
+
+// GetErasure returns the pending erasure request
+// @Summary Get pending erasure request
+// @Tags privacy
+// @Success 200 {object} ErasureRequest
+// @Failure 401,404,500 {object} ErrorResponse
+// @Router /api/v1/me/erasure [get]
+func (c *PrivacyController) GetErasure(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+
+	request, err := c.service.GetPendingErasure(ctx.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sendErrorResponse(ctx, fiber.StatusNotFound, errErasureNotFound)
+	}
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.JSON(request)
+}
+
+// CancelErasure cancels the pending erasure request during the cooling-off period
+// @Summary Cancel erasure request
+// @Tags privacy
+// @Success 200 {object} ErasureRequest
+// @Failure 401,404,500 {object} ErrorResponse
+// @Router /api/v1/me/erasure [delete]
+func (c *PrivacyController) CancelErasure(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+
+	request, err := c.service.CancelErasure(ctx.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sendErrorResponse(ctx, fiber.StatusNotFound, errErasureNotFound)
+	}
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.JSON(request)
+}
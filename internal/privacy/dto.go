@@ -0,0 +1,9 @@
+package privacy
+
+// ErrorResponse represents the structure of an error response
+// swagger:model
+type ErrorResponse struct {
+	// Error message
+	// example: no data export requested
+	Error string `json:"error"`
+}
@@ -0,0 +1,28 @@
+package privacy
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Module struct {
+	Controller *PrivacyController
+	Worker     *PrivacyWorker
+}
+
+func NewModule(controller *PrivacyController, worker *PrivacyWorker) *Module {
+	return &Module{
+		Controller: controller,
+		Worker:     worker,
+	}
+}
+
+func (m *Module) SetupRoutes(router fiber.Router) {
+	m.Controller.SetupRoutes(router)
+}
+
+// StartJobs launches the module's background jobs
+func (m *Module) StartJobs(ctx context.Context) {
+	go m.Worker.Run(ctx)
+}
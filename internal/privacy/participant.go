@@ -0,0 +1,96 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Exporter adds the data a module stores about a user to an export archive
+type Exporter interface {
+	Export(ctx context.Context, userID int32, w *ArchiveWriter) error
+}
+
+// Eraser anonymizes or deletes the data a module stores about a user.
+// Failed erasures are retried, so implementations must be idempotent.
+type Eraser interface {
+	Erase(ctx context.Context, userID int32) error
+}
+
+type namedExporter struct {
+	name     string
+	exporter Exporter
+}
+
+type namedEraser struct {
+	name   string
+	eraser Eraser
+}
+
+// Registry collects the exporters and erasers of all modules.
+// They are run in registration order.
+type Registry struct {
+	mu        sync.RWMutex
+	exporters []namedExporter
+	erasers   []namedEraser
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// RegisterExporter adds an exporter whose files go into the name/ folder of the archive
+func (r *Registry) RegisterExporter(name string, exporter Exporter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.exporters = append(r.exporters, namedExporter{name: name, exporter: exporter})
+}
+
+func (r *Registry) RegisterEraser(name string, eraser Eraser) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.erasers = append(r.erasers, namedEraser{name: name, eraser: eraser})
+}
+
+func (r *Registry) exportersSnapshot() []namedExporter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]namedExporter(nil), r.exporters...)
+}
+
+func (r *Registry) erasersSnapshot() []namedEraser {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]namedEraser(nil), r.erasers...)
+}
+
+// ArchiveWriter writes one participant's folder of a data export archive:
+// JSON documents plus attachments such as user/avatar.jpg. The manifest.json
+// at the root lists the folders.
+type ArchiveWriter struct {
+	zw     *zip.Writer
+	prefix string
+}
+
+// WriteJSON stores v as an indented JSON document
+func (w *ArchiveWriter) WriteJSON(name string, v any) error {
+	f, err := w.zw.Create(w.prefix + name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// WriteFile stores an attachment such as an uploaded image
+func (w *ArchiveWriter) WriteFile(name string, r io.Reader) error {
+	f, err := w.zw.Create(w.prefix + name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	return err
+}
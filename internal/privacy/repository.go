@@ -0,0 +1,184 @@
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+)
+
+// Export statuses
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusReady      = "ready"
+	StatusFailed     = "failed"
+	StatusExpired    = "expired"
+)
+
+// Erasure statuses
+const (
+	ErasurePending   = "pending"
+	ErasureCancelled = "cancelled"
+	ErasureCompleted = "completed"
+)
+
+type DataExport struct {
+	ID          int32      `json:"id"`
+	UserID      int32      `json:"user_id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	filePath    string
+}
+
+type ErasureRequest struct {
+	ID           int32      `json:"id"`
+	UserID       int32      `json:"user_id"`
+	Status       string     `json:"status"`
+	RequestedAt  time.Time  `json:"requested_at"`
+	ScheduledFor time.Time  `json:"scheduled_for"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+}
+
+type PrivacyRepository struct {
+	q *db.Queries
+}
+
+func NewPrivacyRepository(database *db.DB) *PrivacyRepository {
+	return &PrivacyRepository{q: database.Queries()}
+}
+
+func (r *PrivacyRepository) CreateDataExport(ctx context.Context, userID int32) (DataExport, error) {
+	dbExport, err := r.q.CreateDataExport(ctx, userID)
+	if err != nil {
+		return DataExport{}, err
+	}
+	return convertDbDataExport(dbExport), nil
+}
+
+func (r *PrivacyRepository) GetLatestDataExport(ctx context.Context, userID int32) (DataExport, error) {
+	dbExport, err := r.q.GetLatestDataExport(ctx, userID)
+	if err != nil {
+		return DataExport{}, err
+	}
+	return convertDbDataExport(dbExport), nil
+}
+
+func (r *PrivacyRepository) ClaimDataExport(ctx context.Context) (DataExport, error) {
+	dbExport, err := r.q.ClaimDataExport(ctx)
+	if err != nil {
+		return DataExport{}, err
+	}
+	return convertDbDataExport(dbExport), nil
+}
+
+func (r *PrivacyRepository) CompleteDataExport(ctx context.Context, id int32, filePath string, expiresAt time.Time) error {
+	return r.q.CompleteDataExport(ctx, db.CompleteDataExportParams{
+		ID:        id,
+		FilePath:  sql.NullString{String: filePath, Valid: true},
+		ExpiresAt: sql.NullTime{Time: expiresAt, Valid: true},
+	})
+}
+
+func (r *PrivacyRepository) FailDataExport(ctx context.Context, id int32, reason string) error {
+	return r.q.FailDataExport(ctx, db.FailDataExportParams{
+		ID:    id,
+		Error: sql.NullString{String: reason, Valid: true},
+	})
+}
+
+func (r *PrivacyRepository) ListExpiredDataExports(ctx context.Context) ([]DataExport, error) {
+	dbExports, err := r.q.ListExpiredDataExports(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exports := make([]DataExport, len(dbExports))
+	for i, dbExport := range dbExports {
+		exports[i] = convertDbDataExport(dbExport)
+	}
+	return exports, nil
+}
+
+func (r *PrivacyRepository) ExpireDataExport(ctx context.Context, id int32) error {
+	return r.q.ExpireDataExport(ctx, id)
+}
+
+func (r *PrivacyRepository) CreateErasureRequest(ctx context.Context, userID int32, scheduledFor time.Time) (ErasureRequest, error) {
+	dbRequest, err := r.q.CreateErasureRequest(ctx, db.CreateErasureRequestParams{
+		UserID:       userID,
+		ScheduledFor: scheduledFor,
+	})
+	if err != nil {
+		return ErasureRequest{}, err
+	}
+	return convertDbErasureRequest(dbRequest), nil
+}
+
+func (r *PrivacyRepository) GetPendingErasureRequest(ctx context.Context, userID int32) (ErasureRequest, error) {
+	dbRequest, err := r.q.GetPendingErasureRequest(ctx, userID)
+	if err != nil {
+		return ErasureRequest{}, err
+	}
+	return convertDbErasureRequest(dbRequest), nil
+}
+
+func (r *PrivacyRepository) CancelErasureRequest(ctx context.Context, userID int32) (ErasureRequest, error) {
+	dbRequest, err := r.q.CancelErasureRequest(ctx, userID)
+	if err != nil {
+		return ErasureRequest{}, err
+	}
+	return convertDbErasureRequest(dbRequest), nil
+}
+
+func (r *PrivacyRepository) ListDueErasureRequests(ctx context.Context, limit int32) ([]ErasureRequest, error) {
+	dbRequests, err := r.q.ListDueErasureRequests(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	requests := make([]ErasureRequest, len(dbRequests))
+	for i, dbRequest := range dbRequests {
+		requests[i] = convertDbErasureRequest(dbRequest)
+	}
+	return requests, nil
+}
+
+func (r *PrivacyRepository) CompleteErasureRequest(ctx context.Context, id int32) error {
+	return r.q.CompleteErasureRequest(ctx, id)
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func convertDbDataExport(dbExport db.DataExports) DataExport {
+	return DataExport{
+		ID:          dbExport.ID,
+		UserID:      dbExport.UserID,
+		Status:      dbExport.Status,
+		Error:       dbExport.Error.String,
+		CreatedAt:   dbExport.CreatedAt,
+		CompletedAt: nullTimePtr(dbExport.CompletedAt),
+		ExpiresAt:   nullTimePtr(dbExport.ExpiresAt),
+		filePath:    dbExport.FilePath.String,
+	}
+}
+
+func convertDbErasureRequest(dbRequest db.ErasureRequests) ErasureRequest {
+	return ErasureRequest{
+		ID:           dbRequest.ID,
+		UserID:       dbRequest.UserID,
+		Status:       dbRequest.Status,
+		RequestedAt:  dbRequest.RequestedAt,
+		ScheduledFor: dbRequest.ScheduledFor,
+		CompletedAt:  nullTimePtr(dbRequest.CompletedAt),
+		CancelledAt:  nullTimePtr(dbRequest.CancelledAt),
+	}
+}
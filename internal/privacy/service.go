@@ -0,0 +1,256 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultExportDir    = "data/exports"
+	defaultExportTTL    = 7 * 24 * time.Hour
+	defaultCoolingOff   = 14 * 24 * time.Hour
+	erasureBatchSize    = 100
+	exportNotReadyErr   = "export is not ready"
+	exportExpiredErr    = "export has expired"
+	exportManifestName  = "manifest.json"
+	exportArchivePrefix = "export-"
+)
+
+var (
+	ErrExportNotReady = errors.New(exportNotReadyErr)
+	ErrExportExpired  = errors.New(exportExpiredErr)
+)
+
+type PrivacyService struct {
+	repo       *PrivacyRepository
+	registry   *Registry
+	exportDir  string
+	exportTTL  time.Duration
+	coolingOff time.Duration
+	wake       chan struct{}
+}
+
+// NewPrivacyService reads DATA_EXPORT_DIR, DATA_EXPORT_TTL and
+// ERASURE_COOLING_OFF, the latter two as Go durations
+func NewPrivacyService(repo *PrivacyRepository, registry *Registry) *PrivacyService {
+	exportDir := viper.GetString("DATA_EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = defaultExportDir
+	}
+	exportTTL := viper.GetDuration("DATA_EXPORT_TTL")
+	if exportTTL <= 0 {
+		exportTTL = defaultExportTTL
+	}
+	coolingOff := viper.GetDuration("ERASURE_COOLING_OFF")
+	if coolingOff <= 0 {
+		coolingOff = defaultCoolingOff
+	}
+	return &PrivacyService{
+		repo:       repo,
+		registry:   registry,
+		exportDir:  exportDir,
+		exportTTL:  exportTTL,
+		coolingOff: coolingOff,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// RequestExport queues a data export, or returns the one already in progress
+func (s *PrivacyService) RequestExport(ctx context.Context, userID int32) (DataExport, error) {
+	if err := ctx.Err(); err != nil {
+		return DataExport{}, err
+	}
+
+	latest, err := s.repo.GetLatestDataExport(ctx, userID)
+	switch {
+	case err == nil && (latest.Status == StatusPending || latest.Status == StatusProcessing):
+		return latest, nil
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return DataExport{}, err
+	}
+
+	export, err := s.repo.CreateDataExport(ctx, userID)
+	if err != nil {
+		return DataExport{}, err
+	}
+	s.notify()
+	return export, nil
+}
+
+func (s *PrivacyService) GetLatestExport(ctx context.Context, userID int32) (DataExport, error) {
+	if err := ctx.Err(); err != nil {
+		return DataExport{}, err
+	}
+	return s.repo.GetLatestDataExport(ctx, userID)
+}
+
+// ExportFile returns the archive path of the user's latest export
+func (s *PrivacyService) ExportFile(ctx context.Context, userID int32) (string, error) {
+	export, err := s.GetLatestExport(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if export.Status == StatusExpired || (export.ExpiresAt != nil && export.ExpiresAt.Before(time.Now())) {
+		return "", ErrExportExpired
+	}
+	if export.Status != StatusReady {
+		return "", ErrExportNotReady
+	}
+	return export.filePath, nil
+}
+
+// ProcessNextExport builds the oldest pending export.
+// It returns false when there was nothing to do.
+func (s *PrivacyService) ProcessNextExport(ctx context.Context) (bool, error) {
+	export, err := s.repo.ClaimDataExport(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	filePath, err := s.buildArchive(ctx, export)
+	if err != nil {
+		log.Printf("Failed to build data export %d: %v", export.ID, err)
+		return true, s.repo.FailDataExport(ctx, export.ID, err.Error())
+	}
+	return true, s.repo.CompleteDataExport(ctx, export.ID, filePath, time.Now().Add(s.exportTTL))
+}
+
+func (s *PrivacyService) buildArchive(ctx context.Context, export DataExport) (filePath string, err error) {
+	if err := os.MkdirAll(s.exportDir, 0o700); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(s.exportDir, fmt.Sprintf("%s%d-*.zip", exportArchivePrefix, export.ID))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	zw := zip.NewWriter(f)
+	exporters := s.registry.exportersSnapshot()
+	sections := make([]string, len(exporters))
+	for i, e := range exporters {
+		sections[i] = e.name
+		if err := e.exporter.Export(ctx, export.UserID, &ArchiveWriter{zw: zw, prefix: e.name + "/"}); err != nil {
+			return "", fmt.Errorf("%s exporter: %w", e.name, err)
+		}
+	}
+
+	manifest := &ArchiveWriter{zw: zw}
+	if err := manifest.WriteJSON(exportManifestName, map[string]any{
+		"export_id":    export.ID,
+		"user_id":      export.UserID,
+		"generated_at": time.Now().UTC(),
+		"sections":     sections,
+	}); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+
+	return f.Name(), nil
+}
+
+// CleanupExpiredExports removes archives whose download window has passed
+func (s *PrivacyService) CleanupExpiredExports(ctx context.Context) error {
+	exports, err := s.repo.ListExpiredDataExports(ctx)
+	if err != nil {
+		return err
+	}
+	for _, export := range exports {
+		if err := os.Remove(export.filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err := s.repo.ExpireDataExport(ctx, export.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RequestErasure schedules erasure after the cooling-off period,
+// or returns the request that is already pending
+func (s *PrivacyService) RequestErasure(ctx context.Context, userID int32) (ErasureRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return ErasureRequest{}, err
+	}
+
+	request, err := s.repo.CreateErasureRequest(ctx, userID, time.Now().Add(s.coolingOff))
+	if db.IsUniqueViolation(err) {
+		return s.repo.GetPendingErasureRequest(ctx, userID)
+	}
+	return request, err
+}
+
+func (s *PrivacyService) GetPendingErasure(ctx context.Context, userID int32) (ErasureRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return ErasureRequest{}, err
+	}
+	return s.repo.GetPendingErasureRequest(ctx, userID)
+}
+
+func (s *PrivacyService) CancelErasure(ctx context.Context, userID int32) (ErasureRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return ErasureRequest{}, err
+	}
+	return s.repo.CancelErasureRequest(ctx, userID)
+}
+
+// ProcessDueErasures runs every registered eraser for requests whose
+// cooling-off period has ended. A request stays pending until all
+// erasers succeed, so failures are retried on the next run.
+func (s *PrivacyService) ProcessDueErasures(ctx context.Context) (int, error) {
+	requests, err := s.repo.ListDueErasureRequests(ctx, erasureBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	erasers := s.registry.erasersSnapshot()
+	completed := 0
+	for _, request := range requests {
+		if err := s.erase(ctx, erasers, request.UserID); err != nil {
+			log.Printf("Failed to erase data of user %d: %v", request.UserID, err)
+			continue
+		}
+		if err := s.repo.CompleteErasureRequest(ctx, request.ID); err != nil {
+			return completed, err
+		}
+		completed++
+	}
+	return completed, nil
+}
+
+func (s *PrivacyService) erase(ctx context.Context, erasers []namedEraser, userID int32) error {
+	for _, e := range erasers {
+		if err := e.eraser.Erase(ctx, userID); err != nil {
+			return fmt.Errorf("%s eraser: %w", e.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *PrivacyService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,69 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// attachingExporter writes a JSON document and an attachment
+type attachingExporter struct{}
+
+func (attachingExporter) Export(ctx context.Context, userID int32, w *ArchiveWriter) error {
+	if err := w.WriteJSON("profile.json", map[string]int32{"id": userID}); err != nil {
+		return err
+	}
+	return w.WriteFile("avatar.jpg", strings.NewReader("jpeg bytes"))
+}
+
+func TestBuildArchive(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterExporter("user", attachingExporter{})
+	service := &PrivacyService{registry: registry, exportDir: t.TempDir()}
+
+	filePath, err := service.buildArchive(context.Background(), DataExport{ID: 3, UserID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	files := map[string]string{}
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(content)
+	}
+	if len(files) != 3 {
+		t.Fatalf("archive holds %d files", len(files))
+	}
+	if files["user/avatar.jpg"] != "jpeg bytes" {
+		t.Fatalf("attachment = %q", files["user/avatar.jpg"])
+	}
+	var profile map[string]int32
+	if err := json.Unmarshal([]byte(files["user/profile.json"]), &profile); err != nil || profile["id"] != 7 {
+		t.Fatalf("profile.json = %q, %v", files["user/profile.json"], err)
+	}
+	var manifest struct {
+		ExportID int32    `json:"export_id"`
+		UserID   int32    `json:"user_id"`
+		Sections []string `json:"sections"`
+	}
+	if err := json.Unmarshal([]byte(files[exportManifestName]), &manifest); err != nil ||
+		manifest.ExportID != 3 || manifest.UserID != 7 || len(manifest.Sections) != 1 || manifest.Sections[0] != "user" {
+		t.Fatalf("manifest = %q, %v", files[exportManifestName], err)
+	}
+}
@@ -0,0 +1,69 @@
+package privacy
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const defaultWorkerInterval = time.Minute
+
+// PrivacyWorker builds queued exports, carries out due erasures and
+// removes expired archives. New export requests wake it up immediately.
+type PrivacyWorker struct {
+	service  *PrivacyService
+	interval time.Duration
+}
+
+// NewPrivacyWorker reads PRIVACY_WORKER_INTERVAL as a Go duration
+func NewPrivacyWorker(service *PrivacyService) *PrivacyWorker {
+	interval := viper.GetDuration("PRIVACY_WORKER_INTERVAL")
+	if interval <= 0 {
+		interval = defaultWorkerInterval
+	}
+	return &PrivacyWorker{
+		service:  service,
+		interval: interval,
+	}
+}
+
+func (w *PrivacyWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.service.wake:
+		}
+	}
+}
+
+func (w *PrivacyWorker) runOnce(ctx context.Context) {
+	for {
+		processed, err := w.service.ProcessNextExport(ctx)
+		if err != nil {
+			log.Printf("Failed to process data export: %v", err)
+		}
+		if !processed || ctx.Err() != nil {
+			break
+		}
+	}
+
+	erased, err := w.service.ProcessDueErasures(ctx)
+	if err != nil {
+		log.Printf("Failed to process erasure requests: %v", err)
+	} else if erased > 0 {
+		log.Printf("Erased personal data of %d users", erased)
+	}
+
+	if err := w.service.CleanupExpiredExports(ctx); err != nil {
+		log.Printf("Failed to clean up expired data exports: %v", err)
+	}
+}
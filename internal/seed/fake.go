@@ -0,0 +1,72 @@
+package seed
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// defaultFakePassword satisfies the password rules of CreateUserDto
+const defaultFakePassword = "Passw0rd!"
+
+var (
+	fakeFirstNames = []string{
+		"Alexander", "Anna", "Boris", "Daria", "Dmitry", "Elena", "Ivan", "Irina",
+		"Maria", "Mikhail", "Natalia", "Olga", "Pavel", "Sergey", "Sofia", "Yulia",
+		"Emma", "Liam", "Olivia", "Noah", "Ava", "James", "Mia", "Lucas",
+	}
+	fakeLastNames = []string{
+		"Ivanov", "Smirnov", "Kuznetsov", "Popov", "Sokolov", "Lebedev", "Kozlov", "Novikov",
+		"Morozov", "Volkov", "Smith", "Johnson", "Williams", "Brown", "Garcia", "Miller",
+		"Davis", "Wilson", "Taylor", "Clark",
+	}
+	fakeRoles = []string{
+		"Backend developer", "Frontend developer", "Product manager", "Designer", "QA engineer",
+		"Data analyst", "DevOps engineer", "Team lead", "Support specialist", "Technical writer",
+	}
+	fakeInterests = []string{
+		"distributed systems", "hiking", "photography", "chess", "open source", "cycling",
+		"coffee", "machine learning", "board games", "running", "jazz", "travel",
+	}
+	fakeLocales   = []string{"en", "en-US", "ru", "ru-RU", "de"}
+	fakeTimezones = []string{"UTC", "Europe/Moscow", "Europe/Berlin", "America/New_York", "Asia/Tokyo"}
+	fakeThemes    = []string{"system", "light", "dark"}
+)
+
+// fakeUsers expands the fixture into users numbered after offset. The n-th
+// user depends only on the seed and n, and its username ends with offset+n, so
+// usernames never collide as long as offset skips the users generated for the
+// same tenant before.
+func fakeUsers(fixture FakeUsersFixture, offset int) []UserFixture {
+	password := fixture.Password
+	if password == "" {
+		password = defaultFakePassword
+	}
+	users := make([]UserFixture, fixture.Count)
+	for n := range users {
+		rng := rand.New(rand.NewPCG(fixture.Seed, uint64(n)))
+		first := pick(rng, fakeFirstNames)
+		last := pick(rng, fakeLastNames)
+		number := offset + n + 1
+		username := fmt.Sprintf("%s%s%d", strings.ToLower(first), strings.ToLower(last), number)
+		users[n] = UserFixture{
+			Tenant:   fixture.Tenant,
+			Username: username,
+			Email:    fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(first), strings.ToLower(last), number),
+			Password: password,
+			FullName: first + " " + last,
+			Bio: fmt.Sprintf("%s with %d years of experience. Into %s and %s.",
+				pick(rng, fakeRoles), 1+rng.IntN(15), pick(rng, fakeInterests), pick(rng, fakeInterests)),
+			Settings: map[string]any{
+				"locale":   pick(rng, fakeLocales),
+				"timezone": pick(rng, fakeTimezones),
+				"theme":    pick(rng, fakeThemes),
+			},
+		}
+	}
+	return users
+}
+
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.IntN(len(values))]
+}
@@ -0,0 +1,127 @@
+package seed
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profiles bundled with the binary
+const (
+	ProfileDev      = "dev"
+	ProfileDemo     = "demo"
+	ProfileLoadTest = "load-test"
+)
+
+//go:embed profiles/*.yaml
+var profileFiles embed.FS
+
+var ErrUnknownProfile = errors.New("unknown seed profile: expected dev, demo or load-test")
+
+// Fixtures declare the data to seed. Entities are matched by their natural
+// keys, tenant slugs and usernames, so seeding the same fixtures again
+// updates the existing rows instead of duplicating them.
+type Fixtures struct {
+	Tenants   []TenantFixture    `json:"tenants"`
+	Users     []UserFixture      `json:"users"`
+	FakeUsers []FakeUsersFixture `json:"fake_users"`
+}
+
+type TenantFixture struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// UserFixture describes one user. Tenant is a tenant slug; the default
+// tenant of the seeder is used when it is empty.
+type UserFixture struct {
+	Tenant   string         `json:"tenant"`
+	Username string         `json:"username"`
+	Email    string         `json:"email"`
+	Password string         `json:"password"`
+	FullName string         `json:"full_name"`
+	Bio      string         `json:"bio"`
+	IsAdmin  bool           `json:"is_admin"`
+	Settings map[string]any `json:"settings"`
+}
+
+// FakeUsersFixture generates Count users with realistic names. The same Seed
+// always generates the same users, which keeps fake users idempotent too.
+type FakeUsersFixture struct {
+	Tenant   string `json:"tenant"`
+	Count    int    `json:"count"`
+	Password string `json:"password"`
+	Seed     uint64 `json:"seed"`
+}
+
+// Merge appends the fixtures of other
+func (f Fixtures) Merge(other Fixtures) Fixtures {
+	return Fixtures{
+		Tenants:   append(f.Tenants, other.Tenants...),
+		Users:     append(f.Users, other.Users...),
+		FakeUsers: append(f.FakeUsers, other.FakeUsers...),
+	}
+}
+
+// Profile returns the fixtures of a bundled profile
+func Profile(name string) (Fixtures, error) {
+	data, err := profileFiles.ReadFile("profiles/" + name + ".yaml")
+	if err != nil {
+		return Fixtures{}, ErrUnknownProfile
+	}
+	return ParseFixtures(data, "yaml")
+}
+
+// LoadFixtures reads a YAML or JSON fixture file, telling them apart by the
+// extension
+func LoadFixtures(path string) (Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Fixtures{}, err
+	}
+	format := "json"
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = "yaml"
+	}
+	fixtures, err := ParseFixtures(data, format)
+	if err != nil {
+		return Fixtures{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return fixtures, nil
+}
+
+// ParseFixtures decodes fixtures in the "yaml" or "json" format. Unknown
+// keys are rejected, so that a typo does not silently seed less data.
+func ParseFixtures(data []byte, format string) (Fixtures, error) {
+	if format == "yaml" {
+		// YAML is converted to JSON so that both formats share the json
+		// tags and the strict decoding
+		var document any
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return Fixtures{}, err
+		}
+		if document == nil {
+			return Fixtures{}, nil
+		}
+		var err error
+		if data, err = json.Marshal(document); err != nil {
+			return Fixtures{}, err
+		}
+	}
+
+	var fixtures Fixtures
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fixtures); err != nil {
+		return Fixtures{}, err
+	}
+	return fixtures, nil
+}
@@ -0,0 +1,212 @@
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/middleware"
+	"github.com/malytinKonstantin/go-fiber/internal/shared"
+	"github.com/malytinKonstantin/go-fiber/internal/tenant"
+	"github.com/malytinKonstantin/go-fiber/internal/user"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+)
+
+const fallbackTenant = "default"
+
+// Report counts what a seeding run changed
+type Report struct {
+	Tenants      int   `json:"tenants"`
+	UsersCreated int64 `json:"users_created"`
+	UsersUpdated int64 `json:"users_updated"`
+}
+
+// Seeder writes fixtures through the services, so that seeded users get
+// hashed passwords and validated settings just like registered ones
+type Seeder struct {
+	users         *user.UserService
+	tenants       *tenant.TenantRepository
+	tx            *db.TxManager
+	defaultTenant string
+	concurrency   int
+}
+
+// NewSeeder reads TENANT_DEFAULT, the tenant of fixtures that name none
+// ("default" if unset), and SEED_CONCURRENCY, the number of users written in
+// parallel (the number of CPUs by default, as password hashing dominates).
+func NewSeeder(users *user.UserService, tenants *tenant.TenantRepository, tx *db.TxManager) *Seeder {
+	defaultTenant := viper.GetString("TENANT_DEFAULT")
+	if defaultTenant == "" {
+		defaultTenant = fallbackTenant
+	}
+	concurrency := viper.GetInt("SEED_CONCURRENCY")
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	return &Seeder{
+		users:         users,
+		tenants:       tenants,
+		tx:            tx,
+		defaultTenant: defaultTenant,
+		concurrency:   concurrency,
+	}
+}
+
+// Seed upserts the tenants, then the users. Every user is validated before
+// anything is written; each user is then written in its own transaction.
+func (s *Seeder) Seed(ctx context.Context, fixtures Fixtures) (Report, error) {
+	if err := ctx.Err(); err != nil {
+		return Report{}, err
+	}
+
+	users := fixtures.Users
+	// fake users are numbered per tenant, so that blocks of the same tenant,
+	// e.g. of a profile and -fake, do not repeat each other's usernames
+	fakeCounts := make(map[string]int)
+	for _, fake := range fixtures.FakeUsers {
+		slug := s.tenantOf(UserFixture{Tenant: fake.Tenant})
+		users = append(users, fakeUsers(fake, fakeCounts[slug])...)
+		fakeCounts[slug] += fake.Count
+	}
+	if err := s.validateUsers(users); err != nil {
+		return Report{}, err
+	}
+
+	var report Report
+	tenantIDs := make(map[string]int32)
+	for _, fixture := range fixtures.Tenants {
+		if fixture.Slug == "" || fixture.Name == "" {
+			return report, errors.New("tenants need a slug and a name")
+		}
+		t, err := s.tenants.UpsertTenant(ctx, fixture.Slug, fixture.Name)
+		if err != nil {
+			return report, fmt.Errorf("tenant %q: %w", fixture.Slug, err)
+		}
+		tenantIDs[t.Slug] = t.ID
+		report.Tenants++
+	}
+	for _, fixture := range users {
+		slug := s.tenantOf(fixture)
+		if _, ok := tenantIDs[slug]; ok {
+			continue
+		}
+		t, err := s.tenants.GetTenantBySlug(ctx, slug)
+		if errors.Is(err, sql.ErrNoRows) {
+			return report, fmt.Errorf("tenant %q does not exist", slug)
+		}
+		if err != nil {
+			return report, err
+		}
+		tenantIDs[slug] = t.ID
+	}
+
+	var created, updated atomic.Int64
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(s.concurrency)
+	for _, fixture := range users {
+		group.Go(func() error {
+			tenantCtx := db.WithTenant(groupCtx, tenantIDs[s.tenantOf(fixture)])
+			isNew, err := s.upsertUser(tenantCtx, fixture)
+			if err != nil {
+				return fmt.Errorf("user %q: %w", fixture.Username, err)
+			}
+			if isNew {
+				created.Add(1)
+			} else {
+				updated.Add(1)
+			}
+			return nil
+		})
+	}
+	err := group.Wait()
+	report.UsersCreated = created.Load()
+	report.UsersUpdated = updated.Load()
+	return report, err
+}
+
+func (s *Seeder) tenantOf(fixture UserFixture) string {
+	if fixture.Tenant == "" {
+		return s.defaultTenant
+	}
+	return fixture.Tenant
+}
+
+// validateUsers applies the rules of the registration endpoint and rejects
+// fixtures that declare the same user twice
+func (s *Seeder) validateUsers(users []UserFixture) error {
+	type userKey struct{ tenant, username string }
+	seen := make(map[userKey]bool, len(users))
+	var errs []error
+	for _, fixture := range users {
+		key := userKey{s.tenantOf(fixture), fixture.Username}
+		if seen[key] {
+			errs = append(errs, fmt.Errorf("user %q is declared twice in tenant %q", key.username, key.tenant))
+		}
+		seen[key] = true
+
+		dto := createUserDto(fixture)
+		for _, fieldErr := range middleware.ValidateStruct(&dto) {
+			errs = append(errs, fmt.Errorf("user %q: %s: %s", fixture.Username, fieldErr.Field, fieldErr.Message))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// upsertUser creates the user or brings the existing one in line with the
+// fixture. The password is only rehashed when it changed.
+func (s *Seeder) upsertUser(ctx context.Context, fixture UserFixture) (created bool, err error) {
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		created = false
+		existing, err := s.users.GetUserByUsername(ctx, fixture.Username)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			created = true
+			existing, err = s.users.CreateUser(ctx, createUserDto(fixture))
+		case err == nil:
+			dto := user.UpdateUserDto{
+				Email:    nullString(fixture.Email),
+				FullName: nullString(fixture.FullName),
+				Bio:      nullString(fixture.Bio),
+			}
+			if !user.CheckPasswordHash(fixture.Password, existing.PasswordHash) {
+				dto.Password = nullString(fixture.Password)
+			}
+			existing, err = s.users.UpdateUser(ctx, existing.ID, dto)
+		}
+		if err != nil {
+			return err
+		}
+
+		if existing.IsAdmin != fixture.IsAdmin {
+			if _, err := s.users.SetAdmin(ctx, existing.ID, fixture.IsAdmin); err != nil {
+				return err
+			}
+		}
+		if len(fixture.Settings) > 0 {
+			if _, err := s.users.UpdateSettings(ctx, existing.ID, fixture.Settings); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return created, err
+}
+
+func createUserDto(fixture UserFixture) user.CreateUserDto {
+	return user.CreateUserDto{
+		Username: fixture.Username,
+		Email:    fixture.Email,
+		Password: fixture.Password,
+		FullName: nullString(fixture.FullName),
+		Bio:      nullString(fixture.Bio),
+	}
+}
+
+func nullString(s string) shared.NullString {
+	return shared.NullString{NullString: sql.NullString{String: s, Valid: s != ""}}
+}
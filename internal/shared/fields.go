@@ -0,0 +1,24 @@
+package shared
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ParseFieldList parses a comma-separated list such as "id,username" and
+// checks every entry against the allowlist. Duplicates are dropped.
+func ParseFieldList(raw string, allowed []string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || slices.Contains(fields, field) {
+			continue
+		}
+		if !slices.Contains(allowed, field) {
+			return nil, fmt.Errorf("unknown field %q, allowed: %s", field, strings.Join(allowed, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
@@ -0,0 +1,213 @@
+package shared
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter operators accepted in filter[field][op]=value
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpGt       = "gt"
+	OpGte      = "gte"
+	OpLt       = "lt"
+	OpLte      = "lte"
+	OpIn       = "in"
+	OpNin      = "nin"
+	OpContains = "contains"
+	OpNull     = "null"
+)
+
+// FilterType determines how filter values are parsed and which operators apply
+type FilterType string
+
+const (
+	FilterString FilterType = "string"
+	FilterInt    FilterType = "int"
+	FilterTime   FilterType = "time"
+	FilterBool   FilterType = "bool"
+)
+
+var filterOperators = map[FilterType][]string{
+	FilterString: {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpNin, OpContains, OpNull},
+	FilterInt:    {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpNin, OpNull},
+	FilterTime:   {OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpNull},
+	FilterBool:   {OpEq, OpNe, OpNull},
+}
+
+var comparisonSQL = map[string]string{
+	OpEq:  "=",
+	OpNe:  "<>",
+	OpGt:  ">",
+	OpGte: ">=",
+	OpLt:  "<",
+	OpLte: "<=",
+}
+
+// FilterField describes a filterable field of a resource
+type FilterField struct {
+	// Column is the SQL expression the field compiles to
+	Column string
+	Type   FilterType
+	// Ops restricts the allowed operators; empty allows all operators of the
+	// type. Operators the type does not support are never allowed.
+	Ops []string
+}
+
+func (f FilterField) allows(op string) bool {
+	if len(f.Ops) > 0 && !slices.Contains(f.Ops, op) {
+		return false
+	}
+	return slices.Contains(filterOperators[f.Type], op)
+}
+
+// FilterSchema is the per-resource allowlist of filterable fields
+type FilterSchema map[string]FilterField
+
+// FilterCondition is a single parsed and validated filter
+type FilterCondition struct {
+	Field  string
+	Column string
+	Op     string
+	Value  any
+}
+
+// ParseFilters extracts filter[field][op]=value parameters from the query
+// and validates them against the schema. The operator defaults to eq and
+// list operators take comma-separated values. Dates are accepted as
+// YYYY-MM-DD (midnight UTC) or RFC 3339.
+func ParseFilters(query map[string]string, schema FilterSchema) ([]FilterCondition, error) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if strings.HasPrefix(key, "filter[") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	conditions := make([]FilterCondition, 0, len(keys))
+	for _, key := range keys {
+		field, op, err := parseFilterKey(key)
+		if err != nil {
+			return nil, err
+		}
+		spec, ok := schema[field]
+		if !ok {
+			return nil, fmt.Errorf("unknown filter field %q", field)
+		}
+		if !spec.allows(op) {
+			return nil, fmt.Errorf("operator %q is not supported for filter field %q", op, field)
+		}
+		value, err := parseFilterValue(spec.Type, op, query[key])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for filter[%s][%s]: %w", field, op, err)
+		}
+		conditions = append(conditions, FilterCondition{
+			Field:  field,
+			Column: spec.Column,
+			Op:     op,
+			Value:  value,
+		})
+	}
+	return conditions, nil
+}
+
+// parseFilterKey splits "filter[field][op]" or "filter[field]" into its parts
+func parseFilterKey(key string) (string, string, error) {
+	rest := strings.TrimPrefix(key, "filter")
+	var parts []string
+	for rest != "" {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end < 2 {
+			return "", "", fmt.Errorf("malformed filter parameter %q", key)
+		}
+		parts = append(parts, rest[1:end])
+		rest = rest[end+1:]
+	}
+
+	switch len(parts) {
+	case 1:
+		return parts[0], OpEq, nil
+	case 2:
+		return parts[0], parts[1], nil
+	}
+	return "", "", fmt.Errorf("malformed filter parameter %q", key)
+}
+
+func parseFilterValue(typ FilterType, op, raw string) (any, error) {
+	switch op {
+	case OpNull:
+		return strconv.ParseBool(raw)
+	case OpIn, OpNin:
+		return parseFilterList(typ, strings.Split(raw, ","))
+	}
+	return parseFilterScalar(typ, raw)
+}
+
+func parseFilterScalar(typ FilterType, raw string) (any, error) {
+	switch typ {
+	case FilterInt:
+		return strconv.ParseInt(raw, 10, 64)
+	case FilterBool:
+		return strconv.ParseBool(raw)
+	case FilterTime:
+		if t, err := time.Parse(time.DateOnly, raw); err == nil {
+			return t, nil
+		}
+		return time.Parse(time.RFC3339, raw)
+	}
+	return raw, nil
+}
+
+// parseFilterList returns a typed slice so that it binds as a Postgres array
+func parseFilterList(typ FilterType, raw []string) (any, error) {
+	if typ == FilterInt {
+		values := make([]int64, len(raw))
+		for i, item := range raw {
+			value, err := strconv.ParseInt(strings.TrimSpace(item), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+
+	values := make([]string, len(raw))
+	for i, item := range raw {
+		values[i] = strings.TrimSpace(item)
+	}
+	return values, nil
+}
+
+// CompileFilters turns conditions into SQL predicates meant to be joined with AND.
+// Values are always bound as arguments, and columns come from the schema only.
+func CompileFilters(conditions []FilterCondition, args *SQLArgs) []string {
+	predicates := make([]string, 0, len(conditions))
+	for _, c := range conditions {
+		predicates = append(predicates, compileFilter(c, args))
+	}
+	return predicates
+}
+
+func compileFilter(c FilterCondition, args *SQLArgs) string {
+	switch c.Op {
+	case OpIn:
+		return c.Column + " = ANY(" + args.Bind(c.Value) + ")"
+	case OpNin:
+		return c.Column + " <> ALL(" + args.Bind(c.Value) + ")"
+	case OpContains:
+		return c.Column + " ILIKE '%' || " + args.Bind(EscapeLike(c.Value.(string))) + " || '%'"
+	case OpNull:
+		if c.Value.(bool) {
+			return c.Column + " IS NULL"
+		}
+		return c.Column + " IS NOT NULL"
+	}
+	return c.Column + " " + comparisonSQL[c.Op] + " " + args.Bind(c.Value)
+}
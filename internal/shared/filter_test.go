@@ -0,0 +1,21 @@
+package shared
+
+import "testing"
+
+func TestParseFiltersRejectsOperatorsOfOtherTypes(t *testing.T) {
+	schema := FilterSchema{
+		"age": {Column: "age", Type: FilterInt, Ops: []string{OpEq, OpContains}},
+	}
+
+	if _, err := ParseFilters(map[string]string{"filter[age][contains]": "4"}, schema); err == nil {
+		t.Fatal("contains on an int field was accepted")
+	}
+	conditions, err := ParseFilters(map[string]string{"filter[age]": "42"}, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var args SQLArgs
+	if predicates := CompileFilters(conditions, &args); len(predicates) != 1 || predicates[0] != "age = $1" {
+		t.Fatalf("predicates = %v", predicates)
+	}
+}
@@ -0,0 +1,67 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// IncludeLoader batch-loads a related resource for a set of parent IDs
+// with a single query. Parents without related data may be omitted.
+type IncludeLoader func(ctx context.Context, ids []int32) (map[int32]any, error)
+
+// IncludeRegistry holds the related resources that can be embedded
+// into a response with include=
+type IncludeRegistry struct {
+	mu      sync.RWMutex
+	loaders map[string]IncludeLoader
+}
+
+func NewIncludeRegistry() *IncludeRegistry {
+	return &IncludeRegistry{loaders: make(map[string]IncludeLoader)}
+}
+
+func (r *IncludeRegistry) Register(name string, loader IncludeLoader) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loaders[name] = loader
+}
+
+// Names returns the registered include names in sorted order
+func (r *IncludeRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.loaders))
+	for name := range r.loaders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Load calls each requested loader once for all IDs and returns
+// the related resources keyed by include name and parent ID
+func (r *IncludeRegistry) Load(ctx context.Context, names []string, ids []int32) (map[string]map[int32]any, error) {
+	loaders := make([]IncludeLoader, len(names))
+	r.mu.RLock()
+	for i, name := range names {
+		loader, ok := r.loaders[name]
+		if !ok {
+			r.mu.RUnlock()
+			return nil, fmt.Errorf("unknown include %q", name)
+		}
+		loaders[i] = loader
+	}
+	r.mu.RUnlock()
+
+	included := make(map[string]map[int32]any, len(names))
+	for i, name := range names {
+		related, err := loaders[i](ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", name, err)
+		}
+		included[name] = related
+	}
+	return included, nil
+}
@@ -0,0 +1,28 @@
+package shared
+
+// Count modes for paginated listings
+const (
+	CountExact     = "exact"
+	CountEstimated = "estimated"
+	CountNone      = "none"
+)
+
+// TotalCountHeader carries the total number of matching records
+const TotalCountHeader = "X-Total-Count"
+
+// Page is a pagination envelope around a list of records
+type Page[T any] struct {
+	Data    []T    `json:"data"`
+	Total   *int64 `json:"total,omitempty"`
+	Limit   int32  `json:"limit"`
+	Offset  int32  `json:"offset"`
+	HasMore bool   `json:"has_more"`
+}
+
+func IsValidCountMode(mode string) bool {
+	switch mode {
+	case CountExact, CountEstimated, CountNone:
+		return true
+	}
+	return false
+}
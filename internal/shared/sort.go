@@ -0,0 +1,85 @@
+package shared
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Null ordering options for sort keys
+const (
+	NullsFirst = "nulls_first"
+	NullsLast  = "nulls_last"
+)
+
+// SortSchema is the per-resource allowlist mapping sortable fields to SQL columns
+type SortSchema map[string]string
+
+// SortKey is a single parsed and validated sort key
+type SortKey struct {
+	Field  string
+	Column string
+	Desc   bool
+	// Nulls is NullsFirst, NullsLast or empty for the Postgres default
+	Nulls string
+}
+
+// ParseSort parses sort=-created_at,username:nulls_first where a leading "-"
+// means descending order and an optional suffix controls where NULLs go
+func ParseSort(raw string, schema SortSchema) ([]SortKey, error) {
+	var keys []SortKey
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		var key SortKey
+		if field, nulls, ok := strings.Cut(item, ":"); ok {
+			if nulls != NullsFirst && nulls != NullsLast {
+				return nil, fmt.Errorf("invalid null ordering %q, expected %s or %s", nulls, NullsFirst, NullsLast)
+			}
+			item, key.Nulls = field, nulls
+		}
+		if strings.HasPrefix(item, "-") {
+			item, key.Desc = item[1:], true
+		}
+
+		column, ok := schema[item]
+		if !ok {
+			return nil, fmt.Errorf("unknown sort field %q", item)
+		}
+		if seen[item] {
+			return nil, fmt.Errorf("duplicate sort field %q", item)
+		}
+		seen[item] = true
+		key.Field, key.Column = item, column
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// CompileSort renders an ORDER BY list from validated keys and appends the
+// tiebreaker column in ascending order so that pagination stays stable
+func CompileSort(keys []SortKey, tiebreaker string) string {
+	terms := make([]string, 0, len(keys)+1)
+	hasTiebreaker := false
+	for _, key := range keys {
+		term := key.Column + " ASC"
+		if key.Desc {
+			term = key.Column + " DESC"
+		}
+		switch key.Nulls {
+		case NullsFirst:
+			term += " NULLS FIRST"
+		case NullsLast:
+			term += " NULLS LAST"
+		}
+		terms = append(terms, term)
+		hasTiebreaker = hasTiebreaker || key.Column == tiebreaker
+	}
+	if !hasTiebreaker {
+		terms = append(terms, tiebreaker+" ASC")
+	}
+	return strings.Join(terms, ", ")
+}
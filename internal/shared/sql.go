@@ -0,0 +1,20 @@
+package shared
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SQLArgs collects positional arguments while a statement is being built
+type SQLArgs []any
+
+// Bind appends a value and returns its placeholder
+func (a *SQLArgs) Bind(value any) string {
+	*a = append(*a, value)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// EscapeLike escapes LIKE wildcards so that the value matches literally
+func EscapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
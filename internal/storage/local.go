@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultLocalDir       = "data/blobs"
+	defaultLocalPublicURL = "/media"
+)
+
+// LocalStore keeps blobs on the local filesystem. The files are expected to be
+// served by the application under PublicURL.
+type LocalStore struct {
+	root      string
+	publicURL string
+}
+
+func NewLocalStore(root, publicURL string) *LocalStore {
+	return &LocalStore{
+		root:      root,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+// NewLocalStoreFromConfig reads BLOB_LOCAL_DIR and BLOB_PUBLIC_URL
+func NewLocalStoreFromConfig() *LocalStore {
+	root := viper.GetString("BLOB_LOCAL_DIR")
+	if root == "" {
+		root = defaultLocalDir
+	}
+	publicURL := viper.GetString("BLOB_PUBLIC_URL")
+	if publicURL == "" {
+		publicURL = defaultLocalPublicURL
+	}
+	return NewLocalStore(root, publicURL)
+}
+
+// Root returns the directory the blobs are stored in
+func (s *LocalStore) Root() string {
+	return s.root
+}
+
+// PublicURL returns the URL prefix the blobs are served under
+func (s *LocalStore) PublicURL() string {
+	return s.publicURL
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial blob
+	f, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filePath)
+}
+
+func (s *LocalStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) URL(key string) string {
+	return s.publicURL + "/" + key
+}
+
+// path maps a key to a file inside the root, rejecting keys that escape it
+func (s *LocalStore) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" || cleaned != "/"+key {
+		return "", errors.New("invalid blob key")
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/spf13/viper"
+)
+
+const blobCacheControl = "public, max-age=31536000, immutable"
+
+// S3Store keeps blobs in an S3-compatible bucket (AWS S3, MinIO, etc.)
+type S3Store struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+}
+
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+	// PathStyle addresses the bucket as endpoint/bucket, which MinIO and most
+	// local stand-ins expect
+	PathStyle bool
+	// PublicURL is the prefix of the object URLs handed to clients;
+	// defaults to the bucket URL on the endpoint
+	PublicURL string
+}
+
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("s3 endpoint and bucket are required")
+	}
+
+	lookup := minio.BucketLookupAuto
+	if cfg.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure:       cfg.UseSSL,
+		Region:       cfg.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publicURL := cfg.PublicURL
+	if publicURL == "" {
+		scheme := "http"
+		if cfg.UseSSL {
+			scheme = "https"
+		}
+		publicURL = scheme + "://" + cfg.Endpoint + "/" + cfg.Bucket
+	}
+
+	return &S3Store{
+		client:    client,
+		bucket:    cfg.Bucket,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+	}, nil
+}
+
+// NewS3StoreFromConfig reads the BLOB_S3_* settings and BLOB_PUBLIC_URL
+func NewS3StoreFromConfig() (*S3Store, error) {
+	return NewS3Store(S3Config{
+		Endpoint:  viper.GetString("BLOB_S3_ENDPOINT"),
+		Region:    viper.GetString("BLOB_S3_REGION"),
+		Bucket:    viper.GetString("BLOB_S3_BUCKET"),
+		AccessKey: viper.GetString("BLOB_S3_ACCESS_KEY"),
+		SecretKey: viper.GetString("BLOB_S3_SECRET_KEY"),
+		UseSSL:    viper.GetBool("BLOB_S3_USE_SSL"),
+		PathStyle: viper.GetBool("BLOB_S3_PATH_STYLE"),
+		PublicURL: viper.GetString("BLOB_PUBLIC_URL"),
+	})
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType:  contentType,
+		CacheControl: blobCacheControl,
+	})
+	return err
+}
+
+// Open fetches the object lazily, so its existence is checked first to
+// report a missing blob as ErrNotFound
+func (s *S3Store) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return object, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3Store) URL(key string) string {
+	return s.publicURL + "/" + key
+}
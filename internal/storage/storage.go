@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/viper"
+)
+
+// Storage drivers selectable with BLOB_STORAGE_DRIVER
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// ErrNotFound is returned by BlobStore.Open for a missing blob
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps binary objects under slash-separated keys and exposes them by URL
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open reads a blob back, e.g. to include it in a data export
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// NewBlobStore creates the store selected by BLOB_STORAGE_DRIVER (local by default)
+func NewBlobStore() (BlobStore, error) {
+	switch driver := viper.GetString("BLOB_STORAGE_DRIVER"); driver {
+	case "", DriverLocal:
+		return NewLocalStoreFromConfig(), nil
+	case DriverS3:
+		return NewS3StoreFromConfig()
+	default:
+		return nil, fmt.Errorf("unknown blob storage driver %q", driver)
+	}
+}
@@ -0,0 +1,49 @@
+package tenant
+
+import (
+	"context"
+	"time"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+)
+
+type Tenant struct {
+	ID        int32     `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TenantRepository struct {
+	q *db.Queries
+}
+
+func NewTenantRepository(database *db.DB) *TenantRepository {
+	return &TenantRepository{q: database.Queries()}
+}
+
+func (r *TenantRepository) GetTenantBySlug(ctx context.Context, slug string) (Tenant, error) {
+	dbTenant, err := r.q.GetTenantBySlug(ctx, slug)
+	if err != nil {
+		return Tenant{}, err
+	}
+	return convertDbTenantToTenant(dbTenant), nil
+}
+
+// UpsertTenant creates the tenant or renames the one with the same slug
+func (r *TenantRepository) UpsertTenant(ctx context.Context, slug, name string) (Tenant, error) {
+	dbTenant, err := r.q.UpsertTenant(ctx, db.UpsertTenantParams{Slug: slug, Name: name})
+	if err != nil {
+		return Tenant{}, err
+	}
+	return convertDbTenantToTenant(dbTenant), nil
+}
+
+func convertDbTenantToTenant(dbTenant db.Tenants) Tenant {
+	return Tenant{
+		ID:        dbTenant.ID,
+		Slug:      dbTenant.Slug,
+		Name:      dbTenant.Name,
+		CreatedAt: dbTenant.CreatedAt,
+	}
+}
@@ -0,0 +1,74 @@
+package tenant
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/malytinKonstantin/go-fiber/internal/shared"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultCacheTTL = 5 * time.Minute
+	// bounds the cache, which requests with made-up slugs could otherwise grow
+	maxCachedTenants = 10000
+)
+
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// cachedTenant is a lookup result; misses are cached too
+type cachedTenant struct {
+	tenant Tenant
+	found  bool
+}
+
+// TenantResolver maps tenant slugs from subdomains and headers to tenants.
+// Lookups are cached since they happen on every request.
+type TenantResolver struct {
+	repo  *TenantRepository
+	cache *shared.TTLCache[string, cachedTenant]
+}
+
+// NewTenantResolver reads TENANT_CACHE_TTL as a Go duration
+func NewTenantResolver(repo *TenantRepository) *TenantResolver {
+	cacheTTL := viper.GetDuration("TENANT_CACHE_TTL")
+	if cacheTTL <= 0 {
+		cacheTTL = defaultCacheTTL
+	}
+	return &TenantResolver{
+		repo:  repo,
+		cache: shared.NewTTLCache[string, cachedTenant](cacheTTL, maxCachedTenants),
+	}
+}
+
+// GetTenant returns the tenant with the slug or ErrTenantNotFound
+func (r *TenantResolver) GetTenant(ctx context.Context, slug string) (Tenant, error) {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+
+	cached, ok := r.cache.Get(slug)
+	if !ok {
+		tenant, err := r.repo.GetTenantBySlug(ctx, slug)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return Tenant{}, err
+		}
+		cached = cachedTenant{tenant: tenant, found: err == nil}
+		r.cache.Set(slug, cached)
+	}
+
+	if !cached.found {
+		return Tenant{}, ErrTenantNotFound
+	}
+	return cached.tenant, nil
+}
+
+// ResolveTenant returns the ID of the tenant with the slug
+func (r *TenantResolver) ResolveTenant(ctx context.Context, slug string) (int32, error) {
+	tenant, err := r.GetTenant(ctx, slug)
+	if err != nil {
+		return 0, err
+	}
+	return tenant.ID, nil
+}
@@ -0,0 +1,109 @@
+package testutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/malytinKonstantin/go-fiber/internal/app"
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/tenant"
+	"github.com/spf13/viper"
+)
+
+const defaultAPIPrefix = "/api/v1"
+
+// App is the application wired by app.InitializeApp, as main runs it
+type App struct {
+	*app.App
+	// Fiber serves the routes of the application; Client sends it requests
+	Fiber     *fiber.App
+	apiPrefix string
+}
+
+var (
+	configOnce sync.Once
+	blobDir    string
+)
+
+// configure points the settings the tests must not share with a developer
+// setup at test-only values. viper is global, so this happens once per
+// package.
+func configure() {
+	configOnce.Do(func() {
+		dir, err := os.MkdirTemp("", databasePrefix+"_blobs_")
+		if err != nil {
+			log.Fatalf("testutil: %v", err)
+		}
+		blobDir = dir
+		viper.Set("BLOB_STORAGE_DRIVER", "local")
+		viper.Set("BLOB_LOCAL_DIR", blobDir)
+		viper.Set("SMTP_HOST", "")
+		viper.Set("DATABASE_REPLICA_URLS", "")
+	})
+}
+
+func cleanupConfig() {
+	if blobDir != "" {
+		os.RemoveAll(blobDir)
+	}
+}
+
+// NewApp wires the application on the package database. Background jobs are
+// not started, uploaded files go to a temporary directory and emails are
+// only logged. The connections are closed when the test ends.
+func NewApp(t testing.TB) *App {
+	t.Helper()
+	databaseURL := DatabaseURL(t)
+	configure()
+
+	application, err := app.InitializeApp(databaseURL)
+	if err != nil {
+		t.Fatalf("testutil: could not initialize the app: %v", err)
+	}
+	t.Cleanup(application.DB.Pool().Close)
+
+	apiPrefix := viper.GetString("API_PREFIX")
+	if apiPrefix == "" {
+		apiPrefix = defaultAPIPrefix
+	}
+	return &App{
+		App:       application,
+		Fiber:     application.NewFiberApp(apiPrefix),
+		apiPrefix: apiPrefix,
+	}
+}
+
+// CreateTenant adds a tenant with a unique slug. Tenants keep the data of
+// the tests sharing the package database apart, as every query is scoped
+// to one.
+func (a *App) CreateTenant(t testing.TB) tenant.Tenant {
+	t.Helper()
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+	slug := "test-" + hex.EncodeToString(suffix)
+
+	tenants := tenant.NewTenantRepository(a.DB)
+	created, err := tenants.UpsertTenant(db.WithAllTenants(context.Background()), slug, t.Name())
+	if err != nil {
+		t.Fatalf("testutil: could not create a tenant: %v", err)
+	}
+	return created
+}
+
+// Client returns a client for the API of the application
+func (a *App) Client(t testing.TB) *Client {
+	return &Client{
+		t:         t,
+		fiber:     a.Fiber,
+		apiPrefix: a.apiPrefix,
+		header:    make(map[string]string),
+	}
+}
@@ -0,0 +1,219 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/spf13/viper"
+)
+
+const defaultTenantHeader = "X-Tenant"
+
+// Client sends requests to the API of an App in process, without a
+// listening server. Paths are relative to the API prefix.
+type Client struct {
+	t         testing.TB
+	fiber     *fiber.App
+	apiPrefix string
+	header    map[string]string
+}
+
+// WithTenant returns a copy of the client that sends its requests to the
+// tenant of slug
+func (c *Client) WithTenant(slug string) *Client {
+	header := viper.GetString("TENANT_HEADER")
+	if header == "" {
+		header = defaultTenantHeader
+	}
+	return c.with(header, slug)
+}
+
+// WithToken returns a copy of the client authenticated by the JWT token
+func (c *Client) WithToken(token string) *Client {
+	return c.with(fiber.HeaderAuthorization, "Bearer "+token)
+}
+
+// SignIn signs in as username and returns a copy of the client that is
+// authenticated as that user
+func (c *Client) SignIn(username, password string) *Client {
+	c.t.Helper()
+	res := c.Post("/signin", map[string]string{"username": username, "password": password}).
+		ExpectStatus(http.StatusOK)
+	token, _ := res.Field("token").(string)
+	if token == "" {
+		c.t.Fatalf("testutil: sign in as %q returned no token: %s", username, res.Body)
+	}
+	return c.WithToken(token)
+}
+
+func (c *Client) with(key, value string) *Client {
+	header := make(map[string]string, len(c.header)+1)
+	for k, v := range c.header {
+		header[k] = v
+	}
+	header[key] = value
+	clone := *c
+	clone.header = header
+	return &clone
+}
+
+// Do sends a request with body encoded as JSON, unless it is nil
+func (c *Client) Do(method, path string, body any) *Response {
+	c.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatalf("testutil: could not encode the request body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req := httptest.NewRequest(method, c.apiPrefix+path, reader)
+	if body != nil {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	for k, v := range c.header {
+		req.Header.Set(k, v)
+	}
+
+	res, err := c.fiber.Test(req, -1)
+	if err != nil {
+		c.t.Fatalf("testutil: %s %s: %v", method, path, err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		c.t.Fatalf("testutil: %s %s: %v", method, path, err)
+	}
+	return &Response{t: c.t, method: method, path: path, StatusCode: res.StatusCode, Header: res.Header, Body: data}
+}
+
+func (c *Client) Get(path string) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodGet, path, nil)
+}
+
+func (c *Client) Post(path string, body any) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodPost, path, body)
+}
+
+func (c *Client) Put(path string, body any) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodPut, path, body)
+}
+
+func (c *Client) Patch(path string, body any) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodPatch, path, body)
+}
+
+func (c *Client) Delete(path string) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodDelete, path, nil)
+}
+
+// Response is a response of the API. The assertions fail the test and
+// return the response, so that they can be chained.
+type Response struct {
+	t          testing.TB
+	method     string
+	path       string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// ExpectStatus fails the test unless the response has the status code
+func (r *Response) ExpectStatus(code int) *Response {
+	r.t.Helper()
+	if r.StatusCode != code {
+		r.t.Fatalf("%s %s: status %d, want %d: %s", r.method, r.path, r.StatusCode, code, r.Body)
+	}
+	return r
+}
+
+// Decode unmarshals the body into v
+func (r *Response) Decode(v any) {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Fatalf("%s %s: body is not valid JSON: %v: %s", r.method, r.path, err, r.Body)
+	}
+}
+
+// JSON returns the decoded body: objects as map[string]any, arrays as
+// []any and numbers as float64
+func (r *Response) JSON() any {
+	r.t.Helper()
+	var v any
+	r.Decode(&v)
+	return v
+}
+
+// Field returns the value at a dotted path of the body, such as
+// "items.0.username", where numbers index arrays
+func (r *Response) Field(path string) any {
+	r.t.Helper()
+	v := r.JSON()
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			value, ok := node[key]
+			if !ok {
+				r.t.Fatalf("%s %s: no field %q in the body: %s", r.method, r.path, path, r.Body)
+			}
+			v = value
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				r.t.Fatalf("%s %s: no field %q in the body: %s", r.method, r.path, path, r.Body)
+			}
+			v = node[i]
+		default:
+			r.t.Fatalf("%s %s: no field %q in the body: %s", r.method, r.path, path, r.Body)
+		}
+	}
+	return v
+}
+
+// ExpectField fails the test unless the value at path equals want, as
+// compared after encoding want to JSON
+func (r *Response) ExpectField(path string, want any) *Response {
+	r.t.Helper()
+	if got := r.Field(path); !reflect.DeepEqual(got, r.normalize(want)) {
+		r.t.Fatalf("%s %s: %s = %#v, want %#v", r.method, r.path, path, got, want)
+	}
+	return r
+}
+
+// ExpectJSON fails the test unless the body equals want, as compared after
+// encoding want to JSON
+func (r *Response) ExpectJSON(want any) *Response {
+	r.t.Helper()
+	if !reflect.DeepEqual(r.JSON(), r.normalize(want)) {
+		r.t.Fatalf("%s %s: body %s, want %#v", r.method, r.path, r.Body, want)
+	}
+	return r
+}
+
+// normalize converts want to the types JSON decodes into
+func (r *Response) normalize(want any) any {
+	r.t.Helper()
+	encoded, err := json.Marshal(want)
+	if err != nil {
+		r.t.Fatalf("testutil: could not encode %#v: %v", want, err)
+	}
+	var v any
+	if err := json.Unmarshal(encoded, &v); err != nil {
+		r.t.Fatal(err)
+	}
+	return v
+}
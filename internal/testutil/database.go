@@ -0,0 +1,241 @@
+// Package testutil runs integration tests against disposable Postgres
+// databases and the fully wired application.
+//
+// TEST_DATABASE_URL points at a Postgres server, as a URL whose role may
+// create databases; the database named in it is only used to create and
+// drop the others. Tests using testutil are skipped when it is not set.
+// Each test package gets its own database, copied from a template database
+// that holds the migrated schema, and drops it when its tests end.
+package testutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	migrations "github.com/malytinKonstantin/go-fiber/db"
+)
+
+const (
+	// databasePrefix starts the names of the databases created by testutil
+	databasePrefix = "go_fiber_test"
+	// templateLockKey serializes the test packages that build the template
+	// or copy it, which Postgres refuses while another copy is running
+	templateLockKey int64 = 7_351_023_820
+	// setupTimeout bounds building the template and copying it
+	setupTimeout = 2 * time.Minute
+)
+
+var databaseNameSanitizer = regexp.MustCompile(`[^a-z0-9]+`)
+
+// packageDatabase is the database of the running test package
+var packageDatabase struct {
+	mu sync.Mutex
+	// managed is set by Main, which drops the database at the end
+	managed   bool
+	serverURL string
+	name      string
+	url       string
+	err       error
+}
+
+// Main runs the tests of a package and drops the package database
+// afterwards. Packages that use testutil call it from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		testutil.Main(m)
+//	}
+func Main(m *testing.M) {
+	packageDatabase.mu.Lock()
+	packageDatabase.managed = true
+	packageDatabase.mu.Unlock()
+
+	code := m.Run()
+	if err := dropPackageDatabase(); err != nil {
+		log.Printf("testutil: failed to drop the test database: %v", err)
+	}
+	cleanupConfig()
+	os.Exit(code)
+}
+
+// DatabaseURL returns the URL of the package database, creating it on the
+// first call. The database is migrated and shared by the tests of the
+// package, so tests should not depend on it being empty; giving each test
+// its own tenant, see App.CreateTenant, keeps their data apart.
+func DatabaseURL(t testing.TB) string {
+	t.Helper()
+	serverURL := os.Getenv("TEST_DATABASE_URL")
+	if serverURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	packageDatabase.mu.Lock()
+	defer packageDatabase.mu.Unlock()
+	if !packageDatabase.managed {
+		t.Fatal("testutil: call testutil.Main from TestMain, so that the test database is dropped")
+	}
+	if packageDatabase.url == "" && packageDatabase.err == nil {
+		packageDatabase.serverURL = serverURL
+		packageDatabase.name, packageDatabase.url, packageDatabase.err = createPackageDatabase(serverURL)
+	}
+	if packageDatabase.err != nil {
+		t.Fatalf("testutil: could not create the test database: %v", packageDatabase.err)
+	}
+	return packageDatabase.url
+}
+
+// createPackageDatabase copies the template into a new database named after
+// the test binary, building the template first if the migrations changed
+func createPackageDatabase(serverURL string) (name, databaseURL string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	checksum, err := migrations.MigrationsChecksum()
+	if err != nil {
+		return "", "", err
+	}
+	template := databasePrefix + "_template_" + checksum[:12]
+	name, err = packageDatabaseName()
+	if err != nil {
+		return "", "", err
+	}
+	databaseURL, err = withDatabase(serverURL, name)
+	if err != nil {
+		return "", "", err
+	}
+
+	admin, err := pgx.Connect(ctx, serverURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer admin.Close(context.Background())
+	if _, err := admin.Exec(ctx, "SELECT pg_advisory_lock($1)", templateLockKey); err != nil {
+		return "", "", err
+	}
+	defer admin.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", templateLockKey)
+
+	var exists bool
+	err = admin.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", template).Scan(&exists)
+	if err != nil {
+		return "", "", err
+	}
+	if !exists {
+		if err := buildTemplate(ctx, admin, template); err != nil {
+			return "", "", fmt.Errorf("build the template database: %w", err)
+		}
+	}
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{name}.Sanitize()+" TEMPLATE "+pgx.Identifier{template}.Sanitize()); err != nil {
+		return "", "", err
+	}
+	return name, databaseURL, nil
+}
+
+// buildTemplate migrates a new database and renames it to template, so that
+// a build that is interrupted never leaves a half-migrated template behind.
+// Templates of earlier migrations are dropped.
+func buildTemplate(ctx context.Context, admin *pgx.Conn, template string) error {
+	rows, err := admin.Query(ctx, "SELECT datname FROM pg_database WHERE datname LIKE $1", databasePrefix+`\_template\_%`)
+	if err != nil {
+		return err
+	}
+	stale, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	for _, name := range stale {
+		if _, err := admin.Exec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{name}.Sanitize()+" WITH (FORCE)"); err != nil {
+			return err
+		}
+	}
+
+	building := template + "_building"
+	if _, err := admin.Exec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{building}.Sanitize()+" WITH (FORCE)"); err != nil {
+		return err
+	}
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+pgx.Identifier{building}.Sanitize()+" TEMPLATE template0"); err != nil {
+		return err
+	}
+	if err := migrateDatabase(ctx, admin.Config(), building); err != nil {
+		admin.Exec(context.Background(), "DROP DATABASE IF EXISTS "+pgx.Identifier{building}.Sanitize()+" WITH (FORCE)")
+		return err
+	}
+	_, err = admin.Exec(ctx, "ALTER DATABASE "+pgx.Identifier{building}.Sanitize()+" RENAME TO "+pgx.Identifier{template}.Sanitize())
+	return err
+}
+
+// migrateDatabase applies the embedded migrations to the database name on
+// the server of server. The migrations see the rows of all tenants.
+func migrateDatabase(ctx context.Context, server *pgx.ConnConfig, name string) error {
+	config := server.Copy()
+	config.Database = name
+	config.RuntimeParams["app.tenant_id"] = "*"
+
+	sqlDB := stdlib.OpenDB(*config)
+	defer sqlDB.Close()
+	migrator, err := migrations.NewMigrator(ctx, sqlDB)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+	return migrator.Up()
+}
+
+func dropPackageDatabase() error {
+	packageDatabase.mu.Lock()
+	defer packageDatabase.mu.Unlock()
+	if packageDatabase.url == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+	admin, err := pgx.Connect(ctx, packageDatabase.serverURL)
+	if err != nil {
+		return err
+	}
+	defer admin.Close(context.Background())
+	_, err = admin.Exec(ctx, "DROP DATABASE IF EXISTS "+pgx.Identifier{packageDatabase.name}.Sanitize()+" WITH (FORCE)")
+	return err
+}
+
+// packageDatabaseName combines the name of the test binary, such as
+// user.test, with a random suffix, within the 63 bytes Postgres allows
+func packageDatabaseName() (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	pkg := strings.TrimSuffix(filepath.Base(os.Args[0]), ".test")
+	pkg = strings.Trim(databaseNameSanitizer.ReplaceAllString(strings.ToLower(pkg), "_"), "_")
+	if len(pkg) > 30 {
+		pkg = pkg[:30]
+	}
+	return databasePrefix + "_" + pkg + "_" + hex.EncodeToString(suffix), nil
+}
+
+// withDatabase returns serverURL with the database replaced by name
+func withDatabase(serverURL, name string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return "", errors.New("TEST_DATABASE_URL must be a postgres:// URL")
+	}
+	u.Path = "/" + name
+	u.RawPath = ""
+	return u.String(), nil
+}
@@ -0,0 +1,146 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+
+	"github.com/malytinKonstantin/go-fiber/internal/storage"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	defaultAvatarMaxBytes = 2 << 20
+	// avatarMaxPixels bounds the decoded size so that a small, highly
+	// compressed file cannot exhaust memory
+	avatarMaxPixels   = 40_000_000
+	avatarJPEGQuality = 85
+	avatarContentType = "image/jpeg"
+)
+
+var (
+	ErrAvatarTooLarge        = errors.New("avatar file is too large")
+	ErrAvatarUnsupportedType = errors.New("avatar must be a JPEG, PNG, GIF or WebP image")
+	ErrAvatarInvalidImage    = errors.New("avatar image is corrupted or too large in dimensions")
+)
+
+// avatarSize is a square thumbnail rendered for every uploaded avatar
+type avatarSize struct {
+	name   string
+	pixels int
+}
+
+var avatarSizes = []avatarSize{
+	{"small", 64},
+	{"medium", 256},
+	{"large", 512},
+}
+
+// allowedAvatarTypes are the content types accepted by sniffing, not by
+// the client-supplied header
+var allowedAvatarTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// AvatarURLs maps thumbnail size names to their public URLs
+type AvatarURLs map[string]string
+
+// avatarThumbnail is an encoded thumbnail ready to be stored
+type avatarThumbnail struct {
+	size avatarSize
+	data []byte
+}
+
+// processAvatar validates an uploaded image and renders all thumbnail sizes.
+// The thumbnails are re-encoded from decoded pixels, so EXIF and any other
+// metadata of the original never reach the storage; the EXIF orientation is
+// applied to the pixels beforehand.
+func processAvatar(r io.Reader, maxBytes int64) ([]avatarThumbnail, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrAvatarTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if !allowedAvatarTypes[contentType] {
+		return nil, ErrAvatarUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > avatarMaxPixels {
+		return nil, ErrAvatarInvalidImage
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrAvatarInvalidImage
+	}
+	if contentType == "image/jpeg" {
+		img = applyOrientation(img, readJPEGOrientation(data))
+	}
+
+	src := squareCrop(img.Bounds())
+	thumbnails := make([]avatarThumbnail, len(avatarSizes))
+	for i, size := range avatarSizes {
+		dst := image.NewRGBA(image.Rect(0, 0, size.pixels, size.pixels))
+		// JPEG has no alpha channel, so transparent areas are flattened onto white
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Over, nil)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: avatarJPEGQuality}); err != nil {
+			return nil, err
+		}
+		thumbnails[i] = avatarThumbnail{size: size, data: buf.Bytes()}
+	}
+	return thumbnails, nil
+}
+
+// squareCrop returns the largest centered square within the bounds
+func squareCrop(b image.Rectangle) image.Rectangle {
+	side := min(b.Dx(), b.Dy())
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// avatarBlobKey returns the storage key of one thumbnail of an avatar
+func avatarBlobKey(avatarKey string, size avatarSize) string {
+	return avatarKey + "/" + size.name + ".jpg"
+}
+
+// avatarURLs renders the URLs of all thumbnails, or nil if there is no avatar
+func avatarURLs(blobs storage.BlobStore, avatarKey string) AvatarURLs {
+	if avatarKey == "" {
+		return nil
+	}
+	urls := make(AvatarURLs, len(avatarSizes))
+	for _, size := range avatarSizes {
+		urls[size.name] = blobs.URL(avatarBlobKey(avatarKey, size))
+	}
+	return urls
+}
+
+// deleteAvatarBlobs removes all thumbnails of an avatar, attempting every
+// size even if some deletions fail
+func deleteAvatarBlobs(ctx context.Context, blobs storage.BlobStore, avatarKey string) error {
+	var errs []error
+	for _, size := range avatarSizes {
+		if err := blobs.Delete(ctx, avatarBlobKey(avatarKey, size)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,219 @@
+package user
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+var (
+	red  = color.RGBA{R: 255, A: 255}
+	blue = color.RGBA{B: 255, A: 255}
+)
+
+// halvesImage is red on the left half and blue on the right half
+func halvesImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+// encodeJPEG encodes img with an EXIF segment holding the orientation, in
+// the given TIFF byte order, unless the orientation is 0
+func encodeJPEG(t *testing.T, img image.Image, orientation uint16, order binary.AppendByteOrder) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if orientation == 0 {
+		return data
+	}
+
+	tiff := []byte("MM\x00\x2a")
+	if order == binary.LittleEndian {
+		tiff = []byte("II\x2a\x00")
+	}
+	tiff = order.AppendUint32(tiff, 8)
+	tiff = order.AppendUint16(tiff, 1)
+	tiff = order.AppendUint16(tiff, exifOrientationTag)
+	tiff = order.AppendUint16(tiff, 3) // SHORT
+	tiff = order.AppendUint32(tiff, 1)
+	tiff = order.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0)
+	tiff = order.AppendUint32(tiff, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := binary.BigEndian.AppendUint16([]byte{0xFF, 0xE1}, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func TestReadJPEGOrientation(t *testing.T) {
+	img := halvesImage(8, 4)
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"big endian", encodeJPEG(t, img, 6, binary.BigEndian), 6},
+		{"little endian", encodeJPEG(t, img, 8, binary.LittleEndian), 8},
+		{"without EXIF", encodeJPEG(t, img, 0, nil), 1},
+		{"out of range", encodeJPEG(t, img, 9, binary.BigEndian), 1},
+		{"truncated", encodeJPEG(t, img, 6, binary.BigEndian)[:20], 1},
+		{"not a JPEG", []byte("GIF89a"), 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := readJPEGOrientation(tc.data); got != tc.want {
+				t.Fatalf("orientation = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// a 2x1 image: red, blue
+	img := halvesImage(2, 1)
+	for _, tc := range []struct {
+		orientation int
+		// colors of the upright image, row by row
+		want [][]color.RGBA
+	}{
+		{1, [][]color.RGBA{{red, blue}}},
+		{2, [][]color.RGBA{{blue, red}}},
+		{3, [][]color.RGBA{{blue, red}}},
+		{4, [][]color.RGBA{{red, blue}}},
+		{5, [][]color.RGBA{{red}, {blue}}},
+		{6, [][]color.RGBA{{red}, {blue}}},
+		{7, [][]color.RGBA{{blue}, {red}}},
+		{8, [][]color.RGBA{{blue}, {red}}},
+	} {
+		got := applyOrientation(img, tc.orientation)
+		if got.Bounds().Dx() != len(tc.want[0]) || got.Bounds().Dy() != len(tc.want) {
+			t.Fatalf("orientation %d: bounds %v", tc.orientation, got.Bounds())
+		}
+		for y, row := range tc.want {
+			for x, want := range row {
+				if c := color.RGBAModel.Convert(got.At(x, y)); c != want {
+					t.Fatalf("orientation %d: pixel (%d, %d) = %v, want %v", tc.orientation, x, y, c, want)
+				}
+			}
+		}
+	}
+}
+
+// isMostly reports whether c is close to want, allowing for JPEG artifacts
+func isMostly(c color.Color, want color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	near := func(got uint32, want uint8) bool {
+		diff := int(got>>8) - int(want)
+		return diff > -64 && diff < 64
+	}
+	return near(r, want.R) && near(g, want.G) && near(b, want.B)
+}
+
+func TestProcessAvatar(t *testing.T) {
+	// 40x20 with the red half on the left; with orientation 6 it displays
+	// as 20x40 with the red half on top
+	wide := halvesImage(40, 20)
+
+	for _, tc := range []struct {
+		name                 string
+		data                 []byte
+		topLeft, bottomRight color.RGBA
+	}{
+		{"upright", encodeJPEG(t, wide, 0, nil), red, blue},
+		{"rotated", encodeJPEG(t, wide, 6, binary.BigEndian), red, blue},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			thumbnails, err := processAvatar(bytes.NewReader(tc.data), defaultAvatarMaxBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(thumbnails) != len(avatarSizes) {
+				t.Fatalf("got %d thumbnails", len(thumbnails))
+			}
+			for i, thumbnail := range thumbnails {
+				if thumbnail.size != avatarSizes[i] {
+					t.Fatalf("thumbnail %d has size %v", i, thumbnail.size)
+				}
+				if bytes.Contains(thumbnail.data, []byte("Exif")) {
+					t.Fatalf("%s thumbnail keeps the EXIF segment", thumbnail.size.name)
+				}
+				img, format, err := image.Decode(bytes.NewReader(thumbnail.data))
+				if err != nil || format != "jpeg" {
+					t.Fatalf("%s thumbnail: format %q, %v", thumbnail.size.name, format, err)
+				}
+				side := thumbnail.size.pixels
+				if img.Bounds() != image.Rect(0, 0, side, side) {
+					t.Fatalf("%s thumbnail bounds %v", thumbnail.size.name, img.Bounds())
+				}
+				edge := side / 8
+				if !isMostly(img.At(edge, edge), tc.topLeft) || !isMostly(img.At(side-1-edge, side-1-edge), tc.bottomRight) {
+					t.Fatalf("%s thumbnail is not upright", thumbnail.size.name)
+				}
+			}
+			// the rotated image is split horizontally, the upright one vertically
+			medium, _, _ := image.Decode(bytes.NewReader(thumbnails[1].data))
+			topRight := medium.At(256-32, 32)
+			if tc.name == "rotated" && !isMostly(topRight, red) || tc.name == "upright" && !isMostly(topRight, blue) {
+				t.Fatalf("top right pixel of the %s avatar is %v", tc.name, topRight)
+			}
+		})
+	}
+}
+
+func TestProcessAvatarFlattensTransparency(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	thumbnails, err := processAvatar(&buf, defaultAvatarMaxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	small, _, err := image.Decode(bytes.NewReader(thumbnails[0].data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isMostly(small.At(32, 32), color.RGBA{R: 255, G: 255, B: 255}) {
+		t.Fatalf("transparent pixel rendered as %v, want white", small.At(32, 32))
+	}
+}
+
+func TestProcessAvatarRejectsInvalidUploads(t *testing.T) {
+	jpegData := encodeJPEG(t, halvesImage(4, 4), 0, nil)
+	for _, tc := range []struct {
+		name     string
+		data     []byte
+		maxBytes int64
+		want     error
+	}{
+		{"too large", jpegData, int64(len(jpegData) - 1), ErrAvatarTooLarge},
+		{"not an image", []byte(strings.Repeat("plain text ", 10)), defaultAvatarMaxBytes, ErrAvatarUnsupportedType},
+		{"corrupted", jpegData[:len(jpegData)/2], defaultAvatarMaxBytes, ErrAvatarInvalidImage},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := processAvatar(bytes.NewReader(tc.data), tc.maxBytes)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("processAvatar = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/middleware"
+)
+
+// Batch modes
+const (
+	BatchTransactional = "transactional"
+	BatchIndependent   = "independent"
+)
+
+// Batch operations
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+const (
+	errBatchIDRequired = "id is required for update and delete"
+	errBatchIDNotAllow = "id must not be set for create"
+	errBatchRolledBack = "not applied because another operation of the batch failed"
+)
+
+// BatchItemResult is the outcome of one batch operation. Status uses HTTP
+// status codes with the meaning of the equivalent single request.
+type BatchItemResult struct {
+	Index  int                     `json:"index"`
+	Op     string                  `json:"op"`
+	Status int                     `json:"status"`
+	Data   *User                   `json:"data,omitempty"`
+	Error  string                  `json:"error,omitempty"`
+	Errors []middleware.FieldError `json:"errors,omitempty"`
+}
+
+// BatchResult reports every operation of a batch in request order
+type BatchResult struct {
+	Mode      string            `json:"mode"`
+	Committed bool              `json:"committed"`
+	Results   []BatchItemResult `json:"results"`
+}
+
+// batchOperation is a validated operation ready to run
+type batchOperation struct {
+	op     string
+	id     int32
+	create CreateUserDto
+	update UpdateUserDto
+}
+
+// errBatchItemFailed aborts a transactional batch after an item failed
+var errBatchItemFailed = errors.New("batch item failed")
+
+// RunBatch validates all operations up front and runs them in order. In the
+// transactional mode nothing is written unless every operation succeeds;
+// in the independent mode each operation succeeds or fails on its own.
+func (s *UserService) RunBatch(ctx context.Context, mode string, dtos []BatchOperationDto) (BatchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return BatchResult{}, err
+	}
+	if mode == "" {
+		mode = BatchTransactional
+	}
+
+	result := BatchResult{Mode: mode, Results: make([]BatchItemResult, len(dtos))}
+	operations := make([]batchOperation, len(dtos))
+	valid := true
+	for i, dto := range dtos {
+		result.Results[i] = BatchItemResult{Index: i, Op: dto.Op}
+		operation, fieldErrors := parseBatchOperation(dto)
+		if len(fieldErrors) > 0 {
+			result.Results[i].Status = http.StatusBadRequest
+			result.Results[i].Errors = fieldErrors
+			valid = false
+			continue
+		}
+		operations[i] = operation
+	}
+
+	if mode == BatchIndependent {
+		for i, operation := range operations {
+			if result.Results[i].Status == 0 {
+				s.runBatchOperation(ctx, operation, &result.Results[i])
+			}
+		}
+		result.Committed = true
+		return result, nil
+	}
+
+	if !valid {
+		markBatchRolledBack(result.Results)
+		return result, nil
+	}
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		for i, operation := range operations {
+			if !s.runBatchOperation(ctx, operation, &result.Results[i]) {
+				return errBatchItemFailed
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBatchItemFailed) {
+		markBatchRolledBack(result.Results)
+		return result, nil
+	}
+	if err != nil {
+		return BatchResult{}, err
+	}
+	result.Committed = true
+	return result, nil
+}
+
+// parseBatchOperation decodes the operation data into the DTO of the single
+// request and validates it with the same rules as ValidateDTO
+func parseBatchOperation(dto BatchOperationDto) (batchOperation, []middleware.FieldError) {
+	operation := batchOperation{op: dto.Op, id: dto.ID}
+	switch dto.Op {
+	case BatchCreate:
+		if dto.ID != 0 {
+			return operation, []middleware.FieldError{{Field: "id", Message: errBatchIDNotAllow}}
+		}
+		if err := decodeBatchData(dto.Data, &operation.create); err != nil {
+			return operation, []middleware.FieldError{{Field: "data", Message: err.Error()}}
+		}
+		return operation, middleware.ValidateStruct(&operation.create)
+	case BatchUpdate:
+		if dto.ID <= 0 {
+			return operation, []middleware.FieldError{{Field: "id", Message: errBatchIDRequired}}
+		}
+		if err := decodeBatchData(dto.Data, &operation.update); err != nil {
+			return operation, []middleware.FieldError{{Field: "data", Message: err.Error()}}
+		}
+		return operation, middleware.ValidateStruct(&operation.update)
+	case BatchDelete:
+		if dto.ID <= 0 {
+			return operation, []middleware.FieldError{{Field: "id", Message: errBatchIDRequired}}
+		}
+		return operation, nil
+	}
+	return operation, []middleware.FieldError{{Field: "op", Message: "Does not meet the rule: oneof"}}
+}
+
+func decodeBatchData(data json.RawMessage, dto any) error {
+	if len(bytes.TrimSpace(data)) == 0 {
+		data = json.RawMessage("{}")
+	}
+	return json.Unmarshal(data, dto)
+}
+
+// runBatchOperation records the outcome in result and reports whether the
+// operation succeeded
+func (s *UserService) runBatchOperation(ctx context.Context, operation batchOperation, result *BatchItemResult) bool {
+	var user User
+	var err error
+	switch operation.op {
+	case BatchCreate:
+		user, err = s.CreateUser(ctx, operation.create)
+		result.Status = http.StatusCreated
+	case BatchUpdate:
+		user, err = s.UpdateUser(ctx, operation.id, operation.update)
+		result.Status = http.StatusOK
+	case BatchDelete:
+		err = s.DeleteUser(ctx, operation.id)
+		result.Status = http.StatusNoContent
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		result.Status = http.StatusNotFound
+		result.Error = errUserNotFound
+	case db.IsUniqueViolation(err):
+		result.Status = http.StatusConflict
+		result.Error = errUsernameTaken
+	case err != nil:
+		result.Status = http.StatusInternalServerError
+		result.Error = err.Error()
+	case operation.op != BatchDelete:
+		result.Data = &user
+	}
+	return err == nil
+}
+
+// markBatchRolledBack reports the operations of a failed transactional batch
+// that did not fail themselves
+func markBatchRolledBack(results []BatchItemResult) {
+	for i := range results {
+		if results[i].Status < http.StatusBadRequest {
+			results[i].Status = http.StatusFailedDependency
+			results[i].Error = errBatchRolledBack
+			results[i].Data = nil
+		}
+	}
+}
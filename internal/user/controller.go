@@ -1,10 +1,23 @@
 package user
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/export"
 	"github.com/malytinKonstantin/go-fiber/internal/middleware"
+	"github.com/malytinKonstantin/go-fiber/internal/shared"
 )
 
 const (
@@ -14,15 +27,33 @@ const (
 	errInvalidDTOType     = "internal server error: invalid DTO type"
 	errFailedToUpdateUser = "failed to update user"
 	errFailedToDeleteUser = "failed to delete user"
+	errFailedToRestore    = "failed to restore user"
+	errUserNotDeleted     = "user not found or not deleted"
+	errUsernameTaken      = "username or email is already taken by another user"
 	errInvalidQueryParams = "invalid query parameters"
+	errInvalidCountMode   = "invalid count mode: expected exact, estimated or none"
+	errUnauthorized       = "unauthorized"
+	errAvatarMissing      = "avatar file is required in the \"avatar\" form field"
+	errFailedToSetAvatar  = "failed to update avatar"
+	errImportFileMissing  = "import file is required as the request body or the \"file\" form field"
+	errFailedToImport     = "failed to import users"
+	errInvalidIDs         = "ids must be a comma-separated list of at most 100 positive integers"
+	errInvalidSettings    = "settings must be a JSON object"
+	errFailedToGetSetting = "failed to load settings"
+	errFailedToSetSetting = "failed to update settings"
+	maxLookupIDs          = 100
 )
 
 type UserController struct {
-	service *UserService
+	service  *UserService
+	importer *UserImporter
 }
 
-func NewUserController(service *UserService) *UserController {
-	return &UserController{service: service}
+func NewUserController(service *UserService, importer *UserImporter) *UserController {
+	return &UserController{
+		service:  service,
+		importer: importer,
+	}
 }
 
 func sendErrorResponse(ctx *fiber.Ctx, status int, message string) error {
@@ -49,6 +80,7 @@ func (c *UserController) SetupRoutes(router fiber.Router) {
 	// public routes
 	router.Post("/signin", middleware.SkipAuth(c.SignIn))
 	middleware.RegisterDTO("/signin", "POST", SignInDto{})
+	middleware.RegisterDTO("/signup", "POST", CreateUserDto{})
 	router.Post("/signup", middleware.SkipAuth(c.CreateUser))
 
 	// protected routes
@@ -60,6 +92,19 @@ func (c *UserController) SetupRoutes(router fiber.Router) {
 	middleware.RegisterDTO("/users/:id", "PATCH", UpdateUserDto{})
 	router.Patch("/users/:id", c.UpdateUser)
 	router.Delete("/users/:id", c.DeleteUser)
+	middleware.RegisterDTO("/users/lookup", "POST", LookupUsersDto{})
+	router.Post("/users/lookup", c.LookupUsers)
+	middleware.RegisterDTO("/users/batch", "POST", BatchUsersDto{})
+	router.Post("/users/batch", middleware.AdminOnly(c.BatchUsers))
+	router.Post("/me/avatar", c.UploadAvatar)
+	router.Delete("/me/avatar", c.DeleteAvatar)
+	router.Get("/me/settings", c.GetSettings)
+	router.Patch("/me/settings", c.UpdateSettings)
+
+	// admin routes
+	router.Post("/admin/users/:id/restore", middleware.AdminOnly(c.RestoreUser))
+	router.Post("/admin/users/import", middleware.AdminOnly(c.ImportUsers))
+	router.Get("/admin/users/export", middleware.AdminOnly(c.ExportUsers))
 }
 
 // GetUser retrieves a user by ID
@@ -109,11 +154,22 @@ func (c *UserController) GetUserByUsername(ctx *fiber.Ctx) error {
 // @Param bio query string false "Bio"
 // @Param created_from query string false "Created From (YYYY-MM-DD)"
 // @Param created_to query string false "Created To (YYYY-MM-DD)"
-// @Param sort_by query string false "Sort By (e.g., username_asc, created_at_desc)"
+// @Param sort query string false "Sort keys (e.g., -created_at,username or full_name:nulls_last)"
+// @Param sort_by query string false "Sort By (e.g., username_asc, created_at_desc), superseded by sort"
 // @Param limit query int false "Limit" default(100)
 // @Param offset query int false "Offset" default(0)
-// @Param search query string false "Search"
+// @Param search query string false "Full-text search, results ordered by relevance unless sort_by is set"
+// @Param search_config query string false "Text search configuration (simple, english, russian)" default(simple)
+// @Param envelope query bool false "Wrap the result in a pagination envelope"
+// @Param count query string false "Total count strategy (exact, estimated, none)"
+// @Param fields query string false "Comma-separated fields to return (e.g., id,username,full_name)"
+// @Param include query string false "Comma-separated related resources to embed"
+// @Param filter[field][op] query string false "Filter expression, e.g. filter[created_at][gte]=2024-01-01 or filter[username][in]=a,b"
+// @Param ids query string false "Comma-separated IDs to fetch in this order instead of searching; other parameters are ignored"
 // @Success 200 {array} User
+// @Success 200 {array} UserLookupResult "When ids is set"
+// @Success 200 {object} shared.Page[User] "When envelope=true"
+// @Header 200 {integer} X-Total-Count "Total number of matching users"
 // @Failure 400,500 {object} ErrorResponse
 // @Router /api/v1/users [get]
 func (c *UserController) ListUsers(ctx *fiber.Ctx) error {
@@ -121,19 +177,20 @@ func (c *UserController) ListUsers(ctx *fiber.Ctx) error {
 	if err := ctx.QueryParser(query); err != nil {
 		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidQueryParams)
 	}
+	if query.IDs != "" {
+		ids, err := parseIDList(query.IDs)
+		if err != nil {
+			return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidIDs)
+		}
+		return c.sendLookupResults(ctx, ids)
+	}
 
-	params := SearchUsersParams{
-		Username:    query.Username,
-		Email:       query.Email,
-		FullName:    query.FullName,
-		Bio:         query.Bio,
-		CreatedFrom: query.CreatedFrom,
-		CreatedTo:   query.CreatedTo,
-		Search:      query.Search,
-		SortBy:      query.SortBy,
-		Limit:       query.Limit,
-		Offset:      query.Offset,
+	params, err := searchParamsFromQuery(ctx, query)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
+	params.Limit = query.Limit
+	params.Offset = query.Offset
 
 	if params.Limit <= 0 {
 		params.Limit = 100
@@ -142,12 +199,165 @@ func (c *UserController) ListUsers(ctx *fiber.Ctx) error {
 		params.Offset = 0
 	}
 
-	users, err := c.service.SearchUsers(ctx.Context(), params)
+	// Counting is opt-in for the bare array response to keep it cheap
+	countMode := query.Count
+	if countMode == "" {
+		countMode = shared.CountNone
+		if query.Envelope {
+			countMode = shared.CountExact
+		}
+	}
+	if !shared.IsValidCountMode(countMode) {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidCountMode)
+	}
+
+	allowedFields := userFields(ctx)
+	fields, err := shared.ParseFieldList(query.Fields, allowedFields)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	includes, err := shared.ParseFieldList(query.Include, c.service.IncludeNames())
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	params.Fields = fields
+
+	page, err := c.service.SearchUsersPage(ctx.Context(), params, countMode)
+	if isInvalidSearch(err) {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+
+	if page.Total != nil {
+		ctx.Set(shared.TotalCountHeader, strconv.FormatInt(*page.Total, 10))
+	}
+
+	// Whole users carry is_admin, so other callers always get a projection
+	if len(fields) == 0 && len(includes) == 0 && middleware.IsAdmin(ctx) {
+		if query.Envelope {
+			return ctx.JSON(page)
+		}
+		return ctx.JSON(page.Data)
+	}
+	if len(fields) == 0 {
+		fields = allowedFields
+	}
+
+	items, err := c.service.ProjectUsers(ctx.Context(), page.Data, fields, includes)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	if query.Envelope {
+		return ctx.JSON(shared.Page[map[string]any]{
+			Data:    items,
+			Total:   page.Total,
+			Limit:   page.Limit,
+			Offset:  page.Offset,
+			HasMore: page.HasMore,
+		})
+	}
+
+	return ctx.JSON(items)
+}
+
+// LookupUsers fetches several users by ID
+// @Summary Look up users by IDs
+// @Description Results follow the order of the requested IDs; missing or deleted users are reported with found=false
+// @Tags users
+// @Param lookup body LookupUsersDto true "User IDs"
+// @Success 200 {array} UserLookupResult
+// @Failure 400,500 {object} ErrorResponse
+// @Router /api/v1/users/lookup [post]
+func (c *UserController) LookupUsers(ctx *fiber.Ctx) error {
+	dto, err := getDTO[LookupUsersDto](ctx)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	return c.sendLookupResults(ctx, dto.IDs)
+}
+
+func (c *UserController) sendLookupResults(ctx *fiber.Ctx, ids []int32) error {
+	results, err := c.service.LookupUsers(ctx.Context(), ids)
 	if err != nil {
 		return sendErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
 	}
+	return ctx.JSON(results)
+}
 
-	return ctx.JSON(users)
+// userFields returns the fields the caller may select with fields=
+func userFields(ctx *fiber.Ctx) []string {
+	if middleware.IsAdmin(ctx) {
+		return AdminUserFields
+	}
+	return PublicUserFields
+}
+
+// isInvalidSearch reports whether the search failed on parameters of the
+// caller rather than on the database
+func isInvalidSearch(err error) bool {
+	return errors.Is(err, ErrInvalidSearchConfig) || errors.Is(err, ErrInvalidDateFormat) ||
+		errors.Is(err, ErrInvalidCountMode)
+}
+
+// parseIDList parses ids=1,2,3 keeping the order and repetitions
+func parseIDList(raw string) ([]int32, error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) > maxLookupIDs {
+		return nil, errors.New(errInvalidIDs)
+	}
+	ids := make([]int32, len(parts))
+	for i, part := range parts {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 32)
+		if err != nil || id <= 0 {
+			return nil, errors.New(errInvalidIDs)
+		}
+		ids[i] = int32(id)
+	}
+	return ids, nil
+}
+
+// searchParamsFromQuery collects the filters, search and sort shared by
+// user listings and exports
+func searchParamsFromQuery(ctx *fiber.Ctx, query *ListUsersQuery) (SearchUsersParams, error) {
+	schema := UserFilterSchema
+	if middleware.IsAdmin(ctx) {
+		schema = AdminUserFilterSchema
+	}
+	filters, err := shared.ParseFilters(ctx.Queries(), schema)
+	if err != nil {
+		return SearchUsersParams{}, err
+	}
+	sortParam := query.Sort
+	if sortParam == "" {
+		sortParam = legacySortParam(query.SortBy)
+	}
+	sort, err := shared.ParseSort(sortParam, UserSortSchema)
+	if err != nil {
+		return SearchUsersParams{}, err
+	}
+
+	return SearchUsersParams{
+		Username:     query.Username,
+		Email:        query.Email,
+		FullName:     query.FullName,
+		Bio:          query.Bio,
+		CreatedFrom:  query.CreatedFrom,
+		CreatedTo:    query.CreatedTo,
+		Search:       query.Search,
+		SearchConfig: query.SearchConfig,
+		Filters:      filters,
+		Sort:         sort,
+	}, nil
+}
+
+// legacySortParam converts sort_by values such as created_at_desc into sort syntax
+func legacySortParam(sortBy string) string {
+	if field, ok := strings.CutSuffix(sortBy, "_desc"); ok {
+		return "-" + field
+	}
+	return strings.TrimSuffix(sortBy, "_asc")
 }
 
 // CreateUser creates a new user
@@ -198,8 +408,9 @@ func (c *UserController) UpdateUser(ctx *fiber.Ctx) error {
 	return ctx.JSON(user)
 }
 
-// DeleteUser deletes a user
+// DeleteUser soft-deletes a user
 // @Summary Delete a user
+// @Description The user is hidden immediately and purged after the retention period
 // @Tags users
 // @Param id path int true "User ID"
 // @Success 204 "No Content"
@@ -218,6 +429,33 @@ func (c *UserController) DeleteUser(ctx *fiber.Ctx) error {
 	return ctx.SendStatus(fiber.StatusNoContent)
 }
 
+// RestoreUser restores a soft-deleted user
+// @Summary Restore a deleted user
+// @Tags admin
+// @Param id path int true "User ID"
+// @Success 200 {object} User
+// @Failure 400,403,404,409,500 {object} ErrorResponse
+// @Router /api/v1/admin/users/{id}/restore [post]
+func (c *UserController) RestoreUser(ctx *fiber.Ctx) error {
+	id, err := ctx.ParamsInt("id")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidID)
+	}
+
+	user, err := c.service.RestoreUser(ctx.Context(), int32(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return sendErrorResponse(ctx, fiber.StatusNotFound, errUserNotDeleted)
+		case db.IsUniqueViolation(err):
+			return sendErrorResponse(ctx, fiber.StatusConflict, errUsernameTaken)
+		}
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, errFailedToRestore)
+	}
+
+	return ctx.JSON(user)
+}
+
 // SignIn handles user authentication and returns a JWT token
 // @Summary User sign in
 // @Tags auth
@@ -247,3 +485,306 @@ func (c *UserController) SignIn(ctx *fiber.Ctx) error {
 func (c *UserController) SignOut(ctx *fiber.Ctx) error {
 	return ctx.JSON(fiber.Map{"message": "Successfully signed out"})
 }
+
+// UploadAvatar sets the avatar of the current user
+// @Summary Upload avatar
+// @Description Accepts a JPEG, PNG, GIF or WebP image; metadata is stripped and square thumbnails are generated
+// @Tags users
+// @Accept multipart/form-data
+// @Param avatar formData file true "Avatar image"
+// @Success 200 {object} User
+// @Failure 400,401,404,413,415,500 {object} ErrorResponse
+// @Router /api/v1/me/avatar [post]
+func (c *UserController) UploadAvatar(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+
+	fileHeader, err := ctx.FormFile("avatar")
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errAvatarMissing)
+	}
+	if fileHeader.Size > c.service.AvatarMaxBytes() {
+		return sendErrorResponse(ctx, fiber.StatusRequestEntityTooLarge, ErrAvatarTooLarge.Error())
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errAvatarMissing)
+	}
+	defer file.Close()
+
+	user, err := c.service.SetAvatar(ctx.Context(), userID, file)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return sendErrorResponse(ctx, fiber.StatusNotFound, errUserNotFound)
+	case errors.Is(err, ErrAvatarTooLarge):
+		return sendErrorResponse(ctx, fiber.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, ErrAvatarUnsupportedType):
+		return sendErrorResponse(ctx, fiber.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, ErrAvatarInvalidImage):
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case err != nil:
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, errFailedToSetAvatar)
+	}
+
+	return ctx.JSON(user)
+}
+
+// DeleteAvatar removes the avatar of the current user
+// @Summary Delete avatar
+// @Tags users
+// @Success 200 {object} User
+// @Failure 401,404,500 {object} ErrorResponse
+// @Router /api/v1/me/avatar [delete]
+func (c *UserController) DeleteAvatar(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+
+	user, err := c.service.RemoveAvatar(ctx.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sendErrorResponse(ctx, fiber.StatusNotFound, errUserNotFound)
+	}
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, errFailedToSetAvatar)
+	}
+
+	return ctx.JSON(user)
+}
+
+// GetSettings returns the settings of the current user with defaults filled in
+// @Summary Get my settings
+// @Tags users
+// @Success 200 {object} UserSettings
+// @Failure 401,404,500 {object} ErrorResponse
+// @Router /api/v1/me/settings [get]
+func (c *UserController) GetSettings(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+
+	settings, err := c.service.GetSettings(ctx.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sendErrorResponse(ctx, fiber.StatusNotFound, errUserNotFound)
+	}
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, errFailedToGetSetting)
+	}
+
+	return ctx.JSON(settings)
+}
+
+// UpdateSettings changes settings of the current user
+// @Summary Update my settings
+// @Description The body is a JSON merge patch (RFC 7396): nested objects are merged and null resets a setting to its default.
+// @Tags users
+// @Accept json
+// @Param settings body object true "Settings to change"
+// @Success 200 {object} UserSettings
+// @Failure 400,401,404,500 {object} ErrorResponse
+// @Router /api/v1/me/settings [patch]
+func (c *UserController) UpdateSettings(ctx *fiber.Ctx) error {
+	userID, ok := middleware.CurrentUserID(ctx)
+	if !ok {
+		return sendErrorResponse(ctx, fiber.StatusUnauthorized, errUnauthorized)
+	}
+
+	var patch map[string]any
+	if err := json.Unmarshal(ctx.Body(), &patch); err != nil || patch == nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidSettings)
+	}
+
+	settings, err := c.service.UpdateSettings(ctx.Context(), userID, patch)
+	var validationErr *SettingsValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  ErrInvalidSettings.Error(),
+			"errors": validationErr.Errors,
+		})
+	case errors.Is(err, sql.ErrNoRows):
+		return sendErrorResponse(ctx, fiber.StatusNotFound, errUserNotFound)
+	case err != nil:
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, errFailedToSetSetting)
+	}
+
+	return ctx.JSON(settings)
+}
+
+// ImportUsers creates users in bulk from a CSV or NDJSON file
+// @Summary Import users
+// @Description Rows are validated like POST /users. CSV needs a header with username, email, password and optionally full_name and bio.
+// @Description The file is sent as the request body or as the "file" field of a multipart form.
+// @Tags admin
+// @Accept text/csv,application/x-ndjson,multipart/form-data
+// @Param format query string false "csv or ndjson; derived from the content type by default"
+// @Param mode query string false "dry_run, all_or_nothing (default) or best_effort"
+// @Success 200 {object} ImportReport
+// @Failure 400,403,409,500 {object} ErrorResponse
+// @Failure 422 {object} ImportReport
+// @Router /api/v1/admin/users/import [post]
+func (c *UserController) ImportUsers(ctx *fiber.Ctx) error {
+	mode := ctx.Query("mode", ImportAllOrNothing)
+	if !IsValidImportMode(mode) {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, ErrInvalidImportMode.Error())
+	}
+
+	var body io.Reader
+	contentType := string(ctx.Request().Header.ContentType())
+	if strings.HasPrefix(contentType, fiber.MIMEMultipartForm) {
+		fileHeader, err := ctx.FormFile("file")
+		if err != nil {
+			return sendErrorResponse(ctx, fiber.StatusBadRequest, errImportFileMissing)
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return sendErrorResponse(ctx, fiber.StatusBadRequest, errImportFileMissing)
+		}
+		defer file.Close()
+		body = file
+		contentType = fileHeader.Header.Get(fiber.HeaderContentType)
+	} else {
+		if len(ctx.Body()) == 0 {
+			return sendErrorResponse(ctx, fiber.StatusBadRequest, errImportFileMissing)
+		}
+		body = bytes.NewReader(ctx.Body())
+	}
+
+	format := ctx.Query("format", importFormatFromContentType(contentType))
+	if !IsValidImportFormat(format) {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, ErrInvalidImportFormat.Error())
+	}
+
+	report, err := c.importer.Import(ctx.Context(), body, format, mode)
+	switch {
+	case errors.Is(err, ErrImportRejected):
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(report)
+	case errors.Is(err, ErrImportConflict):
+		return sendErrorResponse(ctx, fiber.StatusConflict, err.Error())
+	case errors.Is(err, ErrImportTooManyRows), errors.Is(err, ErrInvalidImportFile):
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	case err != nil:
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, errFailedToImport)
+	}
+
+	return ctx.JSON(report)
+}
+
+func importFormatFromContentType(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch strings.TrimSpace(mediaType) {
+	case "text/csv":
+		return ImportFormatCSV
+	case "application/x-ndjson", "application/jsonl":
+		return ImportFormatNDJSON
+	}
+	return ""
+}
+
+// ExportUsers streams all users matching the listing filters as a file
+// @Summary Export users
+// @Description Accepts the filter, search and sort parameters of GET /users; limit and offset are ignored.
+// @Description The response is streamed, so errors after the first row only truncate the file.
+// @Tags admin
+// @Produce text/csv,application/x-ndjson,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "csv (default), ndjson or xlsx"
+// @Param fields query string false "Comma-separated columns to export, all public fields by default"
+// @Param search query string false "Full-text search"
+// @Param sort query string false "Sort keys (e.g., -created_at,username)"
+// @Param filter[field][op] query string false "Filter expression, e.g. filter[created_at][gte]=2024-01-01"
+// @Success 200 {file} file
+// @Failure 400,403 {object} ErrorResponse
+// @Router /api/v1/admin/users/export [get]
+func (c *UserController) ExportUsers(ctx *fiber.Ctx) error {
+	query := new(ListUsersQuery)
+	if err := ctx.QueryParser(query); err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, errInvalidQueryParams)
+	}
+
+	format := ctx.Query("format", export.FormatCSV)
+	if !export.IsValidFormat(format) {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, export.ErrInvalidFormat.Error())
+	}
+	params, err := searchParamsFromQuery(ctx, query)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	fields, err := shared.ParseFieldList(query.Fields, AdminUserFields)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	if len(fields) == 0 {
+		fields = AdminUserFields
+	}
+	params.Fields = fields
+
+	// Validate everything before the status line is sent
+	exportQuery, err := c.service.BuildExportQuery(params)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	// The stream outlives the request context, so the tenant scope is carried over
+	exportCtx := context.Background()
+	if tenantID, ok := middleware.CurrentTenantID(ctx); ok {
+		exportCtx = db.WithTenant(exportCtx, tenantID)
+	}
+
+	ctx.Set(fiber.HeaderContentType, export.ContentType(format))
+	ctx.Attachment("users-" + time.Now().Format("20060102-150405") + "." + format)
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// The request context is done once the handler returns, and a
+		// disconnected client surfaces as a write error instead
+		rows, err := export.NewRowWriter(format, w, fields)
+		if err == nil {
+			values := make([]any, len(fields))
+			err = c.service.ExportUsers(exportCtx, exportQuery, func(user User) error {
+				item := projectUser(user, fields)
+				for i, field := range fields {
+					values[i] = item[field]
+				}
+				return rows.WriteRow(values)
+			})
+			if closeErr := rows.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		if err != nil {
+			log.Printf("User export aborted: %v", err)
+		}
+	})
+	return nil
+}
+
+// BatchUsers runs several create, update and delete operations at once
+// @Summary Batch user operations
+// @Description Each operation is validated like the equivalent single request and gets its own status.
+// @Description In the transactional mode nothing is applied unless every operation succeeds.
+// @Tags users
+// @Param batch body BatchUsersDto true "Operations"
+// @Success 200 {object} BatchResult
+// @Failure 400,403,500 {object} ErrorResponse
+// @Failure 422 {object} BatchResult "Transactional batch was rolled back"
+// @Router /api/v1/users/batch [post]
+func (c *UserController) BatchUsers(ctx *fiber.Ctx) error {
+	dto, err := getDTO[BatchUsersDto](ctx)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	result, err := c.service.RunBatch(ctx.Context(), dto.Mode, dto.Operations)
+	if err != nil {
+		return sendErrorResponse(ctx, fiber.StatusInternalServerError, err.Error())
+	}
+	if !result.Committed {
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(result)
+	}
+
+	return ctx.JSON(result)
+}
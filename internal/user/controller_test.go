@@ -0,0 +1,60 @@
+package user_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/malytinKonstantin/go-fiber/internal/testutil"
+)
+
+func TestSignUpAndSignIn(t *testing.T) {
+	app := testutil.NewApp(t)
+	tenant := app.CreateTenant(t)
+	client := app.Client(t).WithTenant(tenant.Slug)
+
+	created := client.Post("/signup", map[string]string{
+		"username": "alice",
+		"email":    "alice@example.com",
+		"password": "Passw0rd!",
+	}).ExpectStatus(http.StatusCreated).
+		ExpectField("username", "alice").
+		ExpectField("is_admin", false)
+	id := int(created.Field("id").(float64))
+
+	client.Get("/users/" + strconv.Itoa(id)).ExpectStatus(http.StatusUnauthorized)
+
+	alice := client.SignIn("alice", "Passw0rd!")
+	alice.Get("/users/"+strconv.Itoa(id)).
+		ExpectStatus(http.StatusOK).
+		ExpectField("id", id).
+		ExpectField("email", "alice@example.com")
+
+	// mistakes in search parameters are the client's
+	alice.Get("/users?search=al&search_config=klingon").ExpectStatus(http.StatusBadRequest)
+	alice.Get("/users?created_from=yesterday").ExpectStatus(http.StatusBadRequest)
+
+	// only admins may see who the administrators are
+	alice.Get("/users?filter[is_admin]=true").ExpectStatus(http.StatusBadRequest)
+	alice.Get("/users?fields=id,is_admin").ExpectStatus(http.StatusBadRequest)
+	var listed []map[string]any
+	alice.Get("/users").ExpectStatus(http.StatusOK).Decode(&listed)
+	if len(listed) != 1 || listed[0]["username"] != "alice" {
+		t.Fatalf("listed %v", listed)
+	}
+	if _, ok := listed[0]["is_admin"]; ok {
+		t.Fatalf("is_admin is listed for a non-admin: %v", listed[0])
+	}
+	// nor the metrics of the service
+	client.Get("/admin/debug/vars").ExpectStatus(http.StatusUnauthorized)
+	alice.Get("/admin/debug/vars").ExpectStatus(http.StatusForbidden)
+	// nor change other users in bulk
+	alice.Post("/users/batch", map[string]any{
+		"operations": []map[string]any{{"op": "delete", "id": id}},
+	}).ExpectStatus(http.StatusForbidden)
+
+	other := app.CreateTenant(t)
+	app.Client(t).WithTenant(other.Slug).
+		Post("/signin", map[string]string{"username": "alice", "password": "Passw0rd!"}).
+		ExpectStatus(http.StatusUnauthorized)
+}
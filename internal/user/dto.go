@@ -1,6 +1,8 @@
 package user
 
 import (
+	"encoding/json"
+
 	"github.com/malytinKonstantin/go-fiber/internal/shared"
 )
 
@@ -118,11 +120,19 @@ type ListUsersQuery struct {
 	// example: 2023-12-31
 	CreatedTo string `query:"created_to"`
 
-	// Search for filtering users by email, full name or bio
+	// Full-text search over username, email, full name and bio with typo tolerance
 	// example: john
 	Search string `query:"search"`
 
-	// Field for sorting
+	// Text search configuration: simple, english or russian
+	// example: english
+	SearchConfig string `query:"search_config"`
+
+	// Sort keys, "-" for descending and an optional :nulls_first or :nulls_last suffix
+	// example: -created_at,username
+	Sort string `query:"sort"`
+
+	// Field for sorting, superseded by sort
 	// example: username_asc
 	SortBy string `query:"sort_by"`
 
@@ -133,6 +143,68 @@ type ListUsersQuery struct {
 	// Offset for pagination
 	// example: 0
 	Offset int32 `query:"offset"`
+
+	// Wrap the result in a pagination envelope with totals
+	// example: true
+	Envelope bool `query:"envelope"`
+
+	// Total count strategy: exact, estimated or none
+	// example: exact
+	Count string `query:"count"`
+
+	// Comma-separated list of fields to return
+	// example: id,username,full_name
+	Fields string `query:"fields"`
+
+	// Comma-separated list of related resources to embed
+	// example: organizations
+	Include string `query:"include"`
+
+	// Comma-separated user IDs to fetch instead of searching; at most 100
+	// example: 3,1,2
+	IDs string `query:"ids"`
+}
+
+// LookupUsersDto represents a request to fetch several users by ID
+// swagger:model
+type LookupUsersDto struct {
+	// IDs of the users, results are returned in the same order
+	// required: true
+	// min: 1
+	// max: 100
+	// example: [3, 1, 2]
+	IDs []int32 `json:"ids" validate:"required,min=1,max=100,dive,gt=0"`
+}
+
+// BatchUsersDto represents a batch of user operations
+// swagger:model
+type BatchUsersDto struct {
+	// Execution mode: transactional (default) applies all operations or none,
+	// independent applies each operation on its own
+	// example: transactional
+	Mode string `json:"mode" validate:"omitempty,oneof=transactional independent"`
+
+	// Operations to run in order
+	// required: true
+	// min: 1
+	// max: 100
+	Operations []BatchOperationDto `json:"operations" validate:"required,min=1,max=100,dive"`
+}
+
+// BatchOperationDto represents a single operation of a batch
+// swagger:model
+type BatchOperationDto struct {
+	// Operation type
+	// required: true
+	// example: update
+	Op string `json:"op" validate:"required,oneof=create update delete"`
+
+	// ID of the user to update or delete
+	// example: 42
+	ID int32 `json:"id"`
+
+	// CreateUserDto for create, UpdateUserDto for update; ignored for delete
+	Data json.RawMessage `json:"data" swaggertype:"object"`
 }
 
 // ErrorResponse represents the structure of an error response
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/malytinKonstantin/go-fiber/internal/outbox"
+)
+
+// AggregateUser is the aggregate type of user events; the aggregate ID is
+// the user ID
+const AggregateUser = "user"
+
+// Events recorded in the outbox when users change. Events leave the service
+// for the sinks and stay in the outbox after the user is erased, so their
+// payload is a UserRef without personal data; subscribers that need the
+// user look it up by ID.
+const (
+	EventUserCreated    = "user.created"
+	EventUserUpdated    = "user.updated"
+	EventUserDeleted    = "user.deleted"
+	EventUserRestored   = "user.restored"
+	EventUserAnonymized = "user.anonymized"
+	EventUserPurged     = "user.purged"
+)
+
+// UserRef identifies the user of an event without its personal data. Changed
+// lists the fields of user.updated that were set, e.g. "email" or "avatar",
+// but not their values.
+type UserRef struct {
+	ID      int32    `json:"id"`
+	Changed []string `json:"changed,omitempty"`
+}
+
+// changeUser runs change in a transaction together with recording an event
+// about the changed user, so that the event is published if and only if the
+// change is committed
+func (s *UserService) changeUser(ctx context.Context, eventType string, changed []string, change func(ctx context.Context) (User, error)) (User, error) {
+	var user User
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = s.withAvatar(change(ctx))
+		if err != nil {
+			return err
+		}
+		return s.recordUserEvent(ctx, eventType, user.ID, user.TenantID, UserRef{ID: user.ID, Changed: changed})
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+// recordUserEvent records an event in the transaction of ctx, which must
+// have changed the user already. The event belongs to tenantID, the tenant of
+// the user, as ctx may be scoped to all tenants.
+func (s *UserService) recordUserEvent(ctx context.Context, eventType string, userID, tenantID int32, payload any) error {
+	event, err := outbox.NewEvent(eventType, AggregateUser, strconv.FormatInt(int64(userID), 10), payload)
+	if err != nil {
+		return err
+	}
+	event.TenantID = tenantID
+	return s.events.Record(ctx, event)
+}
@@ -0,0 +1,475 @@
+package user
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/middleware"
+	"github.com/malytinKonstantin/go-fiber/internal/shared"
+	"github.com/spf13/viper"
+)
+
+// Formats accepted by the bulk import
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+// Import modes
+const (
+	// ImportDryRun validates every row and writes nothing
+	ImportDryRun = "dry_run"
+	// ImportAllOrNothing writes the rows only if every row is valid
+	ImportAllOrNothing = "all_or_nothing"
+	// ImportBestEffort writes the valid rows and reports the rest
+	ImportBestEffort = "best_effort"
+)
+
+const (
+	defaultImportMaxRows = 10000
+	maxNDJSONLineBytes   = 1 << 20
+)
+
+var (
+	ErrInvalidImportFormat = errors.New("invalid import format: expected csv or ndjson")
+	ErrInvalidImportMode   = errors.New("invalid import mode: expected dry_run, all_or_nothing or best_effort")
+	ErrImportTooManyRows   = errors.New("import file has too many rows")
+	ErrInvalidImportFile   = errors.New("invalid import file")
+	// ErrImportRejected is returned with the report when an all-or-nothing
+	// import had invalid rows and nothing was written
+	ErrImportRejected = errors.New("import rejected: some rows are invalid")
+	// ErrImportConflict means a username or email was taken concurrently
+	// during an all-or-nothing import and nothing was written
+	ErrImportConflict = errors.New("a username or email was taken during the import, nothing was imported")
+)
+
+// importColumns are the CSV columns; username, email and password are required
+var importColumns = []string{"username", "email", "password", "full_name", "bio"}
+
+// ImportRowError lists the problems of one input row. Row is the line number
+// in the file, so the CSV header is line 1.
+type ImportRowError struct {
+	Row      int                     `json:"row"`
+	Username string                  `json:"username,omitempty"`
+	Errors   []middleware.FieldError `json:"errors"`
+}
+
+// ImportReport is the outcome of a bulk import
+type ImportReport struct {
+	Mode     string           `json:"mode"`
+	Total    int              `json:"total"`
+	Valid    int              `json:"valid"`
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// importRow is a parsed input row; errors collects everything wrong with it
+type importRow struct {
+	line   int
+	dto    CreateUserDto
+	errors []middleware.FieldError
+}
+
+// UserImporter creates users in bulk from CSV or NDJSON files. Like
+// CreateUser, it records user.created for every inserted user in the
+// transaction of the insert.
+type UserImporter struct {
+	service *UserService
+	repo    Repository
+	maxRows int
+}
+
+// NewUserImporter reads IMPORT_MAX_ROWS, the row limit of a single import
+func NewUserImporter(service *UserService) *UserImporter {
+	maxRows := viper.GetInt("IMPORT_MAX_ROWS")
+	if maxRows <= 0 {
+		maxRows = defaultImportMaxRows
+	}
+	return &UserImporter{
+		service: service,
+		repo:    service.repo,
+		maxRows: maxRows,
+	}
+}
+
+func IsValidImportFormat(format string) bool {
+	return format == ImportFormatCSV || format == ImportFormatNDJSON
+}
+
+func IsValidImportMode(mode string) bool {
+	switch mode {
+	case ImportDryRun, ImportAllOrNothing, ImportBestEffort:
+		return true
+	}
+	return false
+}
+
+// Import validates every row with the CreateUserDto rules, checks usernames
+// and emails for duplicates within the file and among existing users, and
+// writes the rows according to the mode. Errors affecting the whole file
+// (unreadable input, bad header, too many rows) are returned without a report.
+func (i *UserImporter) Import(ctx context.Context, r io.Reader, format, mode string) (ImportReport, error) {
+	if err := ctx.Err(); err != nil {
+		return ImportReport{}, err
+	}
+	if !IsValidImportMode(mode) {
+		return ImportReport{}, ErrInvalidImportMode
+	}
+
+	var rows []importRow
+	var err error
+	switch format {
+	case ImportFormatCSV:
+		rows, err = i.readCSV(r)
+	case ImportFormatNDJSON:
+		rows, err = i.readNDJSON(r)
+	default:
+		return ImportReport{}, ErrInvalidImportFormat
+	}
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	for j := range rows {
+		if rows[j].errors == nil {
+			rows[j].errors = middleware.ValidateStruct(&rows[j].dto)
+		}
+	}
+	markDuplicateRows(rows)
+	if err := i.markTakenRows(ctx, rows); err != nil {
+		return ImportReport{}, err
+	}
+
+	report := ImportReport{Mode: mode, Total: len(rows), Errors: []ImportRowError{}}
+	var valid []*importRow
+	for j := range rows {
+		if len(rows[j].errors) == 0 {
+			valid = append(valid, &rows[j])
+		}
+	}
+	report.Valid = len(valid)
+	report.Failed = report.Total - report.Valid
+
+	if mode == ImportDryRun || len(valid) == 0 {
+		report.Errors = collectRowErrors(rows)
+		return report, nil
+	}
+	if mode == ImportAllOrNothing && report.Failed > 0 {
+		report.Errors = collectRowErrors(rows)
+		return report, ErrImportRejected
+	}
+
+	params, err := hashImportRows(ctx, valid)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	if mode == ImportAllOrNothing {
+		imported, err := i.copyUsers(ctx, params, i.repo.CopyUsers)
+		if db.IsUniqueViolation(err) {
+			// Someone took a username or email after the conflict check
+			return report, ErrImportConflict
+		}
+		if err != nil {
+			return ImportReport{}, err
+		}
+		report.Imported = len(imported)
+		return report, nil
+	}
+
+	imported, err := i.copyUsers(ctx, params, i.repo.CopyUsersSkippingConflicts)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	inserted := make(map[string]bool, len(imported))
+	for _, user := range imported {
+		inserted[user.Username] = true
+	}
+	for _, row := range valid {
+		if !inserted[row.dto.Username] {
+			row.errors = append(row.errors, middleware.FieldError{Message: errUsernameTaken})
+		}
+	}
+	report.Imported = len(inserted)
+	report.Failed = report.Total - report.Imported
+	report.Errors = collectRowErrors(rows)
+	return report, nil
+}
+
+// copyUsers inserts the users with insert and records user.created for each
+// inserted one before the transaction commits
+func (i *UserImporter) copyUsers(ctx context.Context, users []db.CreateUserParams, insert func(context.Context, []db.CreateUserParams) ([]ImportedUser, error)) ([]ImportedUser, error) {
+	var imported []ImportedUser
+	err := i.service.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		imported, err = insert(ctx, users)
+		if err != nil {
+			return err
+		}
+		for _, user := range imported {
+			if err := i.service.recordUserEvent(ctx, EventUserCreated, user.ID, user.TenantID, UserRef{ID: user.ID}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imported, nil
+}
+
+func (i *UserImporter) readCSV(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: CSV header: %v", ErrInvalidImportFile, err)
+	}
+	columns, err := parseImportHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(rows) >= i.maxRows {
+			return nil, ErrImportTooManyRows
+		}
+
+		row := importRow{line: line}
+		if err != nil {
+			row.errors = []middleware.FieldError{{Message: "expected " + strconv.Itoa(len(columns)) + " fields"}}
+		} else {
+			row.dto = csvRecordToDto(columns, record)
+		}
+		rows = append(rows, row)
+	}
+}
+
+// parseImportHeader maps CSV columns to their positions; unknown and
+// repeated columns are rejected so that typos are not silently ignored
+func parseImportHeader(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	for index, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(importColumns, name) {
+			return nil, fmt.Errorf("%w: unknown CSV column %q, expected %s", ErrInvalidImportFile, name, strings.Join(importColumns, ", "))
+		}
+		if _, ok := columns[name]; ok {
+			return nil, fmt.Errorf("%w: duplicate CSV column %q", ErrInvalidImportFile, name)
+		}
+		columns[name] = index
+	}
+	for _, required := range importColumns[:3] {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: missing CSV column %q", ErrInvalidImportFile, required)
+		}
+	}
+	return columns, nil
+}
+
+func csvRecordToDto(columns map[string]int, record []string) CreateUserDto {
+	value := func(name string) string {
+		if index, ok := columns[name]; ok {
+			return strings.TrimSpace(record[index])
+		}
+		return ""
+	}
+	optional := func(name string) shared.NullString {
+		v := value(name)
+		return shared.NullString{NullString: sql.NullString{String: v, Valid: v != ""}}
+	}
+	return CreateUserDto{
+		Username: value("username"),
+		Email:    value("email"),
+		Password: value("password"),
+		FullName: optional("full_name"),
+		Bio:      optional("bio"),
+	}
+}
+
+func (i *UserImporter) readNDJSON(r io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLineBytes)
+
+	var rows []importRow
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		if len(rows) >= i.maxRows {
+			return nil, ErrImportTooManyRows
+		}
+
+		row := importRow{line: line}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row.dto); err != nil {
+			row.errors = []middleware.FieldError{{Message: "invalid JSON: " + err.Error()}}
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	return rows, nil
+}
+
+// markDuplicateRows flags rows repeating a username or email of an earlier row
+func markDuplicateRows(rows []importRow) {
+	usernames := make(map[string]int)
+	emails := make(map[string]int)
+	for j := range rows {
+		row := &rows[j]
+		if row.dto.Username != "" {
+			if first, ok := usernames[row.dto.Username]; ok {
+				row.errors = append(row.errors, middleware.FieldError{
+					Field:   "username",
+					Message: "Duplicates row " + strconv.Itoa(first),
+				})
+			} else {
+				usernames[row.dto.Username] = row.line
+			}
+		}
+		if row.dto.Email != "" {
+			if first, ok := emails[row.dto.Email]; ok {
+				row.errors = append(row.errors, middleware.FieldError{
+					Field:   "email",
+					Message: "Duplicates row " + strconv.Itoa(first),
+				})
+			} else {
+				emails[row.dto.Email] = row.line
+			}
+		}
+	}
+}
+
+// markTakenRows flags valid rows whose username or email belongs to an active user
+func (i *UserImporter) markTakenRows(ctx context.Context, rows []importRow) error {
+	var usernames, emails []string
+	for _, row := range rows {
+		if len(row.errors) == 0 {
+			usernames = append(usernames, row.dto.Username)
+			emails = append(emails, row.dto.Email)
+		}
+	}
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	takenUsernames, takenEmails, err := i.repo.ListTakenUsernamesAndEmails(ctx, usernames, emails)
+	if err != nil {
+		return err
+	}
+	for j := range rows {
+		row := &rows[j]
+		if len(row.errors) > 0 {
+			continue
+		}
+		if takenUsernames[row.dto.Username] {
+			row.errors = append(row.errors, middleware.FieldError{Field: "username", Message: "Username is already taken"})
+		}
+		if takenEmails[row.dto.Email] {
+			row.errors = append(row.errors, middleware.FieldError{Field: "email", Message: "Email is already taken"})
+		}
+	}
+	return nil
+}
+
+// hashImportRows hashes the passwords on all CPUs; bcrypt dominates the
+// cost of an import
+func hashImportRows(ctx context.Context, rows []*importRow) ([]db.CreateUserParams, error) {
+	params := make([]db.CreateUserParams, len(rows))
+	indexes := make(chan int)
+	errs := make(chan error, 1)
+
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				dto := rows[index].dto
+				hash, err := HashPassword(dto.Password)
+				if err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					continue
+				}
+				params[index] = db.CreateUserParams{
+					Username:     dto.Username,
+					Email:        dto.Email,
+					PasswordHash: hash,
+					FullName:     dto.FullName.NullString,
+					Bio:          dto.Bio.NullString,
+				}
+			}
+		}()
+	}
+
+feed:
+	for index := range rows {
+		select {
+		case indexes <- index:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+	}
+	return params, nil
+}
+
+func collectRowErrors(rows []importRow) []ImportRowError {
+	rowErrors := []ImportRowError{}
+	for _, row := range rows {
+		if len(row.errors) > 0 {
+			rowErrors = append(rowErrors, ImportRowError{
+				Row:      row.line,
+				Username: row.dto.Username,
+				Errors:   row.errors,
+			})
+		}
+	}
+	return rowErrors
+}
@@ -0,0 +1,863 @@
+package user
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/outbox"
+	"github.com/malytinKonstantin/go-fiber/internal/shared"
+)
+
+// Unique indexes of the users table reported by MemoryRepository
+const (
+	usernameActiveKey = "users_username_active_key"
+	emailActiveKey    = "users_email_active_key"
+)
+
+// MemoryRepository is a Repository that keeps users in memory, for unit
+// tests of the services. It follows the schema of the users table: rows are
+// scoped to the tenant of the context like the row-level security policy
+// scopes them, usernames and emails are unique among the active users of a
+// tenant, and UserQuery filters, sorts and pages like the SQL built for it.
+// Full-text search is approximated by prefix and substring matching, and
+// strings are ordered bytewise rather than by collation. It is also an
+// outbox.Recorder that keeps the recorded events. It is safe for concurrent
+// use.
+type MemoryRepository struct {
+	mu     sync.RWMutex
+	users  map[int32]db.Users
+	nextID int32
+	events []outbox.Event
+	// settingsMu applies settings updates one after another, like the row
+	// lock taken by UserRepository.UpdateUserSettings
+	settingsMu sync.Mutex
+	// txMu runs the transactions of WithinTx one after another
+	txMu sync.Mutex
+}
+
+var (
+	_ Repository      = (*MemoryRepository)(nil)
+	_ db.Transactor   = (*MemoryRepository)(nil)
+	_ outbox.Recorder = (*MemoryRepository)(nil)
+)
+
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{users: make(map[int32]db.Users), nextID: 1}
+}
+
+type memoryTxKey struct{}
+
+// WithinTx makes MemoryRepository a db.Transactor for the services under
+// test. Transactions run one at a time and a failed one restores the users
+// and events as they were when it started, discarding concurrent writes made
+// outside transactions as well. After-commit hooks run immediately, as db.AfterCommit
+// does outside a database transaction. A nested call joins the outer one.
+func (r *MemoryRepository) WithinTx(ctx context.Context, fn func(ctx context.Context) error, opts ...db.TxOption) error {
+	if ctx.Value(memoryTxKey{}) != nil {
+		return fn(ctx)
+	}
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+
+	r.mu.RLock()
+	snapshot := maps.Clone(r.users)
+	events := len(r.events)
+	r.mu.RUnlock()
+
+	if err := fn(context.WithValue(ctx, memoryTxKey{}, true)); err != nil {
+		r.mu.Lock()
+		r.users = snapshot
+		r.events = r.events[:events]
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Record keeps the events, numbered in the order they are recorded. Events
+// without a tenant get the tenant of ctx, as in the outbox table.
+func (r *MemoryRepository) Record(ctx context.Context, events ...outbox.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, event := range events {
+		event.ID = int64(len(r.events) + 1)
+		if event.TenantID == 0 {
+			event.TenantID, _ = db.TenantFromContext(ctx)
+		}
+		event.CreatedAt = now()
+		r.events = append(r.events, event)
+	}
+	return nil
+}
+
+// Events returns the recorded events that were not rolled back
+func (r *MemoryRepository) Events() []outbox.Event {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.events)
+}
+
+func (r *MemoryRepository) GetUser(ctx context.Context, id int32) (User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := r.activeUser(ctx, id)
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	return convertDbUserToUser(u), nil
+}
+
+func (r *MemoryRepository) GetUsersByIDs(ctx context.Context, ids []int32) (map[int32]User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	users := make(map[int32]User, len(ids))
+	for _, id := range ids {
+		if u, ok := r.activeUser(ctx, id); ok {
+			users[id] = convertDbUserToUser(u)
+		}
+	}
+	return users, nil
+}
+
+func (r *MemoryRepository) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, u := range r.sortedUsers() {
+		if u.Username == username && isActive(u) && visibleIn(ctx, u) {
+			return convertDbUserToUser(u), nil
+		}
+	}
+	return User{}, sql.ErrNoRows
+}
+
+// SearchUsers returns only the columns requested in q.Fields, like
+// UserRepository.SearchUsers
+func (r *MemoryRepository) SearchUsers(ctx context.Context, q UserQuery) ([]User, error) {
+	dbUsers, err := r.search(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return convertDbUsersToUsers(dbUsers), nil
+}
+
+// StreamUsers passes the users matching q to fn. The users are selected
+// before the first call, so fn sees a consistent snapshot and may use the
+// repository.
+func (r *MemoryRepository) StreamUsers(ctx context.Context, q UserQuery, fn func(User) error) error {
+	dbUsers, err := r.search(ctx, q)
+	if err != nil {
+		return err
+	}
+	for _, dbUser := range dbUsers {
+		if err := fn(convertDbUserToUser(dbUser)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) CountUsers(ctx context.Context, q UserQuery) (int64, error) {
+	matches, err := compileUserQuery(q)
+	if err != nil {
+		return 0, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var total int64
+	for _, u := range r.users {
+		if visibleIn(ctx, u) && matches(u) {
+			total++
+		}
+	}
+	return total, nil
+}
+
+// EstimateUsersCount counts the rows of all tenants, deleted ones included,
+// as the planner statistics do
+func (r *MemoryRepository) EstimateUsersCount(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return int64(len(r.users)), nil
+}
+
+func (r *MemoryRepository) CreateUser(ctx context.Context, params db.CreateUserParams) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, err := r.insert(ctx, params)
+	if err != nil {
+		return User{}, err
+	}
+	return convertDbUserToUser(u), nil
+}
+
+func (r *MemoryRepository) SetUserAdmin(ctx context.Context, id int32, isAdmin bool) (User, error) {
+	return r.updateActive(ctx, id, func(u *db.Users) {
+		u.IsAdmin = isAdmin
+	})
+}
+
+// UpdateUser leaves empty strings and invalid NullStrings of params unchanged
+func (r *MemoryRepository) UpdateUser(ctx context.Context, params db.UpdateUserParams) (User, error) {
+	return r.updateActive(ctx, params.ID, func(u *db.Users) {
+		if params.Username != "" {
+			u.Username = params.Username
+		}
+		if params.Email != "" {
+			u.Email = params.Email
+		}
+		if params.PasswordHash != "" {
+			u.PasswordHash = params.PasswordHash
+		}
+		if params.FullName.Valid {
+			u.FullName = params.FullName
+		}
+		if params.Bio.Valid {
+			u.Bio = params.Bio
+		}
+	})
+}
+
+func (r *MemoryRepository) DeleteUser(ctx context.Context, id int32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.activeUser(ctx, id); ok {
+		u.DeletedAt = sql.NullTime{Time: now(), Valid: true}
+		r.users[id] = u
+	}
+	return nil
+}
+
+// RestoreUser fails with a unique violation if the username or email was
+// taken meanwhile
+func (r *MemoryRepository) RestoreUser(ctx context.Context, id int32) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok || isActive(u) || !visibleIn(ctx, u) {
+		return User{}, sql.ErrNoRows
+	}
+	u.DeletedAt = sql.NullTime{}
+	u.UpdatedAt = sql.NullTime{Time: now(), Valid: true}
+	if err := r.checkUnique(u); err != nil {
+		return User{}, err
+	}
+	r.users[id] = u
+	return convertDbUserToUser(u), nil
+}
+
+func (r *MemoryRepository) AnonymizeUser(ctx context.Context, id int32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok || !visibleIn(ctx, u) {
+		return nil
+	}
+	u.Username = fmt.Sprintf("erased_%d", id)
+	u.Email = fmt.Sprintf("erased_%d@invalid", id)
+	u.PasswordHash = ""
+	u.FullName = sql.NullString{}
+	u.Bio = sql.NullString{}
+	u.AvatarKey = sql.NullString{}
+	u.Settings = json.RawMessage("{}")
+	u.IsAdmin = false
+	if !u.DeletedAt.Valid {
+		u.DeletedAt = sql.NullTime{Time: now(), Valid: true}
+	}
+	u.UpdatedAt = sql.NullTime{Time: now(), Valid: true}
+	r.users[id] = u
+	return nil
+}
+
+func (r *MemoryRepository) ListTakenUsernamesAndEmails(ctx context.Context, usernames, emails []string) (map[string]bool, map[string]bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	takenUsernames := make(map[string]bool)
+	takenEmails := make(map[string]bool)
+	for _, u := range r.users {
+		if !isActive(u) || !visibleIn(ctx, u) {
+			continue
+		}
+		if slices.Contains(usernames, u.Username) || slices.Contains(emails, u.Email) {
+			takenUsernames[u.Username] = true
+			takenEmails[u.Email] = true
+		}
+	}
+	return takenUsernames, takenEmails, nil
+}
+
+// CopyUsers inserts all users or, if one of them conflicts, none
+func (r *MemoryRepository) CopyUsers(ctx context.Context, users []db.CreateUserParams) ([]ImportedUser, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	imported := make([]ImportedUser, 0, len(users))
+	for _, params := range users {
+		u, err := r.insert(ctx, params)
+		if err != nil {
+			for _, user := range imported {
+				delete(r.users, user.ID)
+			}
+			return nil, err
+		}
+		imported = append(imported, ImportedUser{ID: u.ID, TenantID: u.TenantID, Username: u.Username})
+	}
+	return imported, nil
+}
+
+// CopyUsersSkippingConflicts inserts the users that conflict neither with
+// existing users nor with those before them, and returns them
+func (r *MemoryRepository) CopyUsersSkippingConflicts(ctx context.Context, users []db.CreateUserParams) ([]ImportedUser, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	imported := make([]ImportedUser, 0, len(users))
+	for _, params := range users {
+		u, err := r.insert(ctx, params)
+		if db.IsUniqueViolation(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		imported = append(imported, ImportedUser{ID: u.ID, TenantID: u.TenantID, Username: u.Username})
+	}
+	return imported, nil
+}
+
+func (r *MemoryRepository) SetUserAvatar(ctx context.Context, id int32, avatarKey string) (User, error) {
+	return r.updateActive(ctx, id, func(u *db.Users) {
+		u.AvatarKey = sql.NullString{String: avatarKey, Valid: avatarKey != ""}
+	})
+}
+
+// GetUserAvatarKey returns the avatar key of any user, including deleted ones
+func (r *MemoryRepository) GetUserAvatarKey(ctx context.Context, id int32) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := r.users[id]
+	if !ok || !visibleIn(ctx, u) {
+		return "", sql.ErrNoRows
+	}
+	return u.AvatarKey.String, nil
+}
+
+// GetUserTenant returns the tenant of any user, including deleted ones
+func (r *MemoryRepository) GetUserTenant(ctx context.Context, id int32) (int32, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	u, ok := r.users[id]
+	if !ok || !visibleIn(ctx, u) {
+		return 0, sql.ErrNoRows
+	}
+	return u.TenantID, nil
+}
+
+func (r *MemoryRepository) GetUserSettings(ctx context.Context, id int32) (map[string]any, error) {
+	r.mu.RLock()
+	u, ok := r.activeUser(ctx, id)
+	r.mu.RUnlock()
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return parseSettingsOverrides(u.Settings)
+}
+
+func (r *MemoryRepository) UpdateUserSettings(ctx context.Context, id int32, update func(overrides map[string]any) (map[string]any, error)) error {
+	r.settingsMu.Lock()
+	defer r.settingsMu.Unlock()
+
+	overrides, err := r.GetUserSettings(ctx, id)
+	if err != nil {
+		return err
+	}
+	overrides, err = update(overrides)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(overrides)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u, ok := r.activeUser(ctx, id); ok {
+		u.Settings = raw
+		r.users[id] = u
+	}
+	return nil
+}
+
+func (r *MemoryRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) ([]PurgedUser, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	purged := []PurgedUser{}
+	for id, u := range r.users {
+		if !u.DeletedAt.Valid || !u.DeletedAt.Time.Before(deletedBefore) || !visibleIn(ctx, u) {
+			continue
+		}
+		delete(r.users, id)
+		purged = append(purged, PurgedUser{ID: id, TenantID: u.TenantID, AvatarKey: u.AvatarKey.String})
+	}
+	return purged, nil
+}
+
+// activeUser returns the user if it is not deleted and ctx may see it.
+// The caller holds mu.
+func (r *MemoryRepository) activeUser(ctx context.Context, id int32) (db.Users, bool) {
+	u, ok := r.users[id]
+	if !ok || !isActive(u) || !visibleIn(ctx, u) {
+		return db.Users{}, false
+	}
+	return u, true
+}
+
+// sortedUsers returns all users ordered by ID. The caller holds mu.
+func (r *MemoryRepository) sortedUsers() []db.Users {
+	users := slices.Collect(maps.Values(r.users))
+	slices.SortFunc(users, func(a, b db.Users) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return users
+}
+
+// insert adds a user to the tenant of ctx with the column defaults.
+// The caller holds mu.
+func (r *MemoryRepository) insert(ctx context.Context, params db.CreateUserParams) (db.Users, error) {
+	tenantID, ok := db.TenantFromContext(ctx)
+	if !ok {
+		// current_tenant_id() is NULL without a single tenant in scope
+		return db.Users{}, &pgconn.PgError{
+			Code:       db.NotNullViolation,
+			Message:    `null value in column "tenant_id" of relation "users" violates not-null constraint`,
+			TableName:  "users",
+			ColumnName: "tenant_id",
+		}
+	}
+	createdAt := now()
+	createdAtPtr := &createdAt
+	u := db.Users{
+		ID:           r.nextID,
+		Username:     params.Username,
+		Email:        params.Email,
+		PasswordHash: params.PasswordHash,
+		FullName:     params.FullName,
+		Bio:          params.Bio,
+		CreatedAt:    &createdAtPtr,
+		UpdatedAt:    sql.NullTime{Time: createdAt, Valid: true},
+		TenantID:     tenantID,
+		Settings:     json.RawMessage("{}"),
+	}
+	// like a sequence, the ID is used up even if the insert fails
+	r.nextID++
+	if err := r.checkUnique(u); err != nil {
+		return db.Users{}, err
+	}
+	r.users[u.ID] = u
+	return u, nil
+}
+
+// updateActive applies change to an active user and stamps updated_at
+func (r *MemoryRepository) updateActive(ctx context.Context, id int32, change func(u *db.Users)) (User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.activeUser(ctx, id)
+	if !ok {
+		return User{}, sql.ErrNoRows
+	}
+	change(&u)
+	u.UpdatedAt = sql.NullTime{Time: now(), Valid: true}
+	if err := r.checkUnique(u); err != nil {
+		return User{}, err
+	}
+	r.users[id] = u
+	return convertDbUserToUser(u), nil
+}
+
+// checkUnique enforces the unique indexes on (tenant_id, username) and
+// (tenant_id, email) among active users. Like the indexes, it sees the rows
+// of every tenant. The caller holds mu.
+func (r *MemoryRepository) checkUnique(u db.Users) error {
+	if !isActive(u) {
+		return nil
+	}
+	for _, other := range r.users {
+		if other.ID == u.ID || other.TenantID != u.TenantID || !isActive(other) {
+			continue
+		}
+		if other.Username == u.Username {
+			return uniqueViolation(usernameActiveKey, "username", u.Username)
+		}
+		if other.Email == u.Email {
+			return uniqueViolation(emailActiveKey, "email", u.Email)
+		}
+	}
+	return nil
+}
+
+func uniqueViolation(constraint, column, value string) error {
+	return &pgconn.PgError{
+		Code:           db.UniqueViolation,
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		Detail:         fmt.Sprintf("Key (tenant_id, %s)=(..., %s) already exists.", column, value),
+		TableName:      "users",
+		ConstraintName: constraint,
+	}
+}
+
+// search filters, orders and pages the users like buildSearchUsersSQL
+func (r *MemoryRepository) search(ctx context.Context, q UserQuery) ([]db.Users, error) {
+	matches, err := compileUserQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	var users []db.Users
+	for _, u := range r.users {
+		if visibleIn(ctx, u) && matches(u) {
+			users = append(users, u)
+		}
+	}
+	r.mu.RUnlock()
+
+	switch {
+	case len(q.Sort) > 0:
+		slices.SortFunc(users, func(a, b db.Users) int {
+			return compareUsers(a, b, q.Sort)
+		})
+	case q.Search != "":
+		slices.SortFunc(users, func(a, b db.Users) int {
+			return cmp.Or(cmp.Compare(searchRank(b, q.Search), searchRank(a, q.Search)), cmp.Compare(a.ID, b.ID))
+		})
+	default:
+		slices.SortFunc(users, func(a, b db.Users) int {
+			return cmp.Compare(a.ID, b.ID)
+		})
+	}
+
+	offset := min(max(int(q.Offset), 0), len(users))
+	users = users[offset:]
+	if q.Limit > 0 && int(q.Limit) < len(users) {
+		users = users[:q.Limit]
+	}
+
+	columns := selectedUserColumns(q.Fields)
+	projected := make([]db.Users, len(users))
+	for i, u := range users {
+		for _, column := range columns {
+			reflect.ValueOf(column.target(&projected[i])).Elem().Set(reflect.ValueOf(column.target(&u)).Elem())
+		}
+	}
+	return projected, nil
+}
+
+// compileUserQuery returns the predicate of buildUserWhere
+func compileUserQuery(q UserQuery) (func(u db.Users) bool, error) {
+	predicates := []func(u db.Users) bool{isActive}
+	for _, filter := range []struct{ column, value string }{
+		{"username", q.Username},
+		{"email", q.Email},
+		{"full_name", q.FullName},
+		{"bio", q.Bio},
+	} {
+		if filter.value == "" {
+			continue
+		}
+		pattern, err := compileILike("%" + filter.value + "%")
+		if err != nil {
+			return nil, err
+		}
+		column := filter.column
+		predicates = append(predicates, func(u db.Users) bool {
+			value, ok := userColumnValue(u, column)
+			return ok && pattern.MatchString(value.(string))
+		})
+	}
+	if q.CreatedFrom != nil {
+		from := q.CreatedFrom.UTC().Format(time.DateOnly)
+		predicates = append(predicates, func(u db.Users) bool {
+			createdAt, ok := userColumnValue(u, "created_at")
+			return ok && createdAt.(time.Time).UTC().Format(time.DateOnly) >= from
+		})
+	}
+	if q.CreatedTo != nil {
+		to := q.CreatedTo.UTC().Format(time.DateOnly)
+		predicates = append(predicates, func(u db.Users) bool {
+			createdAt, ok := userColumnValue(u, "created_at")
+			return ok && createdAt.(time.Time).UTC().Format(time.DateOnly) <= to
+		})
+	}
+	if q.Search != "" {
+		predicates = append(predicates, func(u db.Users) bool {
+			return searchRank(u, q.Search) > 0
+		})
+	}
+	for _, condition := range q.Filters {
+		predicate, err := compileFilterCondition(condition)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, predicate)
+	}
+
+	return func(u db.Users) bool {
+		for _, predicate := range predicates {
+			if !predicate(u) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// compileFilterCondition mirrors shared.CompileFilters, including SQL's
+// treatment of NULL: it matches no comparison, list or pattern
+func compileFilterCondition(c shared.FilterCondition) (func(u db.Users) bool, error) {
+	switch c.Op {
+	case shared.OpNull:
+		isNull := c.Value.(bool)
+		return func(u db.Users) bool {
+			_, ok := userColumnValue(u, c.Column)
+			return ok != isNull
+		}, nil
+	case shared.OpContains:
+		pattern, err := compileILike("%" + shared.EscapeLike(c.Value.(string)) + "%")
+		if err != nil {
+			return nil, err
+		}
+		return func(u db.Users) bool {
+			value, ok := userColumnValue(u, c.Column)
+			return ok && pattern.MatchString(value.(string))
+		}, nil
+	case shared.OpIn, shared.OpNin:
+		var list []any
+		switch values := c.Value.(type) {
+		case []int64:
+			for _, value := range values {
+				list = append(list, value)
+			}
+		case []string:
+			for _, value := range values {
+				list = append(list, value)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported list for filter %q: %T", c.Field, c.Value)
+		}
+		return func(u db.Users) bool {
+			value, ok := userColumnValue(u, c.Column)
+			if !ok {
+				return false
+			}
+			found := slices.ContainsFunc(list, func(item any) bool {
+				return compareValues(value, item) == 0
+			})
+			return found == (c.Op == shared.OpIn)
+		}, nil
+	}
+
+	accepts := map[string]func(int) bool{
+		shared.OpEq:  func(order int) bool { return order == 0 },
+		shared.OpNe:  func(order int) bool { return order != 0 },
+		shared.OpGt:  func(order int) bool { return order > 0 },
+		shared.OpGte: func(order int) bool { return order >= 0 },
+		shared.OpLt:  func(order int) bool { return order < 0 },
+		shared.OpLte: func(order int) bool { return order <= 0 },
+	}[c.Op]
+	if accepts == nil {
+		return nil, fmt.Errorf("unsupported filter operator %q", c.Op)
+	}
+	return func(u db.Users) bool {
+		value, ok := userColumnValue(u, c.Column)
+		return ok && accepts(compareValues(value, c.Value))
+	}, nil
+}
+
+// compareUsers orders by the sort keys, then by ID like shared.CompileSort.
+// NULLs sort after all values unless the key says otherwise, as in Postgres.
+func compareUsers(a, b db.Users, keys []shared.SortKey) int {
+	for _, key := range keys {
+		av, aOK := userColumnValue(a, key.Column)
+		bv, bOK := userColumnValue(b, key.Column)
+		switch {
+		case !aOK && !bOK:
+			continue
+		case !aOK || !bOK:
+			nullsFirst := key.Desc
+			switch key.Nulls {
+			case shared.NullsFirst:
+				nullsFirst = true
+			case shared.NullsLast:
+				nullsFirst = false
+			}
+			if !aOK == nullsFirst {
+				return -1
+			}
+			return 1
+		}
+		c := compareValues(av, bv)
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// userColumnValue returns the value of a filterable or sortable column as
+// the type of its filter values; false means NULL
+func userColumnValue(u db.Users, column string) (any, bool) {
+	switch column {
+	case "id":
+		return int64(u.ID), true
+	case "username":
+		return u.Username, true
+	case "email":
+		return u.Email, true
+	case "full_name":
+		return u.FullName.String, u.FullName.Valid
+	case "bio":
+		return u.Bio.String, u.Bio.Valid
+	case "created_at":
+		if u.CreatedAt == nil || *u.CreatedAt == nil {
+			return nil, false
+		}
+		return **u.CreatedAt, true
+	case "updated_at":
+		return u.UpdatedAt.Time, u.UpdatedAt.Valid
+	case "is_admin":
+		return u.IsAdmin, true
+	}
+	return nil, false
+}
+
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case int64:
+		return cmp.Compare(a, b.(int64))
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case bool:
+		switch {
+		case a == b.(bool):
+			return 0
+		case a:
+			return 1
+		}
+		return -1
+	}
+	panic(fmt.Sprintf("user: cannot compare %T", a))
+}
+
+// compileILike turns an ILIKE pattern into a regular expression: % matches
+// any run of characters, _ a single one, and a backslash escapes the next one
+func compileILike(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString(`(?is)^`)
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			sb.WriteString(`.*`)
+		case r == '_':
+			sb.WriteString(`.`)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString(`$`)
+	return regexp.Compile(sb.String())
+}
+
+// searchRank approximates the relevance used to order searches, and is zero
+// for users the search does not match. Every word of the search has to
+// prefix a word of the user, with username and full name weighing more than
+// the email and the bio; a search contained in the username, email or full
+// name matches as well, standing in for trigram similarity.
+func searchRank(u db.Users, search string) float64 {
+	words := searchWords(search)
+	if len(words) == 0 {
+		return 0
+	}
+	fields := []struct {
+		value  string
+		weight float64
+	}{
+		{u.Username, 1},
+		{u.FullName.String, 1},
+		{u.Email, 0.4},
+		{u.Bio.String, 0.2},
+	}
+
+	var rank float64
+	matchedAll := true
+	for _, word := range words {
+		var best float64
+		for _, field := range fields {
+			for _, candidate := range searchWords(field.value) {
+				if strings.HasPrefix(candidate, word) {
+					best = max(best, field.weight)
+				}
+			}
+		}
+		matchedAll = matchedAll && best > 0
+		rank += best
+	}
+	if !matchedAll {
+		rank = 0
+	}
+
+	needle := strings.ToLower(strings.TrimSpace(search))
+	for _, field := range fields[:3] {
+		if strings.Contains(strings.ToLower(field.value), needle) {
+			rank += float64(len(needle)) / float64(max(len(field.value), 1))
+		}
+	}
+	return rank
+}
+
+// searchWords splits text into lowercase words like buildPrefixTSQuery
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// visibleIn reports whether the tenant scope of ctx lets queries see u, as
+// the row-level security policy of users does
+func visibleIn(ctx context.Context, u db.Users) bool {
+	if db.AllTenantsFromContext(ctx) {
+		return true
+	}
+	tenantID, ok := db.TenantFromContext(ctx)
+	return ok && u.TenantID == tenantID
+}
+
+func isActive(u db.Users) bool {
+	return !u.DeletedAt.Valid
+}
+
+// now returns the current time at the microsecond precision of Postgres
+func now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
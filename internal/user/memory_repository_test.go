@@ -0,0 +1,42 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+)
+
+func TestMemoryRepository(t *testing.T) {
+	RunRepositoryConformance(t, func(t *testing.T) Repository {
+		return NewMemoryRepository()
+	})
+}
+
+func TestMemoryRepositoryWithinTx(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := db.WithTenant(context.Background(), conformanceTenantA)
+	errRollback := errors.New("rollback")
+
+	err := repo.WithinTx(ctx, func(ctx context.Context) error {
+		mustCreateUser(t, repo, ctx, userParams("alice", "", ""))
+		return repo.WithinTx(ctx, func(ctx context.Context) error {
+			mustCreateUser(t, repo, ctx, userParams("bob", "", ""))
+			return errRollback
+		})
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithinTx = %v, want the error of fn", err)
+	}
+	assertUsernames(t, repo, ctx, UserQuery{})
+
+	err = repo.WithinTx(ctx, func(ctx context.Context) error {
+		mustCreateUser(t, repo, ctx, userParams("alice", "", ""))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertUsernames(t, repo, ctx, UserQuery{}, "alice")
+}
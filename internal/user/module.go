@@ -1,19 +1,30 @@
 package user
 
 import (
+	"context"
+
 	"github.com/gofiber/fiber/v2"
 )
 
 type Module struct {
 	Controller *UserController
+	Importer   *UserImporter
+	PurgeJob   *PurgeJob
 }
 
-func NewModule(controller *UserController) *Module {
+func NewModule(controller *UserController, importer *UserImporter, purgeJob *PurgeJob) *Module {
 	return &Module{
 		Controller: controller,
+		Importer:   importer,
+		PurgeJob:   purgeJob,
 	}
 }
 
 func (m *Module) SetupRoutes(router fiber.Router) {
 	m.Controller.SetupRoutes(router)
 }
+
+// StartJobs launches the module's background jobs
+func (m *Module) StartJobs(ctx context.Context) {
+	go m.PurgeJob.Run(ctx)
+}
@@ -0,0 +1,62 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/malytinKonstantin/go-fiber/internal/privacy"
+	"github.com/malytinKonstantin/go-fiber/internal/storage"
+)
+
+// PrivacyParticipant exports and erases the personal data held by the user module
+type PrivacyParticipant struct {
+	service *UserService
+}
+
+func NewPrivacyParticipant(service *UserService) *PrivacyParticipant {
+	return &PrivacyParticipant{service: service}
+}
+
+func (p *PrivacyParticipant) Export(ctx context.Context, userID int32, w *privacy.ArchiveWriter) error {
+	user, err := p.service.GetUser(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.WriteJSON("profile.json", user); err != nil {
+		return err
+	}
+	settings, err := p.service.GetSettings(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteJSON("settings.json", settings); err != nil {
+		return err
+	}
+	return p.exportAvatar(ctx, user.AvatarKey, w)
+}
+
+// exportAvatar adds the largest thumbnail as avatar.jpg; the upload itself
+// is not kept
+func (p *PrivacyParticipant) exportAvatar(ctx context.Context, avatarKey string, w *privacy.ArchiveWriter) error {
+	if avatarKey == "" {
+		return nil
+	}
+	largest := avatarSizes[len(avatarSizes)-1]
+	avatar, err := p.service.blobs.Open(ctx, avatarBlobKey(avatarKey, largest))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer avatar.Close()
+	return w.WriteFile("avatar.jpg", avatar)
+}
+
+func (p *PrivacyParticipant) Erase(ctx context.Context, userID int32) error {
+	return p.service.AnonymizeUser(ctx, userID)
+}
@@ -0,0 +1,61 @@
+package user
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultPurgeRetention = 30 * 24 * time.Hour
+	defaultPurgeInterval  = time.Hour
+)
+
+// PurgeJob periodically deletes users that stayed soft-deleted
+// for longer than the retention period
+type PurgeJob struct {
+	service   *UserService
+	retention time.Duration
+	interval  time.Duration
+}
+
+// NewPurgeJob reads USER_PURGE_RETENTION and USER_PURGE_INTERVAL
+// as Go durations, e.g. 720h and 1h
+func NewPurgeJob(service *UserService) *PurgeJob {
+	retention := viper.GetDuration("USER_PURGE_RETENTION")
+	if retention <= 0 {
+		retention = defaultPurgeRetention
+	}
+	interval := viper.GetDuration("USER_PURGE_INTERVAL")
+	if interval <= 0 {
+		interval = defaultPurgeInterval
+	}
+	return &PurgeJob{
+		service:   service,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Run purges immediately and then on every interval until ctx is cancelled
+func (j *PurgeJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := j.service.PurgeDeletedUsers(ctx, j.retention)
+		if err != nil {
+			log.Printf("Failed to purge deleted users: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d deleted users", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -2,9 +2,12 @@ package user
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
 	"github.com/malytinKonstantin/go-fiber/internal/db"
 )
 
@@ -12,47 +15,204 @@ type User struct {
 	ID           int32  `json:"id"`
 	Username     string `json:"username"`
 	Email        string `json:"email"`
-	PasswordHash string `json:"password_hash"`
+	PasswordHash string `json:"-"`
 	FullName     string `json:"full_name"`
 	Bio          string `json:"bio"`
 	CreatedAt    string `json:"created_at"`
 	UpdatedAt    string `json:"updated_at"`
+	IsAdmin      bool   `json:"is_admin"`
+	AvatarKey    string `json:"-"`
+	TenantID     int32  `json:"-"`
+	// Avatar holds the thumbnail URLs; set by the service, nil without an avatar
+	Avatar AvatarURLs `json:"avatar,omitempty"`
 }
 
+// Repository stores users. Its methods behave like the sqlc queries behind
+// UserRepository: lookups and listings skip soft-deleted users, missing rows
+// are reported as sql.ErrNoRows and taken usernames or emails as unique
+// violations (see db.IsUniqueViolation). MemoryRepository implements it for
+// tests, and the conformance suite keeps the two in line.
+type Repository interface {
+	GetUser(ctx context.Context, id int32) (User, error)
+	GetUsersByIDs(ctx context.Context, ids []int32) (map[int32]User, error)
+	GetUserByUsername(ctx context.Context, username string) (User, error)
+	SearchUsers(ctx context.Context, q UserQuery) ([]User, error)
+	StreamUsers(ctx context.Context, q UserQuery, fn func(User) error) error
+	CountUsers(ctx context.Context, q UserQuery) (int64, error)
+	EstimateUsersCount(ctx context.Context) (int64, error)
+	CreateUser(ctx context.Context, params db.CreateUserParams) (User, error)
+	SetUserAdmin(ctx context.Context, id int32, isAdmin bool) (User, error)
+	UpdateUser(ctx context.Context, params db.UpdateUserParams) (User, error)
+	DeleteUser(ctx context.Context, id int32) error
+	RestoreUser(ctx context.Context, id int32) (User, error)
+	AnonymizeUser(ctx context.Context, id int32) error
+	ListTakenUsernamesAndEmails(ctx context.Context, usernames, emails []string) (map[string]bool, map[string]bool, error)
+	CopyUsers(ctx context.Context, users []db.CreateUserParams) ([]ImportedUser, error)
+	CopyUsersSkippingConflicts(ctx context.Context, users []db.CreateUserParams) ([]ImportedUser, error)
+	SetUserAvatar(ctx context.Context, id int32, avatarKey string) (User, error)
+	GetUserAvatarKey(ctx context.Context, id int32) (string, error)
+	GetUserTenant(ctx context.Context, id int32) (int32, error)
+	GetUserSettings(ctx context.Context, id int32) (map[string]any, error)
+	UpdateUserSettings(ctx context.Context, id int32, update func(overrides map[string]any) (map[string]any, error)) error
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) ([]PurgedUser, error)
+}
+
+// ImportedUser is a user inserted by a bulk import
+type ImportedUser struct {
+	ID       int32
+	TenantID int32
+	Username string
+}
+
+// PurgedUser is a user permanently deleted by PurgeDeletedUsers
+type PurgedUser struct {
+	ID        int32
+	TenantID  int32
+	AvatarKey string
+}
+
+// UserRepository is the Repository backed by Postgres
 type UserRepository struct {
-	q *db.Queries
+	db *db.DB
+	tx *db.TxManager
+	q  *db.Queries
+	// reads serves the lookups that may come from a read replica
+	reads *db.Queries
 }
 
-func NewUserRepository(dbConn *pgxpool.Pool) *UserRepository {
+func NewUserRepository(database *db.DB, tx *db.TxManager) *UserRepository {
 	return &UserRepository{
-		q: db.New(stdlib.OpenDBFromPool(dbConn)),
+		db:    database,
+		tx:    tx,
+		q:     database.Queries(),
+		reads: database.ReadQueries(),
 	}
 }
 
 func (r *UserRepository) GetUser(ctx context.Context, id int32) (User, error) {
-	dbUser, err := r.q.GetUser(ctx, id)
+	dbUser, err := r.reads.GetUser(ctx, id)
 	if err != nil {
 		return User{}, err
 	}
 	return convertDbUserToUser(dbUser), nil
 }
 
+// GetUsersByIDs returns the active users among ids, keyed by ID
+func (r *UserRepository) GetUsersByIDs(ctx context.Context, ids []int32) (map[int32]User, error) {
+	dbUsers, err := r.q.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	users := make(map[int32]User, len(dbUsers))
+	for _, dbUser := range dbUsers {
+		users[dbUser.ID] = convertDbUserToUser(dbUser)
+	}
+	return users, nil
+}
+
 func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (User, error) {
-	dbUser, err := r.q.GetUserByUsername(ctx, username)
+	dbUser, err := r.reads.GetUserByUsername(ctx, username)
 	if err != nil {
 		return User{}, err
 	}
 	return convertDbUserToUser(dbUser), nil
 }
 
-func (r *UserRepository) SearchUsers(ctx context.Context, params db.SearchUsersParams) ([]User, error) {
-	dbUsers, err := r.q.SearchUsers(ctx, params)
+// SearchUsers selects only the columns requested in q.Fields;
+// the remaining User fields are left empty
+func (r *UserRepository) SearchUsers(ctx context.Context, q UserQuery) ([]User, error) {
+	columns := selectedUserColumns(q.Fields)
+	query, args := buildSearchUsersSQL(q, columns)
+
+	rows, err := r.db.Reader().QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	dbUsers := []db.Users{}
+	for rows.Next() {
+		var dbUser db.Users
+		targets := make([]any, len(columns))
+		for i, column := range columns {
+			targets[i] = column.target(&dbUser)
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		dbUsers = append(dbUsers, dbUser)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 	return convertDbUsersToUsers(dbUsers), nil
 }
 
+// exportFetchSize is the number of rows fetched from the cursor at a time
+const exportFetchSize = 500
+
+// StreamUsers passes every user matching q to fn without holding the result
+// in memory. Rows are read through a server-side cursor in a read-only
+// transaction, so fn sees a consistent snapshot. Like SearchUsers, only the
+// columns in q.Fields are selected.
+func (r *UserRepository) StreamUsers(ctx context.Context, q UserQuery, fn func(User) error) error {
+	columns := selectedUserColumns(q.Fields)
+	query, args := buildSearchUsersSQL(q, columns)
+
+	return r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := r.db.ExecContext(ctx, "DECLARE users_export NO SCROLL CURSOR FOR "+query, args...); err != nil {
+			return err
+		}
+		fetch := "FETCH FORWARD " + strconv.Itoa(exportFetchSize) + " FROM users_export"
+		for {
+			fetched, err := r.fetchUsers(ctx, fetch, columns, fn)
+			if err != nil {
+				return err
+			}
+			if fetched < exportFetchSize {
+				return nil
+			}
+		}
+	}, db.ReadOnly())
+}
+
+// fetchUsers passes the rows of one FETCH to fn and returns their count
+func (r *UserRepository) fetchUsers(ctx context.Context, fetch string, columns []userColumn, fn func(User) error) (int, error) {
+	rows, err := r.db.QueryContext(ctx, fetch)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	fetched := 0
+	for rows.Next() {
+		var dbUser db.Users
+		targets := make([]any, len(columns))
+		for i, column := range columns {
+			targets[i] = column.target(&dbUser)
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return fetched, err
+		}
+		fetched++
+		if err := fn(convertDbUserToUser(dbUser)); err != nil {
+			return fetched, err
+		}
+	}
+	return fetched, rows.Err()
+}
+
+func (r *UserRepository) CountUsers(ctx context.Context, q UserQuery) (int64, error) {
+	query, args := buildCountUsersSQL(q)
+	var total int64
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&total)
+	return total, err
+}
+
+func (r *UserRepository) EstimateUsersCount(ctx context.Context) (int64, error) {
+	return r.q.EstimateUsersCount(ctx)
+}
+
 func (r *UserRepository) CreateUser(ctx context.Context, params db.CreateUserParams) (User, error) {
 	dbUser, err := r.q.CreateUser(ctx, params)
 	if err != nil {
@@ -61,6 +221,14 @@ func (r *UserRepository) CreateUser(ctx context.Context, params db.CreateUserPar
 	return convertDbUserToUser(dbUser), nil
 }
 
+func (r *UserRepository) SetUserAdmin(ctx context.Context, id int32, isAdmin bool) (User, error) {
+	dbUser, err := r.q.SetUserAdmin(ctx, db.SetUserAdminParams{ID: id, IsAdmin: isAdmin})
+	if err != nil {
+		return User{}, err
+	}
+	return convertDbUserToUser(dbUser), nil
+}
+
 func (r *UserRepository) UpdateUser(ctx context.Context, params db.UpdateUserParams) (User, error) {
 	dbUser, err := r.q.UpdateUser(ctx, params)
 	if err != nil {
@@ -73,6 +241,180 @@ func (r *UserRepository) DeleteUser(ctx context.Context, id int32) error {
 	return r.q.DeleteUser(ctx, id)
 }
 
+func (r *UserRepository) RestoreUser(ctx context.Context, id int32) (User, error) {
+	dbUser, err := r.q.RestoreUser(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+	return convertDbUserToUser(dbUser), nil
+}
+
+// AnonymizeUser overwrites personal data of the user in place, keeping the row
+func (r *UserRepository) AnonymizeUser(ctx context.Context, id int32) error {
+	return r.q.AnonymizeUser(ctx, id)
+}
+
+// ListTakenUsernamesAndEmails returns which of the given usernames and emails
+// belong to active users
+func (r *UserRepository) ListTakenUsernamesAndEmails(ctx context.Context, usernames, emails []string) (map[string]bool, map[string]bool, error) {
+	rows, err := r.q.ListTakenUsernamesAndEmails(ctx, db.ListTakenUsernamesAndEmailsParams{
+		Usernames: usernames,
+		Emails:    emails,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	takenUsernames := make(map[string]bool, len(rows))
+	takenEmails := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		takenUsernames[row.Username] = true
+		takenEmails[row.Email] = true
+	}
+	return takenUsernames, takenEmails, nil
+}
+
+var importUserColumns = []string{"username", "email", "password_hash", "full_name", "bio"}
+
+func importUserRows(users []db.CreateUserParams) pgx.CopyFromSource {
+	return pgx.CopyFromSlice(len(users), func(i int) ([]any, error) {
+		u := users[i]
+		return []any{u.Username, u.Email, u.PasswordHash, u.FullName, u.Bio}, nil
+	})
+}
+
+// CopyUsers inserts all users with COPY in one transaction.
+// A single conflicting row fails the whole batch. COPY returns no rows, so
+// the inserted users are selected by username in the same transaction; the
+// usernames of active users are unique within the tenant.
+func (r *UserRepository) CopyUsers(ctx context.Context, users []db.CreateUserParams) ([]ImportedUser, error) {
+	var imported []ImportedUser
+	err := r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		return r.db.WithPgxConn(ctx, func(conn *pgx.Conn) error {
+			if _, err := conn.CopyFrom(ctx, pgx.Identifier{"users"}, importUserColumns, importUserRows(users)); err != nil {
+				return err
+			}
+
+			usernames := make([]string, len(users))
+			for i, u := range users {
+				usernames[i] = u.Username
+			}
+			rows, err := conn.Query(ctx, `SELECT id, tenant_id, username FROM users
+				WHERE username = ANY($1) AND deleted_at IS NULL AND tenant_id = current_tenant_id()
+				ORDER BY id`, usernames)
+			if err != nil {
+				return err
+			}
+			imported, err = pgx.CollectRows(rows, pgx.RowToStructByPos[ImportedUser])
+			return err
+		})
+	})
+	return imported, err
+}
+
+// CopyUsersSkippingConflicts copies the users into a temporary table and moves
+// them into users, skipping rows that violate a unique index. Returns the
+// inserted users.
+func (r *UserRepository) CopyUsersSkippingConflicts(ctx context.Context, users []db.CreateUserParams) ([]ImportedUser, error) {
+	var imported []ImportedUser
+	err := r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		return r.db.WithPgxConn(ctx, func(conn *pgx.Conn) error {
+			_, err := conn.Exec(ctx, `CREATE TEMP TABLE users_import (
+				username VARCHAR(50),
+				email VARCHAR(100),
+				password_hash VARCHAR(255),
+				full_name VARCHAR(100),
+				bio TEXT
+			) ON COMMIT DROP`)
+			if err != nil {
+				return err
+			}
+			if _, err := conn.CopyFrom(ctx, pgx.Identifier{"users_import"}, importUserColumns, importUserRows(users)); err != nil {
+				return err
+			}
+
+			rows, err := conn.Query(ctx, `INSERT INTO users (username, email, password_hash, full_name, bio)
+				SELECT username, email, password_hash, full_name, bio FROM users_import
+				ON CONFLICT DO NOTHING
+				RETURNING id, tenant_id, username`)
+			if err != nil {
+				return err
+			}
+			imported, err = pgx.CollectRows(rows, pgx.RowToStructByPos[ImportedUser])
+			return err
+		})
+	})
+	return imported, err
+}
+
+// SetUserAvatar stores the avatar key of an active user; an empty key removes the avatar
+func (r *UserRepository) SetUserAvatar(ctx context.Context, id int32, avatarKey string) (User, error) {
+	dbUser, err := r.q.SetUserAvatar(ctx, db.SetUserAvatarParams{
+		ID:        id,
+		AvatarKey: sql.NullString{String: avatarKey, Valid: avatarKey != ""},
+	})
+	if err != nil {
+		return User{}, err
+	}
+	return convertDbUserToUser(dbUser), nil
+}
+
+// GetUserAvatarKey returns the avatar key of any user, including deleted ones
+func (r *UserRepository) GetUserAvatarKey(ctx context.Context, id int32) (string, error) {
+	key, err := r.q.GetUserAvatarKey(ctx, id)
+	return key.String, err
+}
+
+// GetUserTenant returns the tenant of any user, including deleted ones
+func (r *UserRepository) GetUserTenant(ctx context.Context, id int32) (int32, error) {
+	return r.q.GetUserTenant(ctx, id)
+}
+
+// GetUserSettings returns the settings the user has changed from the defaults
+func (r *UserRepository) GetUserSettings(ctx context.Context, id int32) (map[string]any, error) {
+	raw, err := r.q.GetUserSettings(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return parseSettingsOverrides(raw)
+}
+
+// UpdateUserSettings replaces the stored settings with the result of update,
+// holding a row lock so that concurrent updates are applied one after another
+func (r *UserRepository) UpdateUserSettings(ctx context.Context, id int32, update func(overrides map[string]any) (map[string]any, error)) error {
+	return r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		raw, err := r.q.GetUserSettingsForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		overrides, err := parseSettingsOverrides(raw)
+		if err != nil {
+			return err
+		}
+		overrides, err = update(overrides)
+		if err != nil {
+			return err
+		}
+		raw, err = json.Marshal(overrides)
+		if err != nil {
+			return err
+		}
+		return r.q.SetUserSettings(ctx, db.SetUserSettingsParams{ID: id, Settings: raw})
+	})
+}
+
+// PurgeDeletedUsers returns the purged users with their tenants and avatar keys
+func (r *UserRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) ([]PurgedUser, error) {
+	rows, err := r.q.PurgeDeletedUsers(ctx, sql.NullTime{Time: deletedBefore, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	purged := make([]PurgedUser, len(rows))
+	for i, row := range rows {
+		purged[i] = PurgedUser{ID: row.ID, TenantID: row.TenantID, AvatarKey: row.AvatarKey.String}
+	}
+	return purged, nil
+}
+
 func convertDbUserToUser(dbUser db.Users) User {
 	var createdAtStr string = ""
 	if dbUser.CreatedAt != nil && *dbUser.CreatedAt != nil {
@@ -93,6 +435,9 @@ func convertDbUserToUser(dbUser db.Users) User {
 		Bio:          dbUser.Bio.String,
 		CreatedAt:    createdAtStr,
 		UpdatedAt:    updatedAtStr,
+		IsAdmin:      dbUser.IsAdmin,
+		AvatarKey:    dbUser.AvatarKey.String,
+		TenantID:     dbUser.TenantID,
 	}
 }
 
@@ -103,3 +448,31 @@ func convertDbUsersToUsers(dbUsers []db.Users) []User {
 	}
 	return users
 }
+
+// projectUser renders only the given public fields of a user
+func projectUser(user User, fields []string) map[string]any {
+	item := make(map[string]any, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "id":
+			item[field] = user.ID
+		case "username":
+			item[field] = user.Username
+		case "email":
+			item[field] = user.Email
+		case "full_name":
+			item[field] = user.FullName
+		case "bio":
+			item[field] = user.Bio
+		case "created_at":
+			item[field] = user.CreatedAt
+		case "updated_at":
+			item[field] = user.UpdatedAt
+		case "is_admin":
+			item[field] = user.IsAdmin
+		case "avatar":
+			item[field] = user.Avatar
+		}
+	}
+	return item
+}
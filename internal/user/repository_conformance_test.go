@@ -0,0 +1,506 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/shared"
+)
+
+// Tenants that every repository given to the conformance suite must accept
+const (
+	conformanceTenantA int32 = 1
+	conformanceTenantB int32 = 2
+)
+
+// RunRepositoryConformance checks that a Repository behaves like the sqlc
+// queries on Postgres. newRepository is called for every subtest and must
+// return a repository without users whose tenants include
+// conformanceTenantA and conformanceTenantB, the tenants 1 and 2. It is
+// exported for the Postgres run, which lives in package user_test.
+func RunRepositoryConformance(t *testing.T, newRepository func(t *testing.T) Repository) {
+	tenantA := db.WithTenant(context.Background(), conformanceTenantA)
+	tenantB := db.WithTenant(context.Background(), conformanceTenantB)
+	allTenants := db.WithAllTenants(context.Background())
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		repo := newRepository(t)
+		created := mustCreateUser(t, repo, tenantA, userParams("alice", "Alice Smith", "likes go"))
+		if created.ID == 0 || created.TenantID != conformanceTenantA || created.CreatedAt == "" || created.IsAdmin {
+			t.Fatalf("unexpected defaults: %+v", created)
+		}
+
+		byID, err := repo.GetUser(tenantA, created.ID)
+		if err != nil || !reflect.DeepEqual(byID, created) {
+			t.Fatalf("GetUser = %+v, %v; want %+v", byID, err, created)
+		}
+		byName, err := repo.GetUserByUsername(tenantA, "alice")
+		if err != nil || !reflect.DeepEqual(byName, created) {
+			t.Fatalf("GetUserByUsername = %+v, %v; want %+v", byName, err, created)
+		}
+		if _, err := repo.GetUser(tenantA, created.ID+1000); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetUser of a missing user: %v, want sql.ErrNoRows", err)
+		}
+		if _, err := repo.GetUserByUsername(tenantA, "nobody"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetUserByUsername of a missing user: %v, want sql.ErrNoRows", err)
+		}
+
+		bob := mustCreateUser(t, repo, tenantA, userParams("bob", "", ""))
+		users, err := repo.GetUsersByIDs(tenantA, []int32{created.ID, bob.ID, bob.ID + 1000})
+		if err != nil || len(users) != 2 || users[bob.ID].Username != "bob" {
+			t.Fatalf("GetUsersByIDs = %v, %v", users, err)
+		}
+	})
+
+	t.Run("UniqueAmongActiveUsersOfTenant", func(t *testing.T) {
+		repo := newRepository(t)
+		alice := mustCreateUser(t, repo, tenantA, userParams("alice", "", ""))
+
+		sameUsername := userParams("alice", "", "")
+		sameUsername.Email = "other@example.com"
+		if _, err := repo.CreateUser(tenantA, sameUsername); !db.IsUniqueViolation(err) {
+			t.Fatalf("duplicate username: %v, want a unique violation", err)
+		}
+		sameEmail := userParams("alice2", "", "")
+		sameEmail.Email = alice.Email
+		if _, err := repo.CreateUser(tenantA, sameEmail); !db.IsUniqueViolation(err) {
+			t.Fatalf("duplicate email: %v, want a unique violation", err)
+		}
+		mustCreateUser(t, repo, tenantB, userParams("alice", "", ""))
+
+		if err := repo.DeleteUser(tenantA, alice.ID); err != nil {
+			t.Fatal(err)
+		}
+		reused := mustCreateUser(t, repo, tenantA, userParams("alice", "", ""))
+		if _, err := repo.RestoreUser(tenantA, alice.ID); !db.IsUniqueViolation(err) {
+			t.Fatalf("restore of a taken username: %v, want a unique violation", err)
+		}
+
+		bob := mustCreateUser(t, repo, tenantA, userParams("bob", "", ""))
+		if _, err := repo.UpdateUser(tenantA, db.UpdateUserParams{ID: bob.ID, Username: reused.Username}); !db.IsUniqueViolation(err) {
+			t.Fatalf("rename to a taken username: %v, want a unique violation", err)
+		}
+	})
+
+	t.Run("TenantIsolation", func(t *testing.T) {
+		repo := newRepository(t)
+		alice := mustCreateUser(t, repo, tenantA, userParams("alice", "", ""))
+		mustCreateUser(t, repo, tenantB, userParams("bob", "", ""))
+
+		if _, err := repo.GetUser(tenantB, alice.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetUser from another tenant: %v, want sql.ErrNoRows", err)
+		}
+		if _, err := repo.GetUser(context.Background(), alice.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetUser without a tenant: %v, want sql.ErrNoRows", err)
+		}
+		if _, err := repo.UpdateUser(tenantB, db.UpdateUserParams{ID: alice.ID, Bio: validString("x")}); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("UpdateUser from another tenant: %v, want sql.ErrNoRows", err)
+		}
+		assertUsernames(t, repo, tenantA, UserQuery{}, "alice")
+		assertUsernames(t, repo, allTenants, UserQuery{}, "alice", "bob")
+
+		_, err := repo.CreateUser(context.Background(), userParams("carol", "", ""))
+		if err == nil || db.IsUniqueViolation(err) {
+			t.Fatalf("CreateUser without a tenant: %v, want a constraint error", err)
+		}
+	})
+
+	t.Run("UpdateUser", func(t *testing.T) {
+		repo := newRepository(t)
+		alice := mustCreateUser(t, repo, tenantA, userParams("alice", "Alice Smith", ""))
+
+		updated, err := repo.UpdateUser(tenantA, db.UpdateUserParams{ID: alice.ID, Bio: validString("likes go")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Username != "alice" || updated.Email != alice.Email || updated.PasswordHash != alice.PasswordHash ||
+			updated.FullName != "Alice Smith" || updated.Bio != "likes go" {
+			t.Fatalf("partial update changed other fields: %+v", updated)
+		}
+
+		updated, err = repo.UpdateUser(tenantA, db.UpdateUserParams{ID: alice.ID, Username: "alicia", PasswordHash: "new-hash"})
+		if err != nil || updated.Username != "alicia" || updated.PasswordHash != "new-hash" || updated.Bio != "likes go" {
+			t.Fatalf("UpdateUser = %+v, %v", updated, err)
+		}
+
+		admin, err := repo.SetUserAdmin(tenantA, alice.ID, true)
+		if err != nil || !admin.IsAdmin {
+			t.Fatalf("SetUserAdmin = %+v, %v", admin, err)
+		}
+		if _, err := repo.UpdateUser(tenantA, db.UpdateUserParams{ID: alice.ID + 1000, Bio: validString("x")}); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("UpdateUser of a missing user: %v, want sql.ErrNoRows", err)
+		}
+	})
+
+	t.Run("SoftDeleteAndRestore", func(t *testing.T) {
+		repo := newRepository(t)
+		alice := mustCreateUser(t, repo, tenantA, userParams("alice", "", ""))
+		if _, err := repo.SetUserAvatar(tenantA, alice.ID, "avatars/1/abc"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.DeleteUser(tenantA, alice.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetUser(tenantA, alice.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetUser of a deleted user: %v, want sql.ErrNoRows", err)
+		}
+		if _, err := repo.SetUserAvatar(tenantA, alice.ID, ""); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("SetUserAvatar of a deleted user: %v, want sql.ErrNoRows", err)
+		}
+		if key, err := repo.GetUserAvatarKey(tenantA, alice.ID); err != nil || key != "avatars/1/abc" {
+			t.Fatalf("GetUserAvatarKey of a deleted user = %q, %v", key, err)
+		}
+		if err := repo.DeleteUser(tenantA, alice.ID); err != nil {
+			t.Fatalf("deleting twice: %v", err)
+		}
+		assertUsernames(t, repo, tenantA, UserQuery{})
+
+		restored, err := repo.RestoreUser(tenantA, alice.ID)
+		if err != nil || restored.Username != "alice" {
+			t.Fatalf("RestoreUser = %+v, %v", restored, err)
+		}
+		if _, err := repo.RestoreUser(tenantA, alice.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("RestoreUser of an active user: %v, want sql.ErrNoRows", err)
+		}
+		if _, err := repo.GetUserAvatarKey(tenantA, alice.ID+1000); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetUserAvatarKey of a missing user: %v, want sql.ErrNoRows", err)
+		}
+	})
+
+	t.Run("AnonymizeUser", func(t *testing.T) {
+		repo := newRepository(t)
+		alice := mustCreateUser(t, repo, tenantA, userParams("alice", "Alice Smith", "likes go"))
+		if _, err := repo.SetUserAvatar(tenantA, alice.ID, "avatars/1/abc"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.AnonymizeUser(tenantA, alice.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetUser(tenantA, alice.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetUser of an anonymized user: %v, want sql.ErrNoRows", err)
+		}
+		if key, err := repo.GetUserAvatarKey(tenantA, alice.ID); err != nil || key != "" {
+			t.Fatalf("GetUserAvatarKey of an anonymized user = %q, %v", key, err)
+		}
+		if tenantID, err := repo.GetUserTenant(tenantA, alice.ID); err != nil || tenantID != conformanceTenantA {
+			t.Fatalf("GetUserTenant of an anonymized user = %d, %v", tenantID, err)
+		}
+		if _, err := repo.GetUserTenant(tenantB, alice.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetUserTenant from another tenant: %v, want sql.ErrNoRows", err)
+		}
+		mustCreateUser(t, repo, tenantA, userParams("alice", "", ""))
+		if err := repo.AnonymizeUser(tenantA, alice.ID+1000); err != nil {
+			t.Fatalf("AnonymizeUser of a missing user: %v", err)
+		}
+	})
+
+	t.Run("SearchFilters", func(t *testing.T) {
+		repo := newRepository(t)
+		seedSearchUsers(t, repo, tenantA)
+
+		assertUsernames(t, repo, tenantA, UserQuery{Username: "LI"}, "alice")
+		assertUsernames(t, repo, tenantA, UserQuery{FullName: "smith"}, "alice", "carol")
+		assertUsernames(t, repo, tenantA, UserQuery{Bio: "go"}, "alice", "dave")
+		assertUsernames(t, repo, tenantA, UserQuery{Email: "b%b"}, "bob")
+
+		yesterday := time.Now().AddDate(0, 0, -1)
+		twoDaysAgo := time.Now().AddDate(0, 0, -2)
+		assertUsernames(t, repo, tenantA, UserQuery{CreatedFrom: &yesterday}, "alice", "bob", "carol", "dave")
+		assertUsernames(t, repo, tenantA, UserQuery{CreatedTo: &twoDaysAgo})
+
+		filter := func(field string, op string, value any) UserQuery {
+			return UserQuery{Filters: []shared.FilterCondition{{Field: field, Column: AdminUserFilterSchema[field].Column, Op: op, Value: value}}}
+		}
+		assertUsernames(t, repo, tenantA, filter("is_admin", shared.OpEq, true), "carol")
+		assertUsernames(t, repo, tenantA, filter("username", shared.OpGt, "b"), "bob", "carol", "dave")
+		assertUsernames(t, repo, tenantA, filter("username", shared.OpIn, []string{"alice", "dave", "nobody"}), "alice", "dave")
+		assertUsernames(t, repo, tenantA, filter("username", shared.OpNin, []string{"alice", "dave"}), "bob", "carol")
+		assertUsernames(t, repo, tenantA, filter("full_name", shared.OpNe, "Alice Smith"), "carol", "dave")
+		assertUsernames(t, repo, tenantA, filter("bio", shared.OpNull, true), "bob")
+		assertUsernames(t, repo, tenantA, filter("bio", shared.OpContains, "GO"), "alice", "dave")
+		assertUsernames(t, repo, tenantA, filter("bio", shared.OpContains, "100%"), "dave")
+		assertUsernames(t, repo, tenantA, filter("bio", shared.OpContains, "1_0"))
+		assertUsernames(t, repo, tenantA, filter("created_at", shared.OpGte, yesterday), "alice", "bob", "carol", "dave")
+
+		alice, err := repo.GetUserByUsername(tenantA, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertUsernames(t, repo, tenantA, filter("id", shared.OpLte, int64(alice.ID)), "alice")
+
+		total, err := repo.CountUsers(tenantA, UserQuery{FullName: "smith", Limit: 1})
+		if err != nil || total != 2 {
+			t.Fatalf("CountUsers = %d, %v; want 2", total, err)
+		}
+		if _, err := repo.EstimateUsersCount(tenantA); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("FullTextSearch", func(t *testing.T) {
+		repo := newRepository(t)
+		seedSearchUsers(t, repo, tenantA)
+
+		search := func(text string) UserQuery {
+			return UserQuery{Search: text, SearchConfig: SearchConfigSimple, SearchQuery: buildPrefixTSQuery(text)}
+		}
+		assertUsernames(t, repo, tenantA, search("alice"), "alice")
+		assertUsernames(t, repo, tenantA, search("smi"), "alice", "carol")
+		assertUsernames(t, repo, tenantA, search("carol smith"), "carol")
+		assertUsernames(t, repo, tenantA, search("zzzz"))
+	})
+
+	t.Run("SortingAndPagination", func(t *testing.T) {
+		repo := newRepository(t)
+		seedSearchUsers(t, repo, tenantA)
+
+		sortBy := func(field string, desc bool, nulls string) []shared.SortKey {
+			return []shared.SortKey{{Field: field, Column: UserSortSchema[field], Desc: desc, Nulls: nulls}}
+		}
+		assertUsernamesInOrder(t, repo, UserQuery{}, "alice", "bob", "carol", "dave")
+		assertUsernamesInOrder(t, repo, UserQuery{Sort: sortBy("username", true, "")}, "dave", "carol", "bob", "alice")
+		assertUsernamesInOrder(t, repo, UserQuery{Sort: sortBy("full_name", false, "")}, "alice", "carol", "dave", "bob")
+		assertUsernamesInOrder(t, repo, UserQuery{Sort: sortBy("full_name", true, "")}, "bob", "dave", "carol", "alice")
+		assertUsernamesInOrder(t, repo, UserQuery{Sort: sortBy("full_name", true, shared.NullsLast)}, "dave", "carol", "alice", "bob")
+		assertUsernamesInOrder(t, repo, UserQuery{Sort: sortBy("full_name", false, shared.NullsFirst)}, "bob", "alice", "carol", "dave")
+		assertUsernamesInOrder(t, repo, UserQuery{Limit: 2, Offset: 1}, "bob", "carol")
+		assertUsernamesInOrder(t, repo, UserQuery{Offset: 10})
+
+		users, err := repo.SearchUsers(tenantA, UserQuery{Fields: []string{"username"}, Limit: 1})
+		if err != nil || len(users) != 1 {
+			t.Fatalf("SearchUsers = %v, %v", users, err)
+		}
+		if users[0].ID == 0 || users[0].Username != "alice" || users[0].Email != "" || users[0].CreatedAt != "" {
+			t.Fatalf("SearchUsers selected more than id and username: %+v", users[0])
+		}
+
+		var streamed []string
+		err = repo.StreamUsers(tenantA, UserQuery{Sort: sortBy("username", true, "")}, func(user User) error {
+			streamed = append(streamed, user.Username)
+			return nil
+		})
+		if err != nil || !slices.Equal(streamed, []string{"dave", "carol", "bob", "alice"}) {
+			t.Fatalf("StreamUsers = %v, %v", streamed, err)
+		}
+		errStop := errors.New("stop")
+		if err := repo.StreamUsers(tenantA, UserQuery{}, func(User) error { return errStop }); !errors.Is(err, errStop) {
+			t.Fatalf("StreamUsers did not return the error of fn: %v", err)
+		}
+	})
+
+	t.Run("BulkImport", func(t *testing.T) {
+		repo := newRepository(t)
+		mustCreateUser(t, repo, tenantA, userParams("alice", "", ""))
+
+		taken, takenEmails, err := repo.ListTakenUsernamesAndEmails(tenantA, []string{"alice", "bob"}, []string{"nobody@example.com"})
+		if err != nil || !taken["alice"] || taken["bob"] || !takenEmails["alice@example.com"] {
+			t.Fatalf("ListTakenUsernamesAndEmails = %v, %v, %v", taken, takenEmails, err)
+		}
+
+		batch := []db.CreateUserParams{userParams("bob", "", ""), userParams("alice", "", "")}
+		if _, err := repo.CopyUsers(tenantA, batch); !db.IsUniqueViolation(err) {
+			t.Fatalf("CopyUsers with a conflict: %v, want a unique violation", err)
+		}
+		assertUsernames(t, repo, tenantA, UserQuery{}, "alice")
+
+		batch = append(batch, userParams("carol", "", ""), userParams("carol", "", ""))
+		inserted, err := repo.CopyUsersSkippingConflicts(tenantA, batch)
+		if err != nil || !slices.Equal(importedUsernames(t, repo, inserted), []string{"bob", "carol"}) {
+			t.Fatalf("CopyUsersSkippingConflicts = %+v, %v", inserted, err)
+		}
+		copied, err := repo.CopyUsers(tenantA, []db.CreateUserParams{userParams("dave", "", ""), userParams("erin", "", "")})
+		if err != nil || !slices.Equal(importedUsernames(t, repo, copied), []string{"dave", "erin"}) {
+			t.Fatalf("CopyUsers = %+v, %v", copied, err)
+		}
+		assertUsernames(t, repo, tenantA, UserQuery{}, "alice", "bob", "carol", "dave", "erin")
+	})
+
+	t.Run("Settings", func(t *testing.T) {
+		repo := newRepository(t)
+		alice := mustCreateUser(t, repo, tenantA, userParams("alice", "", ""))
+
+		settings, err := repo.GetUserSettings(tenantA, alice.ID)
+		if err != nil || len(settings) != 0 {
+			t.Fatalf("GetUserSettings of a new user = %v, %v", settings, err)
+		}
+		err = repo.UpdateUserSettings(tenantA, alice.ID, func(overrides map[string]any) (map[string]any, error) {
+			overrides["theme"] = "dark"
+			return overrides, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		settings, err = repo.GetUserSettings(tenantA, alice.ID)
+		if err != nil || settings["theme"] != "dark" {
+			t.Fatalf("GetUserSettings = %v, %v", settings, err)
+		}
+
+		errRejected := errors.New("rejected")
+		err = repo.UpdateUserSettings(tenantA, alice.ID, func(map[string]any) (map[string]any, error) {
+			return nil, errRejected
+		})
+		if !errors.Is(err, errRejected) {
+			t.Fatalf("UpdateUserSettings did not return the error of update: %v", err)
+		}
+		if _, err := repo.GetUserSettings(tenantB, alice.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("GetUserSettings from another tenant: %v, want sql.ErrNoRows", err)
+		}
+	})
+
+	t.Run("PurgeDeletedUsers", func(t *testing.T) {
+		repo := newRepository(t)
+		alice := mustCreateUser(t, repo, tenantA, userParams("alice", "", ""))
+		bob := mustCreateUser(t, repo, tenantA, userParams("bob", "", ""))
+		if _, err := repo.SetUserAvatar(tenantA, alice.ID, "avatars/1/abc"); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []int32{alice.ID, bob.ID} {
+			if err := repo.DeleteUser(tenantA, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		purged, err := repo.PurgeDeletedUsers(tenantA, time.Now().Add(-time.Hour))
+		if err != nil || len(purged) != 0 {
+			t.Fatalf("PurgeDeletedUsers of recently deleted users = %+v, %v", purged, err)
+		}
+		purged, err = repo.PurgeDeletedUsers(tenantA, time.Now().Add(time.Hour))
+		slices.SortFunc(purged, func(a, b PurgedUser) int { return int(a.ID - b.ID) })
+		want := []PurgedUser{
+			{ID: alice.ID, TenantID: conformanceTenantA, AvatarKey: "avatars/1/abc"},
+			{ID: bob.ID, TenantID: conformanceTenantA},
+		}
+		if err != nil || !slices.Equal(purged, want) {
+			t.Fatalf("PurgeDeletedUsers = %+v, %v, want %+v", purged, err, want)
+		}
+		if _, err := repo.RestoreUser(tenantA, alice.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("RestoreUser of a purged user: %v, want sql.ErrNoRows", err)
+		}
+	})
+
+	t.Run("ConcurrentCreates", func(t *testing.T) {
+		repo := newRepository(t)
+		const writers = 8
+		var wg sync.WaitGroup
+		errs := make([]error, writers)
+		for i := range writers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = repo.CreateUser(tenantA, userParams("alice", "", ""))
+			}()
+		}
+		wg.Wait()
+
+		created := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				created++
+			case !db.IsUniqueViolation(err):
+				t.Fatalf("concurrent create: %v", err)
+			}
+		}
+		if created != 1 {
+			t.Fatalf("%d concurrent creates of the same user succeeded, want 1", created)
+		}
+	})
+}
+
+// seedSearchUsers creates, in this order:
+// alice "Alice Smith" "likes go", bob without name and bio,
+// carol "Carol Smith" (admin) "rust", dave "Dave Brown" "go 100% of the time"
+func seedSearchUsers(t *testing.T, repo Repository, ctx context.Context) {
+	t.Helper()
+	mustCreateUser(t, repo, ctx, userParams("alice", "Alice Smith", "likes go"))
+	mustCreateUser(t, repo, ctx, userParams("bob", "", ""))
+	carol := mustCreateUser(t, repo, ctx, userParams("carol", "Carol Smith", "rust"))
+	if _, err := repo.SetUserAdmin(ctx, carol.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	mustCreateUser(t, repo, ctx, userParams("dave", "Dave Brown", "go 100% of the time"))
+}
+
+func userParams(username, fullName, bio string) db.CreateUserParams {
+	return db.CreateUserParams{
+		Username:     username,
+		Email:        username + "@example.com",
+		PasswordHash: "hash-of-" + username,
+		FullName:     sql.NullString{String: fullName, Valid: fullName != ""},
+		Bio:          sql.NullString{String: bio, Valid: bio != ""},
+	}
+}
+
+func validString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: true}
+}
+
+func mustCreateUser(t *testing.T, repo Repository, ctx context.Context, params db.CreateUserParams) User {
+	t.Helper()
+	user, err := repo.CreateUser(ctx, params)
+	if err != nil {
+		t.Fatalf("CreateUser(%s): %v", params.Username, err)
+	}
+	return user
+}
+
+// assertUsernames checks the users matching q regardless of their order
+func assertUsernames(t *testing.T, repo Repository, ctx context.Context, q UserQuery, want ...string) {
+	t.Helper()
+	got := searchUsernames(t, repo, ctx, q)
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Fatalf("users matching %+v = %v, want %v", q, got, want)
+	}
+	total, err := repo.CountUsers(ctx, q)
+	if err != nil || total != int64(len(want)) {
+		t.Fatalf("CountUsers(%+v) = %d, %v; want %d", q, total, err, len(want))
+	}
+}
+
+// assertUsernamesInOrder checks the users of conformanceTenantA listed by q
+func assertUsernamesInOrder(t *testing.T, repo Repository, q UserQuery, want ...string) {
+	t.Helper()
+	ctx := db.WithTenant(context.Background(), conformanceTenantA)
+	if got := searchUsernames(t, repo, ctx, q); !slices.Equal(got, want) {
+		t.Fatalf("users listed by %+v = %v, want %v", q, got, want)
+	}
+}
+
+func searchUsernames(t *testing.T, repo Repository, ctx context.Context, q UserQuery) []string {
+	t.Helper()
+	users, err := repo.SearchUsers(ctx, q)
+	if err != nil {
+		t.Fatalf("SearchUsers(%+v): %v", q, err)
+	}
+	usernames := make([]string, len(users))
+	for i, user := range users {
+		usernames[i] = user.Username
+	}
+	return usernames
+}
+
+// importedUsernames checks that the imported users are stored in
+// conformanceTenantA under their IDs and returns their usernames
+func importedUsernames(t *testing.T, repo Repository, imported []ImportedUser) []string {
+	t.Helper()
+	ctx := db.WithTenant(context.Background(), conformanceTenantA)
+	usernames := make([]string, len(imported))
+	for i, user := range imported {
+		stored, err := repo.GetUser(ctx, user.ID)
+		if err != nil || stored.Username != user.Username || user.TenantID != conformanceTenantA {
+			t.Fatalf("imported %+v is stored as %+v, %v", user, stored, err)
+		}
+		usernames[i] = user.Username
+	}
+	return usernames
+}
@@ -0,0 +1,50 @@
+package user_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/testutil"
+	"github.com/malytinKonstantin/go-fiber/internal/user"
+)
+
+func TestMain(m *testing.M) {
+	testutil.Main(m)
+}
+
+// TestUserRepository runs the conformance suite against the Postgres test
+// database. The role of TEST_DATABASE_URL must not be a superuser, as those
+// bypass row-level security.
+func TestUserRepository(t *testing.T) {
+	ctx := db.WithAllTenants(context.Background())
+	databaseURL := testutil.DatabaseURL(t)
+
+	pool, err := db.NewPostgresPool(databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	database, err := db.NewDB(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.NewTxManager(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := user.NewUserRepository(database, tx)
+
+	user.RunRepositoryConformance(t, func(t *testing.T) user.Repository {
+		// the identities restart, so the tenants get the IDs the suite expects
+		_, err := pool.Exec(ctx, `TRUNCATE users, tenants RESTART IDENTITY CASCADE`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = pool.Exec(ctx, `INSERT INTO tenants (slug, name) VALUES ('conformance-a', 'A'), ('conformance-b', 'B')`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
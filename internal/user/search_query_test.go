@@ -0,0 +1,48 @@
+package user
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/malytinKonstantin/go-fiber/internal/shared"
+)
+
+// TestCountUsersSQLMatchesSearch checks that the count of a listing applies
+// exactly the conditions of the listing, and none of its paging or order
+func TestCountUsersSQLMatchesSearch(t *testing.T) {
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	for _, q := range []UserQuery{
+		{},
+		{Username: "al", Email: "example", FullName: "smith", Bio: "go"},
+		{CreatedFrom: &day, CreatedTo: &day},
+		{Search: "alice smi", SearchConfig: SearchConfigEnglish, SearchQuery: "alice:* & smi:*"},
+		{Filters: []shared.FilterCondition{
+			{Field: "username", Column: "username", Op: shared.OpIn, Value: []string{"alice", "bob"}},
+			{Field: "bio", Column: "bio", Op: shared.OpContains, Value: "100%"},
+		}},
+		{
+			Username: "al", Search: "alice", SearchConfig: SearchConfigSimple, SearchQuery: "alice:*",
+			Sort:   []shared.SortKey{{Field: "created_at", Column: "created_at", Desc: true}},
+			Limit:  10,
+			Offset: 20,
+			Fields: []string{"username"},
+		},
+	} {
+		listSQL, listArgs := buildSearchUsersSQL(q, selectedUserColumns(q.Fields))
+		countSQL, countArgs := buildCountUsersSQL(q)
+
+		where, ok := strings.CutPrefix(countSQL, "SELECT COUNT(*) FROM users")
+		if !ok || strings.Contains(where, "ORDER BY") || strings.Contains(where, "LIMIT") || strings.Contains(where, "OFFSET") {
+			t.Fatalf("count of %+v: %s", q, countSQL)
+		}
+		if !strings.Contains(listSQL, " FROM users"+where+" ORDER BY ") {
+			t.Fatalf("count of %+v has other conditions than the listing:\n%s\n%s", q, countSQL, listSQL)
+		}
+		// the conditions are bound first, so they get the same placeholders
+		if len(countArgs) > len(listArgs) || !reflect.DeepEqual(countArgs, listArgs[:len(countArgs)]) {
+			t.Fatalf("count of %+v binds %v, the listing %v", q, countArgs, listArgs)
+		}
+	}
+}
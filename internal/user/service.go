@@ -1,42 +1,161 @@
 package user
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/malytinKonstantin/go-fiber/internal/auth"
 	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/outbox"
+	"github.com/malytinKonstantin/go-fiber/internal/shared"
+	"github.com/malytinKonstantin/go-fiber/internal/storage"
+	"github.com/spf13/viper"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	defaultSettingsCacheTTL = time.Minute
+	maxCachedSettings       = 100000
+)
+
 const (
 	dateFormat            = "2006-01-02"
-	invalidDateFormatErr  = "invalid date format"
 	invalidCredentialsErr = "invalid credentials"
 )
 
+// Errors of search parameters supplied by the caller
+var (
+	ErrInvalidDateFormat   = errors.New("invalid date format")
+	ErrInvalidCountMode    = errors.New("invalid count mode")
+	ErrInvalidSearchConfig = errors.New("invalid search config")
+)
+
+// Text search configurations supported by the users search_vector column
+const (
+	SearchConfigSimple  = "simple"
+	SearchConfigEnglish = "english"
+	SearchConfigRussian = "russian"
+)
+
 type UserService struct {
-	repo *UserRepository
+	repo           Repository
+	tx             db.Transactor
+	events         outbox.Recorder
+	includes       *shared.IncludeRegistry
+	blobs          storage.BlobStore
+	avatarMaxBytes int64
+	settings       *shared.TTLCache[settingsCacheKey, UserSettings]
 }
 
-func NewUserService(repo *UserRepository) *UserService {
-	return &UserService{repo: repo}
+// settingsCacheKey includes the tenant of the user, so that cached settings
+// are never served across tenants
+type settingsCacheKey struct {
+	tenantID int32
+	userID   int32
+}
+
+// NewUserService reads AVATAR_MAX_BYTES, the upload limit for avatars, and
+// USER_SETTINGS_CACHE_TTL, how long settings read by other modules are cached.
+// Changes of users are recorded as events with events, in the transaction
+// run by tx.
+func NewUserService(repo Repository, tx db.Transactor, blobs storage.BlobStore, events outbox.Recorder) *UserService {
+	avatarMaxBytes := viper.GetInt64("AVATAR_MAX_BYTES")
+	if avatarMaxBytes <= 0 {
+		avatarMaxBytes = defaultAvatarMaxBytes
+	}
+	settingsCacheTTL := viper.GetDuration("USER_SETTINGS_CACHE_TTL")
+	if settingsCacheTTL <= 0 {
+		settingsCacheTTL = defaultSettingsCacheTTL
+	}
+	return &UserService{
+		repo:           repo,
+		tx:             tx,
+		events:         events,
+		includes:       shared.NewIncludeRegistry(),
+		blobs:          blobs,
+		avatarMaxBytes: avatarMaxBytes,
+		settings:       shared.NewTTLCache[settingsCacheKey, UserSettings](settingsCacheTTL, maxCachedSettings),
+	}
+}
+
+// AvatarMaxBytes returns the size limit of avatar uploads
+func (s *UserService) AvatarMaxBytes() int64 {
+	return s.avatarMaxBytes
+}
+
+// withAvatar fills in the avatar URLs of a user returned by the repository
+func (s *UserService) withAvatar(user User, err error) (User, error) {
+	if err != nil {
+		return User{}, err
+	}
+	user.Avatar = avatarURLs(s.blobs, user.AvatarKey)
+	return user, nil
+}
+
+func (s *UserService) withAvatars(users []User, err error) ([]User, error) {
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		users[i].Avatar = avatarURLs(s.blobs, users[i].AvatarKey)
+	}
+	return users, nil
 }
 
 func (s *UserService) GetUser(ctx context.Context, id int32) (User, error) {
 	if err := ctx.Err(); err != nil {
 		return User{}, err
 	}
-	return s.repo.GetUser(ctx, id)
+	return s.withAvatar(s.repo.GetUser(ctx, id))
+}
+
+// UserLookupResult is the outcome of looking up one requested ID
+type UserLookupResult struct {
+	ID    int32 `json:"id"`
+	Found bool  `json:"found"`
+	User  *User `json:"user"`
+}
+
+// LookupUsers fetches users by ID in one query. Results follow the order of
+// ids, repeated IDs included, and missing or deleted users get an entry with
+// found set to false.
+func (s *UserService) LookupUsers(ctx context.Context, ids []int32) ([]UserLookupResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	users, err := s.repo.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]UserLookupResult, len(ids))
+	for i, id := range ids {
+		results[i] = UserLookupResult{ID: id}
+		if user, ok := users[id]; ok {
+			user.Avatar = avatarURLs(s.blobs, user.AvatarKey)
+			results[i].Found = true
+			results[i].User = &user
+		}
+	}
+	return results, nil
 }
 
 func (s *UserService) GetUserByUsername(ctx context.Context, username string) (User, error) {
 	if err := ctx.Err(); err != nil {
 		return User{}, err
 	}
-	return s.repo.GetUserByUsername(ctx, username)
+	return s.withAvatar(s.repo.GetUserByUsername(ctx, username))
 }
 
 func (s *UserService) SearchUsers(ctx context.Context, params SearchUsersParams) ([]User, error) {
@@ -44,42 +163,205 @@ func (s *UserService) SearchUsers(ctx context.Context, params SearchUsersParams)
 		return nil, err
 	}
 
-	dbParams := db.SearchUsersParams{
-		Username:    params.Username,
-		Email:       params.Email,
-		FullName:    params.FullName,
-		Bio:         params.Bio,
-		SortBy:      params.SortBy,
-		Search:      params.Search,
-		LimitParam:  sql.NullInt32{Int32: params.Limit, Valid: params.Limit > 0},
-		OffsetParam: sql.NullInt32{Int32: params.Offset, Valid: params.Offset >= 0},
+	query, err := s.buildUserQuery(params)
+	if err != nil {
+		return nil, err
 	}
 
-	if params.CreatedFrom != "" || params.CreatedTo != "" {
-		if err := s.parseAndSetDates(&dbParams, params.CreatedFrom, params.CreatedTo); err != nil {
+	return s.withAvatars(s.repo.SearchUsers(ctx, query))
+}
+
+// SearchUsersPage returns a page of users together with pagination metadata.
+// One extra row is fetched to determine has_more without relying on the total.
+func (s *UserService) SearchUsersPage(ctx context.Context, params SearchUsersParams, countMode string) (shared.Page[User], error) {
+	if err := ctx.Err(); err != nil {
+		return shared.Page[User]{}, err
+	}
+	if !shared.IsValidCountMode(countMode) {
+		return shared.Page[User]{}, ErrInvalidCountMode
+	}
+
+	query, err := s.buildUserQuery(params)
+	if err != nil {
+		return shared.Page[User]{}, err
+	}
+	if params.Limit > 0 {
+		query.Limit = params.Limit + 1
+	}
+
+	users, err := s.withAvatars(s.repo.SearchUsers(ctx, query))
+	if err != nil {
+		return shared.Page[User]{}, err
+	}
+
+	page := shared.Page[User]{
+		Data:   users,
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}
+	if params.Limit > 0 && int32(len(users)) > params.Limit {
+		page.Data = users[:params.Limit]
+		page.HasMore = true
+	}
+
+	page.Total, err = s.countUsers(ctx, query, countMode)
+	if err != nil {
+		return shared.Page[User]{}, err
+	}
+
+	return page, nil
+}
+
+// BuildExportQuery validates the search parameters of an export. Limit and
+// offset are ignored, and only the fields admins may select are ever
+// selected, so password hashes cannot end up in an export.
+func (s *UserService) BuildExportQuery(params SearchUsersParams) (UserQuery, error) {
+	params.Limit, params.Offset = 0, 0
+	if len(params.Fields) == 0 {
+		params.Fields = AdminUserFields
+	}
+	return s.buildUserQuery(params)
+}
+
+// ExportUsers passes every user matching the query to fn, in the listing order
+func (s *UserService) ExportUsers(ctx context.Context, query UserQuery, fn func(User) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.repo.StreamUsers(ctx, query, func(user User) error {
+		user.Avatar = avatarURLs(s.blobs, user.AvatarKey)
+		return fn(user)
+	})
+}
+
+// countUsers computes the total according to the count mode.
+// The pg_class estimate describes the whole table, including soft-deleted
+// users, so it is only used for unfiltered listings and falls back to an
+// exact count otherwise.
+func (s *UserService) countUsers(ctx context.Context, query UserQuery, countMode string) (*int64, error) {
+	if countMode == shared.CountNone {
+		return nil, nil
+	}
+
+	if countMode == shared.CountEstimated && !query.hasFilters() {
+		estimate, err := s.repo.EstimateUsersCount(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if estimate >= 0 {
+			return &estimate, nil
+		}
+	}
+
+	total, err := s.repo.CountUsers(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &total, nil
+}
+
+// RegisterInclude makes a related resource available through include=
+// on user listings. Other modules call it to attach their data to users.
+func (s *UserService) RegisterInclude(name string, loader shared.IncludeLoader) {
+	s.includes.Register(name, loader)
+}
+
+// IncludeNames returns the related resources that can be requested with include=
+func (s *UserService) IncludeNames() []string {
+	return s.includes.Names()
+}
+
+// ProjectUsers renders users with only the requested fields and embeds
+// the requested related resources, loading each of them in one batch
+func (s *UserService) ProjectUsers(ctx context.Context, users []User, fields, includes []string) ([]map[string]any, error) {
+	if len(fields) == 0 {
+		fields = PublicUserFields
+	}
+
+	var included map[string]map[int32]any
+	if len(includes) > 0 && len(users) > 0 {
+		ids := make([]int32, len(users))
+		for i, user := range users {
+			ids[i] = user.ID
+		}
+		var err error
+		included, err = s.includes.Load(ctx, includes, ids)
+		if err != nil {
 			return nil, err
 		}
 	}
 
-	return s.repo.SearchUsers(ctx, dbParams)
+	items := make([]map[string]any, len(users))
+	for i, user := range users {
+		item := projectUser(user, fields)
+		for _, name := range includes {
+			item[name] = included[name][user.ID]
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+func (s *UserService) buildUserQuery(params SearchUsersParams) (UserQuery, error) {
+	query := UserQuery{
+		Username: params.Username,
+		Email:    params.Email,
+		FullName: params.FullName,
+		Bio:      params.Bio,
+		Sort:     params.Sort,
+		Search:   strings.TrimSpace(params.Search),
+		Limit:    params.Limit,
+		Offset:   params.Offset,
+		Filters:  params.Filters,
+		Fields:   params.Fields,
+	}
+
+	query.SearchConfig = params.SearchConfig
+	switch query.SearchConfig {
+	case "":
+		query.SearchConfig = SearchConfigSimple
+	case SearchConfigSimple, SearchConfigEnglish, SearchConfigRussian:
+	default:
+		return UserQuery{}, ErrInvalidSearchConfig
+	}
+	query.SearchQuery = buildPrefixTSQuery(query.Search)
+
+	if params.CreatedFrom != "" || params.CreatedTo != "" {
+		if err := s.parseAndSetDates(&query, params.CreatedFrom, params.CreatedTo); err != nil {
+			return UserQuery{}, err
+		}
+	}
+
+	return query, nil
+}
+
+// buildPrefixTSQuery turns free text into a to_tsquery expression where every
+// word is matched as a prefix. Only letters and digits are kept, so the result
+// never contains tsquery operators supplied by the caller.
+func buildPrefixTSQuery(search string) string {
+	words := strings.FieldsFunc(search, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
 }
 
-func (s *UserService) parseAndSetDates(dbParams *db.SearchUsersParams, createdFrom, createdTo string) error {
+func (s *UserService) parseAndSetDates(query *UserQuery, createdFrom, createdTo string) error {
 	if createdFrom != "" {
 		if t, err := time.Parse(dateFormat, createdFrom); err != nil {
-			return errors.New(invalidDateFormatErr)
+			return ErrInvalidDateFormat
 		} else {
-			tPtr := &t
-			dbParams.CreatedFrom = &tPtr
+			query.CreatedFrom = &t
 		}
 	}
 
 	if createdTo != "" {
 		if t, err := time.Parse(dateFormat, createdTo); err != nil {
-			return errors.New(invalidDateFormatErr)
+			return ErrInvalidDateFormat
 		} else {
-			tPtr := &t
-			dbParams.CreatedTo = &tPtr
+			query.CreatedTo = &t
 		}
 	}
 
@@ -102,7 +384,9 @@ func (s *UserService) CreateUser(ctx context.Context, dto CreateUserDto) (User,
 		FullName:     sql.NullString{String: dto.FullName.String, Valid: dto.FullName.Valid},
 		Bio:          sql.NullString{String: dto.Bio.String, Valid: dto.Bio.Valid},
 	}
-	return s.repo.CreateUser(ctx, dbParams)
+	return s.changeUser(ctx, EventUserCreated, nil, func(ctx context.Context) (User, error) {
+		return s.repo.CreateUser(ctx, dbParams)
+	})
 }
 
 func (s *UserService) UpdateUser(ctx context.Context, id int32, dto UpdateUserDto) (User, error) {
@@ -116,7 +400,40 @@ func (s *UserService) UpdateUser(ctx context.Context, id int32, dto UpdateUserDt
 		return User{}, err
 	}
 
-	return s.repo.UpdateUser(ctx, dbParams)
+	return s.changeUser(ctx, EventUserUpdated, updatedFields(dto), func(ctx context.Context) (User, error) {
+		return s.repo.UpdateUser(ctx, dbParams)
+	})
+}
+
+// SetAdmin grants or revokes the administrator role. There is no endpoint
+// for it; it is meant for seeding and maintenance commands.
+func (s *UserService) SetAdmin(ctx context.Context, id int32, isAdmin bool) (User, error) {
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+	return s.changeUser(ctx, EventUserUpdated, []string{"is_admin"}, func(ctx context.Context) (User, error) {
+		return s.repo.SetUserAdmin(ctx, id, isAdmin)
+	})
+}
+
+// updatedFields names the fields dto sets, for the user.updated event
+func updatedFields(dto UpdateUserDto) []string {
+	var fields []string
+	for _, field := range []struct {
+		name string
+		set  bool
+	}{
+		{"username", dto.Username.Valid},
+		{"email", dto.Email.Valid},
+		{"password", dto.Password.Valid},
+		{"full_name", dto.FullName.Valid},
+		{"bio", dto.Bio.Valid},
+	} {
+		if field.set {
+			fields = append(fields, field.name)
+		}
+	}
+	return fields
 }
 
 func (s *UserService) setUpdateParams(dbParams *db.UpdateUserParams, dto UpdateUserDto) error {
@@ -142,11 +459,294 @@ func (s *UserService) setUpdateParams(dbParams *db.UpdateUserParams, dto UpdateU
 	return nil
 }
 
+// DeleteUser soft-deletes a user; it can be restored until it is purged.
+// Deleting a missing or deleted user does nothing.
 func (s *UserService) DeleteUser(ctx context.Context, id int32) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return s.repo.DeleteUser(ctx, id)
+	var tenantID int32
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		user, err := s.repo.GetUser(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.repo.DeleteUser(ctx, id); err != nil {
+			return err
+		}
+		tenantID = user.TenantID
+		return s.recordUserEvent(ctx, EventUserDeleted, id, user.TenantID, UserRef{ID: id})
+	})
+	if err != nil || tenantID == 0 {
+		return err
+	}
+	s.forgetSettings(ctx, tenantID, id)
+	return nil
+}
+
+func (s *UserService) RestoreUser(ctx context.Context, id int32) (User, error) {
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+	return s.changeUser(ctx, EventUserRestored, nil, func(ctx context.Context) (User, error) {
+		return s.repo.RestoreUser(ctx, id)
+	})
+}
+
+// GetSettings returns the effective settings of a user: the stored overrides
+// on top of the defaults. Results are cached for USER_SETTINGS_CACHE_TTL, so
+// other modules can call it on hot paths; changes made through UpdateSettings
+// are visible at once on this instance and after the TTL on the others.
+func (s *UserService) GetSettings(ctx context.Context, userID int32) (UserSettings, error) {
+	if err := ctx.Err(); err != nil {
+		return UserSettings{}, err
+	}
+	key, err := s.settingsKey(ctx, userID)
+	if err != nil {
+		return UserSettings{}, err
+	}
+	if settings, ok := s.settings.Get(key); ok {
+		return settings, nil
+	}
+
+	overrides, err := s.repo.GetUserSettings(ctx, userID)
+	if err != nil {
+		return UserSettings{}, err
+	}
+	settings, err := resolveSettings(overrides, false)
+	if err != nil {
+		return UserSettings{}, err
+	}
+	s.settings.Set(key, settings)
+	return settings, nil
+}
+
+// UpdateSettings applies a JSON merge patch to the user's settings: nested
+// objects are merged, null resets a setting to its default. The patched
+// patch must match the schema or ErrInvalidSettings is returned. Stored
+// overrides the schema no longer accepts are dropped rather than rejected.
+func (s *UserService) UpdateSettings(ctx context.Context, userID int32, patch map[string]any) (UserSettings, error) {
+	if err := ctx.Err(); err != nil {
+		return UserSettings{}, err
+	}
+	if _, err := resolveSettings(patch, true); err != nil {
+		return UserSettings{}, err
+	}
+	defaults, err := toJSONObject(DefaultUserSettings())
+	if err != nil {
+		return UserSettings{}, err
+	}
+	var settings UserSettings
+	err = s.repo.UpdateUserSettings(ctx, userID, func(overrides map[string]any) (map[string]any, error) {
+		overrides = mergePatch(pruneSettings(overrides, defaults), patch)
+		var err error
+		settings, err = resolveSettings(overrides, false)
+		return overrides, err
+	})
+	if err != nil {
+		return UserSettings{}, err
+	}
+	// the caller's transaction may still roll back, so the cache is only
+	// refreshed once the new settings are committed
+	key, err := s.settingsKey(ctx, userID)
+	if err != nil {
+		return UserSettings{}, err
+	}
+	s.settings.Delete(key)
+	db.AfterCommit(ctx, func(context.Context) error {
+		s.settings.Set(key, settings)
+		return nil
+	})
+	return settings, nil
+}
+
+// forgetSettings drops the cached settings of a user now and once more after
+// the commit, so that a read racing with the transaction does not keep
+// stale settings in the cache
+func (s *UserService) forgetSettings(ctx context.Context, tenantID, userID int32) {
+	key := settingsCacheKey{tenantID: tenantID, userID: userID}
+	s.settings.Delete(key)
+	db.AfterCommit(ctx, func(context.Context) error {
+		s.settings.Delete(key)
+		return nil
+	})
+}
+
+// settingsKey returns the cache key of a user's settings. A context scoped to
+// a tenant only sees the users of that tenant, so the tenant is taken from it;
+// otherwise it is looked up, so that every context shares the same entry.
+func (s *UserService) settingsKey(ctx context.Context, userID int32) (settingsCacheKey, error) {
+	tenantID, ok := db.TenantFromContext(ctx)
+	if !ok {
+		var err error
+		if tenantID, err = s.repo.GetUserTenant(ctx, userID); err != nil {
+			return settingsCacheKey{}, err
+		}
+	}
+	return settingsCacheKey{tenantID: tenantID, userID: userID}, nil
+}
+
+// PurgeDeletedUsers permanently deletes users soft-deleted longer than retention ago
+// together with their avatars, recording user.purged for each of them. Users
+// who still own an organization are kept.
+func (s *UserService) PurgeDeletedUsers(ctx context.Context, retention time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	var purged []PurgedUser
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		purged, err = s.repo.PurgeDeletedUsers(ctx, time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		for _, user := range purged {
+			if err := s.recordUserEvent(ctx, EventUserPurged, user.ID, user.TenantID, UserRef{ID: user.ID}); err != nil {
+				return err
+			}
+			if user.AvatarKey != "" {
+				s.discardAvatarAfterCommit(ctx, user.AvatarKey)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(purged)), nil
+}
+
+// SetAvatar validates the uploaded image, stores its thumbnails and replaces
+// the user's previous avatar. Thumbnails are stored under a fresh key, so
+// cached URLs of the old avatar never show the new picture.
+func (s *UserService) SetAvatar(ctx context.Context, id int32, r io.Reader) (User, error) {
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+
+	previous, err := s.repo.GetUser(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+
+	thumbnails, err := processAvatar(r, s.avatarMaxBytes)
+	if err != nil {
+		return User{}, err
+	}
+
+	avatarKey, err := newAvatarKey(id)
+	if err != nil {
+		return User{}, err
+	}
+	for _, thumbnail := range thumbnails {
+		err := s.blobs.Put(ctx, avatarBlobKey(avatarKey, thumbnail.size), bytes.NewReader(thumbnail.data),
+			int64(len(thumbnail.data)), avatarContentType)
+		if err != nil {
+			s.discardAvatar(ctx, avatarKey)
+			return User{}, err
+		}
+	}
+
+	user, err := s.changeUser(ctx, EventUserUpdated, []string{"avatar"}, func(ctx context.Context) (User, error) {
+		return s.repo.SetUserAvatar(ctx, id, avatarKey)
+	})
+	if err != nil {
+		s.discardAvatar(ctx, avatarKey)
+		return User{}, err
+	}
+	if previous.AvatarKey != "" {
+		s.discardAvatarAfterCommit(ctx, previous.AvatarKey)
+	}
+	return user, nil
+}
+
+// RemoveAvatar deletes the user's avatar, if any
+func (s *UserService) RemoveAvatar(ctx context.Context, id int32) (User, error) {
+	if err := ctx.Err(); err != nil {
+		return User{}, err
+	}
+
+	previous, err := s.repo.GetUser(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+	if previous.AvatarKey == "" {
+		return previous, nil
+	}
+
+	user, err := s.changeUser(ctx, EventUserUpdated, []string{"avatar"}, func(ctx context.Context) (User, error) {
+		return s.repo.SetUserAvatar(ctx, id, "")
+	})
+	if err != nil {
+		return User{}, err
+	}
+	s.discardAvatarAfterCommit(ctx, previous.AvatarKey)
+	return user, nil
+}
+
+// AnonymizeUser erases the personal data of a user, including deleted ones,
+// and removes the avatar from the storage
+func (s *UserService) AnonymizeUser(ctx context.Context, id int32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	tenantID, err := s.repo.GetUserTenant(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	avatarKey, err := s.repo.GetUserAvatarKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.AnonymizeUser(ctx, id); err != nil {
+			return err
+		}
+		return s.recordUserEvent(ctx, EventUserAnonymized, id, tenantID, UserRef{ID: id})
+	})
+	if err != nil {
+		return err
+	}
+	s.forgetSettings(ctx, tenantID, id)
+	if avatarKey == "" {
+		return nil
+	}
+	return db.AfterCommit(ctx, func(ctx context.Context) error {
+		return deleteAvatarBlobs(ctx, s.blobs, avatarKey)
+	})
+}
+
+// discardAvatar removes blobs that are no longer referenced. Failures only
+// leave orphaned files behind, so they are logged rather than returned.
+func (s *UserService) discardAvatar(ctx context.Context, avatarKey string) {
+	if err := deleteAvatarBlobs(ctx, s.blobs, avatarKey); err != nil {
+		log.Printf("Failed to delete avatar %s: %v", avatarKey, err)
+	}
+}
+
+// discardAvatarAfterCommit removes blobs that the current transaction stops
+// referencing. They are kept until the commit, because a rolled back or
+// retried transaction still points at them.
+func (s *UserService) discardAvatarAfterCommit(ctx context.Context, avatarKey string) {
+	db.AfterCommit(ctx, func(ctx context.Context) error {
+		s.discardAvatar(ctx, avatarKey)
+		return nil
+	})
+}
+
+func newAvatarKey(userID int32) (string, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("avatars/%d/%s", userID, hex.EncodeToString(token)), nil
 }
 
 func HashPassword(password string) (string, error) {
@@ -176,7 +776,7 @@ func (s *UserService) Authenticate(ctx context.Context, username, password strin
 		return "", errors.New(invalidCredentialsErr)
 	}
 
-	return auth.GenerateToken(auth.User{ID: user.ID})
+	return auth.GenerateToken(auth.User{ID: user.ID, IsAdmin: user.IsAdmin, TenantID: user.TenantID})
 }
 
 func (s *UserService) ValidateToken(tokenString string) (*auth.Claims, error) {
@@ -184,16 +784,19 @@ func (s *UserService) ValidateToken(tokenString string) (*auth.Claims, error) {
 }
 
 type SearchUsersParams struct {
-	Username    string
-	Email       string
-	FullName    string
-	Bio         string
-	CreatedFrom string
-	CreatedTo   string
-	Search      string
-	SortBy      string
-	Limit       int32
-	Offset      int32
+	Username     string
+	Email        string
+	FullName     string
+	Bio          string
+	CreatedFrom  string
+	CreatedTo    string
+	Search       string
+	SearchConfig string
+	Sort         []shared.SortKey
+	Limit        int32
+	Offset       int32
+	Filters      []shared.FilterCondition
+	Fields       []string
 }
 
 type CreateUserParams struct {
@@ -0,0 +1,260 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/malytinKonstantin/go-fiber/internal/shared"
+)
+
+func newTestService() (*UserService, *MemoryRepository) {
+	repo := NewMemoryRepository()
+	return NewUserService(repo, repo, nil, repo), repo
+}
+
+func TestRunBatchTransactionalRollsBack(t *testing.T) {
+	service, repo := newTestService()
+	ctx := db.WithTenant(context.Background(), conformanceTenantA)
+	mustCreateUser(t, repo, ctx, userParams("alice", "", ""))
+
+	result, err := service.RunBatch(ctx, BatchTransactional, []BatchOperationDto{
+		{Op: BatchCreate, Data: json.RawMessage(`{"username": "bob", "email": "bob@example.com", "password": "Passw0rd!"}`)},
+		{Op: BatchCreate, Data: json.RawMessage(`{"username": "alice", "email": "alice2@example.com", "password": "Passw0rd!"}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Committed {
+		t.Fatal("batch with a conflict was committed")
+	}
+	if result.Results[0].Status != http.StatusFailedDependency || result.Results[1].Status != http.StatusConflict {
+		t.Fatalf("statuses = %d, %d", result.Results[0].Status, result.Results[1].Status)
+	}
+	assertUsernames(t, repo, ctx, UserQuery{}, "alice")
+	if events := repo.Events(); len(events) != 0 {
+		t.Fatalf("rolled back batch recorded %d events", len(events))
+	}
+}
+
+func TestUpdateUserKeepsUnsetFields(t *testing.T) {
+	service, repo := newTestService()
+	ctx := db.WithTenant(context.Background(), conformanceTenantA)
+	alice := mustCreateUser(t, repo, ctx, userParams("alice", "Alice Smith", ""))
+
+	var dto UpdateUserDto
+	if err := json.Unmarshal([]byte(`{"bio": "likes go"}`), &dto); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := service.UpdateUser(ctx, alice.ID, dto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Username != "alice" || updated.Email != alice.Email || updated.PasswordHash != alice.PasswordHash ||
+		updated.FullName != "Alice Smith" || updated.Bio != "likes go" {
+		t.Fatalf("UpdateUser = %+v", updated)
+	}
+	if events := repo.Events(); len(events) != 1 || string(events[0].Payload) != fmt.Sprintf(`{"id":%d,"changed":["bio"]}`, alice.ID) {
+		t.Fatalf("events = %+v", events)
+	}
+}
+
+func TestSearchUsersPageRejectsInvalidParams(t *testing.T) {
+	service, _ := newTestService()
+	ctx := db.WithTenant(context.Background(), conformanceTenantA)
+	for _, tc := range []struct {
+		params    SearchUsersParams
+		countMode string
+		want      error
+	}{
+		{SearchUsersParams{Search: "al", SearchConfig: "klingon"}, shared.CountNone, ErrInvalidSearchConfig},
+		{SearchUsersParams{CreatedFrom: "yesterday"}, shared.CountNone, ErrInvalidDateFormat},
+		{SearchUsersParams{}, "approximate", ErrInvalidCountMode},
+	} {
+		if _, err := service.SearchUsersPage(ctx, tc.params, tc.countMode); !errors.Is(err, tc.want) {
+			t.Fatalf("SearchUsersPage(%+v, %q) = %v, want %v", tc.params, tc.countMode, err, tc.want)
+		}
+	}
+}
+
+// TestUpdateSettingsDropsRemovedSettings checks that overrides left behind
+// by a removed or retyped setting do not fail updates, while the patch
+// itself is still validated strictly
+func TestUpdateSettingsDropsRemovedSettings(t *testing.T) {
+	service, repo := newTestService()
+	ctx := db.WithTenant(context.Background(), conformanceTenantA)
+	alice := mustCreateUser(t, repo, ctx, userParams("alice", "", ""))
+	err := repo.UpdateUserSettings(ctx, alice.ID, func(map[string]any) (map[string]any, error) {
+		return map[string]any{
+			"legacy":        true,
+			"theme":         "dark",
+			"notifications": map[string]any{"email": "daily", "digest": true},
+		}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := service.UpdateSettings(ctx, alice.ID, map[string]any{"locale": "ru"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Locale != "ru" || settings.Theme != "dark" || !settings.Notifications.Email {
+		t.Fatalf("UpdateSettings = %+v", settings)
+	}
+	stored, err := repo.GetUserSettings(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]any{"locale": "ru", "theme": "dark"}; fmt.Sprint(stored) != fmt.Sprint(want) {
+		t.Fatalf("stored settings = %v, want %v", stored, want)
+	}
+
+	for _, patch := range []map[string]any{
+		{"legacy": true},
+		{"notifications": map[string]any{"digest": true}},
+		{"theme": 1},
+		{"theme": "blue"},
+	} {
+		if _, err := service.UpdateSettings(ctx, alice.ID, patch); !errors.Is(err, ErrInvalidSettings) {
+			t.Fatalf("UpdateSettings(%v) = %v, want ErrInvalidSettings", patch, err)
+		}
+	}
+}
+
+// TestSettingsCacheIsSharedAcrossScopes checks that changes made with a
+// context for all tenants, as background jobs use, refresh the settings
+// cached for requests of the user's tenant
+func TestSettingsCacheIsSharedAcrossScopes(t *testing.T) {
+	service, repo := newTestService()
+	ctx := db.WithTenant(context.Background(), conformanceTenantA)
+	allTenants := db.WithAllTenants(context.Background())
+	alice := mustCreateUser(t, repo, ctx, userParams("alice", "", ""))
+
+	if settings, err := service.GetSettings(ctx, alice.ID); err != nil || settings.Theme != "system" {
+		t.Fatalf("GetSettings = %+v, %v", settings, err)
+	}
+	if _, err := service.UpdateSettings(allTenants, alice.ID, map[string]any{"theme": "dark"}); err != nil {
+		t.Fatal(err)
+	}
+	if settings, err := service.GetSettings(ctx, alice.ID); err != nil || settings.Theme != "dark" {
+		t.Fatalf("GetSettings after an update for all tenants = %+v, %v", settings, err)
+	}
+	if err := service.AnonymizeUser(allTenants, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetSettings(ctx, alice.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("GetSettings of an anonymized user: %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestUserChangesRecordEvents(t *testing.T) {
+	service, repo := newTestService()
+	ctx := db.WithTenant(context.Background(), conformanceTenantA)
+
+	alice, err := service.CreateUser(ctx, CreateUserDto{Username: "alice", Email: "alice@example.com", Password: "Passw0rd!"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.SetAdmin(ctx, alice.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DeleteUser(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DeleteUser(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.CreateUser(ctx, CreateUserDto{Username: "bob", Email: "alice@example.com", Password: "Passw0rd!"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.RestoreUser(ctx, alice.ID); !db.IsUniqueViolation(err) {
+		t.Fatalf("RestoreUser: %v, want a unique violation", err)
+	}
+	// erasures run in background jobs, scoped to all tenants
+	if err := service.AnonymizeUser(db.WithAllTenants(context.Background()), alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	events := repo.Events()
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+		if event.TenantID != conformanceTenantA {
+			t.Fatalf("%s has tenant %d", event.Type, event.TenantID)
+		}
+	}
+	want := []string{EventUserCreated, EventUserUpdated, EventUserDeleted, EventUserCreated, EventUserAnonymized}
+	if !slices.Equal(types, want) {
+		t.Fatalf("events = %v, want %v", types, want)
+	}
+	if events[0].AggregateType != AggregateUser || events[0].AggregateID != strconv.Itoa(int(alice.ID)) {
+		t.Fatalf("aggregate = %s:%s", events[0].AggregateType, events[0].AggregateID)
+	}
+
+	// payloads leave out personal data, which outlives an erasure in the outbox
+	for i, want := range []string{
+		fmt.Sprintf(`{"id":%d}`, alice.ID),
+		fmt.Sprintf(`{"id":%d,"changed":["is_admin"]}`, alice.ID),
+		fmt.Sprintf(`{"id":%d}`, alice.ID),
+	} {
+		if string(events[i].Payload) != want {
+			t.Fatalf("%s payload = %s, want %s", events[i].Type, events[i].Payload, want)
+		}
+	}
+}
+
+func TestImportAndPurgeRecordEvents(t *testing.T) {
+	service, repo := newTestService()
+	ctx := db.WithTenant(context.Background(), conformanceTenantA)
+	mustCreateUser(t, repo, ctx, userParams("alice", "", ""))
+
+	csv := "username,email,password\n" +
+		"alice,alice2@example.com,Passw0rd!\n" +
+		"bob,bob@example.com,Passw0rd!\n" +
+		"carol,carol@example.com,Passw0rd!\n"
+	report, err := NewUserImporter(service).Import(ctx, strings.NewReader(csv), ImportFormatCSV, ImportBestEffort)
+	if err != nil || report.Imported != 2 {
+		t.Fatalf("Import = %+v, %v", report, err)
+	}
+	bob, err := repo.GetUserByUsername(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeleteUser(ctx, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	// purges run in background jobs, scoped to all tenants
+	if purged, err := service.PurgeDeletedUsers(db.WithAllTenants(context.Background()), -time.Hour); err != nil || purged != 1 {
+		t.Fatalf("PurgeDeletedUsers = %d, %v", purged, err)
+	}
+
+	var got []string
+	for _, event := range repo.Events() {
+		if event.TenantID != conformanceTenantA {
+			t.Fatalf("%s has tenant %d", event.Type, event.TenantID)
+		}
+		got = append(got, event.Type+" "+string(event.Payload))
+	}
+	carol, err := repo.GetUserByUsername(ctx, "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		fmt.Sprintf(`%s {"id":%d}`, EventUserCreated, bob.ID),
+		fmt.Sprintf(`%s {"id":%d}`, EventUserCreated, carol.ID),
+		fmt.Sprintf(`%s {"id":%d}`, EventUserPurged, bob.ID),
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+}
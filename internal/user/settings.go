@@ -0,0 +1,170 @@
+package user
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	_ "time/tzdata" // timezone validation must not depend on the host's zoneinfo
+
+	"github.com/malytinKonstantin/go-fiber/internal/middleware"
+)
+
+var ErrInvalidSettings = errors.New("invalid settings")
+
+// UserSettings are the preferences of a user. Only the values a user has
+// changed are stored; everything else comes from DefaultUserSettings, so
+// changing a default applies to every user who has not overridden it.
+type UserSettings struct {
+	// BCP 47 language tag
+	Locale string `json:"locale" validate:"required,bcp47_language_tag"`
+	// IANA time zone name
+	Timezone      string               `json:"timezone" validate:"required,timezone"`
+	Theme         string               `json:"theme" validate:"required,oneof=system light dark"`
+	Notifications NotificationSettings `json:"notifications"`
+}
+
+type NotificationSettings struct {
+	Email     bool `json:"email"`
+	Push      bool `json:"push"`
+	Marketing bool `json:"marketing"`
+}
+
+// DefaultUserSettings returns the settings of a user who has changed nothing
+func DefaultUserSettings() UserSettings {
+	return UserSettings{
+		Locale:   "en",
+		Timezone: "UTC",
+		Theme:    "system",
+		Notifications: NotificationSettings{
+			Email:     true,
+			Push:      true,
+			Marketing: false,
+		},
+	}
+}
+
+// SettingsValidationError lists the settings that do not match the schema
+type SettingsValidationError struct {
+	Errors []middleware.FieldError
+}
+
+func (e *SettingsValidationError) Error() string {
+	return fmt.Sprintf("%s: %d invalid fields", ErrInvalidSettings, len(e.Errors))
+}
+
+func (e *SettingsValidationError) Unwrap() error {
+	return ErrInvalidSettings
+}
+
+// resolveSettings overlays the overrides on the defaults. In strict mode, used
+// to check the patch of an update, the result must match the schema: unknown
+// keys, wrong types and values failing validation are rejected. Otherwise such
+// leftovers, e.g. of a removed setting, are skipped so that reads keep working.
+func resolveSettings(overrides map[string]any, strict bool) (UserSettings, error) {
+	effective, err := toJSONObject(DefaultUserSettings())
+	if err != nil {
+		return UserSettings{}, err
+	}
+	effective = mergePatch(effective, overrides)
+
+	raw, err := json.Marshal(effective)
+	if err != nil {
+		return UserSettings{}, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	settings := DefaultUserSettings()
+	if err := decoder.Decode(&settings); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		isTypeErr := errors.As(err, &typeErr)
+		if isTypeErr && strict {
+			return UserSettings{}, &SettingsValidationError{Errors: []middleware.FieldError{{
+				Field:   typeErr.Field,
+				Message: "Invalid type: " + typeErr.Value,
+			}}}
+		}
+		if !isTypeErr {
+			return UserSettings{}, &SettingsValidationError{Errors: []middleware.FieldError{{Message: err.Error()}}}
+		}
+	}
+	if strict {
+		if fieldErrors := middleware.ValidateStruct(settings); fieldErrors != nil {
+			return UserSettings{}, &SettingsValidationError{Errors: fieldErrors}
+		}
+	}
+	return settings, nil
+}
+
+// pruneSettings drops the overrides the schema no longer accepts, such as a
+// removed setting or a value whose type has changed, so that they are not
+// written back with the next update
+func pruneSettings(overrides, defaults map[string]any) map[string]any {
+	for key, value := range overrides {
+		def, ok := defaults[key]
+		if !ok {
+			delete(overrides, key)
+			continue
+		}
+		defObject, defIsObject := def.(map[string]any)
+		valueObject, valueIsObject := value.(map[string]any)
+		switch {
+		case defIsObject && valueIsObject:
+			if pruned := pruneSettings(valueObject, defObject); len(pruned) > 0 {
+				overrides[key] = pruned
+			} else {
+				delete(overrides, key)
+			}
+		case reflect.TypeOf(value) != reflect.TypeOf(def):
+			delete(overrides, key)
+		}
+	}
+	return overrides
+}
+
+// parseSettingsOverrides decodes a stored settings document
+func parseSettingsOverrides(raw []byte) (map[string]any, error) {
+	overrides := map[string]any{}
+	if len(raw) == 0 {
+		return overrides, nil
+	}
+	if err := json.Unmarshal(raw, &overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// mergePatch applies a JSON merge patch (RFC 7396) to target: objects are
+// merged recursively, null removes a key and any other value replaces it
+func mergePatch(target, patch map[string]any) map[string]any {
+	if target == nil {
+		target = map[string]any{}
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		patchObject, ok := value.(map[string]any)
+		if !ok {
+			target[key] = value
+			continue
+		}
+		targetObject, _ := target[key].(map[string]any)
+		target[key] = mergePatch(targetObject, patchObject)
+	}
+	return target
+}
+
+func toJSONObject(v any) (map[string]any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]any
+	err = json.Unmarshal(raw, &object)
+	return object, err
+}
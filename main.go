@@ -1,15 +1,13 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
+	"os"
 
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/swagger"
 	_ "github.com/lib/pq"
-	_ "github.com/malytinKonstantin/go-fiber/docs"
-	"github.com/malytinKonstantin/go-fiber/internal/middleware"
+	"github.com/malytinKonstantin/go-fiber/internal/app"
 	"github.com/spf13/viper"
 )
 
@@ -18,20 +16,31 @@ func main() {
 	port := viper.GetString("PORT")
 	apiPrefix := viper.GetString("API_PREFIX")
 
-	app, err := InitializeApp(dbURL)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(dbURL, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if viper.GetBool("DB_AUTO_MIGRATE") {
+		if err := autoMigrate(dbURL); err != nil {
+			log.Fatalf("Failed to migrate the database: %v", err)
+		}
+	}
+
+	application, err := app.InitializeApp(dbURL)
 	if err != nil {
 		log.Fatalf("Failed to initialize app: %v", err)
 	}
+	if len(os.Args) > 1 {
+		if err := runCommand(application, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	application.StartJobs(context.Background())
 
-	fiberApp := fiber.New(fiber.Config{
-		JSONDecoder: json.Unmarshal,
-	})
-	api := fiberApp.Group(apiPrefix)
-	api.Use(middleware.ValidateDTO())
-	api.Use(middleware.AuthMiddleware())
-	app.SetupRoutes(api)
-
-	fiberApp.Get("/swagger/*", swagger.HandlerDefault)
+	fiberApp := application.NewFiberApp(apiPrefix)
 	log.Fatal(fiberApp.Listen(fmt.Sprintf(":%s", port)))
 }
 
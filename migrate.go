@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	migrations "github.com/malytinKonstantin/go-fiber/db"
+	"github.com/malytinKonstantin/go-fiber/internal/db"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultMigrationDir       = "db/migration"
+	defaultSchemaGlob         = "db/schema/*.sql"
+	defaultAutoMigrateTimeout = 5 * time.Minute
+)
+
+// runMigrate executes the migrate subcommands. They do not need the
+// application, so they run before it is initialized and work on an empty
+// database too.
+func runMigrate(databaseURL string, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down|status|force|create|drift")
+	}
+	switch args[0] {
+	case "create":
+		return runMigrateCreate(args[1:])
+	case "drift":
+		return runMigrateDrift(databaseURL, args[1:])
+	case "up", "down", "status", "force":
+	default:
+		return fmt.Errorf("unknown migrate command %q, available commands: up, down, status, force, create, drift", args[0])
+	}
+
+	migrator, closeMigrator, err := openMigrator(context.Background(), databaseURL)
+	if err != nil {
+		return err
+	}
+	defer closeMigrator()
+
+	switch args[0] {
+	case "up":
+		return migrator.Up()
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of migrations to roll back")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		return migrator.Down(*steps)
+	case "force":
+		if len(args) != 2 {
+			return errors.New("usage: migrate force VERSION")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return migrator.Force(version)
+	default: // status
+		return printMigrationStatus(migrator)
+	}
+}
+
+func runMigrateCreate(args []string) error {
+	flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := flags.String("dir", defaultMigrationDir, "migration directory of the source tree")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: migrate create [-dir DIR] NAME")
+	}
+	paths, err := migrations.CreateMigration(*dir, flags.Arg(0))
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+	return err
+}
+
+// runMigrateDrift compares the schema built by the migrations with the schema
+// sqlc generates from and prints the differences. It needs a role that may
+// create databases.
+func runMigrateDrift(databaseURL string, args []string) error {
+	flags := flag.NewFlagSet("migrate drift", flag.ContinueOnError)
+	schema := flags.String("schema", defaultSchemaGlob, "schema files sqlc generates from")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if url := viper.GetString("MIGRATE_DATABASE_URL"); url != "" {
+		databaseURL = url
+	}
+
+	drift, err := migrations.CheckSchemaDrift(context.Background(), databaseURL, *schema)
+	if err != nil {
+		return err
+	}
+	if !drift.Empty() {
+		fmt.Print(drift)
+		return errors.New("the migrations do not produce the schema sqlc generates from")
+	}
+	fmt.Println("no schema drift")
+	return nil
+}
+
+func printMigrationStatus(migrator *migrations.Migrator) error {
+	status, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		fmt.Printf("version: %d (dirty)\n", status.Version)
+	} else {
+		fmt.Printf("version: %d\n", status.Version)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, migration := range status.Migrations {
+		state := "pending"
+		if migration.Applied {
+			state = "applied"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", migration.Version, migration.Name, state)
+	}
+	return w.Flush()
+}
+
+// autoMigrate applies pending migrations on startup when DB_AUTO_MIGRATE is
+// set. Instances starting together take turns on an advisory lock, waiting
+// for at most DB_AUTO_MIGRATE_TIMEOUT.
+func autoMigrate(databaseURL string) error {
+	timeout := viper.GetDuration("DB_AUTO_MIGRATE_TIMEOUT")
+	if timeout <= 0 {
+		timeout = defaultAutoMigrateTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	migrator, closeMigrator, err := openMigrator(ctx, databaseURL)
+	if err != nil {
+		return err
+	}
+	defer closeMigrator()
+	if err := migrator.UpLocked(ctx); err != nil {
+		return err
+	}
+	log.Println("Migrations completed successfully")
+	return nil
+}
+
+// openMigrator connects to MIGRATE_DATABASE_URL, which may name a role
+// allowed to change the schema, or to the application database. The
+// connection sees the rows of all tenants, so data migrations are not
+// hidden by row-level security.
+func openMigrator(ctx context.Context, databaseURL string) (*migrations.Migrator, func(), error) {
+	if url := viper.GetString("MIGRATE_DATABASE_URL"); url != "" {
+		databaseURL = url
+	}
+	pool, err := db.NewPostgresPool(databaseURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	sqlDB := stdlib.OpenDBFromPool(pool)
+	migrator, err := migrations.NewMigrator(db.WithAllTenants(ctx), sqlDB)
+	if err != nil {
+		sqlDB.Close()
+		pool.Close()
+		return nil, nil, err
+	}
+	return migrator, func() {
+		if err := migrator.Close(); err != nil {
+			log.Printf("Failed to close the migration connection: %v", err)
+		}
+		sqlDB.Close()
+		pool.Close()
+	}, nil
+}
@@ -1,43 +1,42 @@
 include .env
 export $(shell sed 's/=.*//' .env)
 
-MIGRATION_DIR=./db/migration
-SCHEMA_DIR=./db/schema
-
-# Генерация и выполнение миграции
-migrate-auto:
-	@echo "Генерация миграции..."
-	@SCHEMA_FILE=$(SCHEMA_FILE) python scripts/generate_migration.py
-	@echo "Выполнение миграций..."
-	@migrate -database "$(DB_URL)" -path $(MIGRATION_DIR) up
-
-# Остальные команды остаются без изменений...
+# Миграции встроены в бинарный файл и выполняются командой `migrate`
 
 migrate-up:
 	@echo "Выполнение миграций..."
-	@migrate -database "$(DB_URL)" -path $(MIGRATION_DIR) up
+	@go run . migrate up
 
 migrate-down:
 	@echo "Откат последней миграции..."
-	@migrate -database "$(DB_URL)" -path $(MIGRATION_DIR) down 1
+	@go run . migrate down
 
 migrate-create:
 	@read -p "Введите название миграции: " name; \
-	migrate create -ext sql -dir $(MIGRATION_DIR) -seq $${name}
+	go run . migrate create $${name}
 
 migrate-status:
 	@echo "Статус миграций:"
-	@migrate -database "$(DB_URL)" -path $(MIGRATION_DIR) version
+	@go run . migrate status
 
 migrate-force:
 	@read -p "Введите версию для принудительной установки: " version; \
-	migrate -database "$(DB_URL)" -path $(MIGRATION_DIR) force $$version
+	go run . migrate force $$version
+
+migrate-drift:
+	@echo "Сравнение миграций со схемой sqlc..."
+	@go run . migrate drift
+
+db-roles:
+	@echo "Создание роли приложения..."
+	@psql "$(MIGRATE_DATABASE_URL)" -v ON_ERROR_STOP=1 -v app_password="$(APP_DATABASE_PASSWORD)" -f db/roles.sql
 
 help:
 	@echo "Доступные команды:"
-	@echo "  make migrate-auto   - Автоматически сгенерировать и выполнить миграцию"
 	@echo "  make migrate-up     - Выполнить все доступные миграции"
 	@echo "  make migrate-down   - Откатить последнюю миграцию"
 	@echo "  make migrate-create - Создать новую миграцию"
 	@echo "  make migrate-status - Проверить статус миграций"
-	@echo "  make migrate-force  - Принудительно установить версию миграции"
\ No newline at end of file
+	@echo "  make migrate-force  - Принудительно установить версию миграции"
+	@echo "  make migrate-drift  - Сравнить схему после миграций со схемой sqlc"
+	@echo "  make db-roles       - Создать роль приложения для DATABASE_URL"